@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/collectors"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/agent"
+)
+
+// collectorFileConfig настройки сборщика из файла конфигурации.
+type collectorFileConfig struct {
+	Enabled  *bool  `json:"enabled"`
+	Interval string `json:"interval"`
+	Timeout  string `json:"timeout"`
+	// Options параметры, специфичные для сборщика.
+	Options json.RawMessage `json:"options"`
+}
+
+// cgroupOptions параметры сборщика метрик cgroup.
+type cgroupOptions struct {
+	// Root точка монтирования cgroup v2, по умолчанию /sys/fs/cgroup.
+	Root string `json:"root"`
+	// Group путь группы, по умолчанию группа агента.
+	Group string `json:"group"`
+}
+
+// processOptions параметры сборщика метрик процессов.
+type processOptions struct {
+	PIDs     []int32  `json:"pids"`
+	Patterns []string `json:"patterns"`
+}
+
+// textfileOptions параметры сборщика метрик из файлов.
+type textfileOptions struct {
+	Directory string `json:"directory"`
+	// MaxAge время, после которого неизменявшийся файл считается устаревшим.
+	MaxAge string `json:"max_age"`
+}
+
+// execCommandOptions параметры команды сборщика exec.
+type execCommandOptions struct {
+	Name    string   `json:"name"`
+	Path    string   `json:"path"`
+	Args    []string `json:"args"`
+	Format  string   `json:"format"`
+	Timeout string   `json:"timeout"`
+}
+
+// execOptions параметры сборщика метрик из вывода команд.
+type execOptions struct {
+	Commands []execCommandOptions `json:"commands"`
+	// MaxAge время, в течение которого отправляется последний успешный результат команды.
+	MaxAge string `json:"max_age"`
+}
+
+// collectorsFileConfig файл конфигурации сборщиков метрик в формате JSON.
+type collectorsFileConfig struct {
+	Collectors map[string]collectorFileConfig `json:"collectors"`
+}
+
+// namedCollector сборщик с именем, под которым он указывается в конфигурации.
+type namedCollector struct {
+	name string
+	// disabled выключен ли сборщик, если он не включен в конфигурации явно
+	disabled bool
+	// build создает сборщик по параметрам из конфигурации
+	build func(options json.RawMessage) (agent.Collector, error)
+}
+
+// builtinCollectors возвращает встроенные сборщики в порядке отправки их метрик.
+func builtinCollectors() []namedCollector {
+	return []namedCollector{
+		{name: collectors.RuntimeName, build: withoutOptions(collectors.NewRuntime())},
+		{name: collectors.MemoryName, build: withoutOptions(collectors.NewMemory())},
+		{name: collectors.PollName, build: withoutOptions(collectors.NewPoll())},
+		{name: collectors.CPUName, build: withoutOptions(collectors.NewCPU())},
+		{name: collectors.LoadName, build: withoutOptions(collectors.NewLoad())},
+		{name: collectors.DiskName, build: withoutOptions(collectors.NewDisk())},
+		{name: collectors.NetName, build: withoutOptions(collectors.NewNet())},
+		{name: collectors.SwapName, build: withoutOptions(collectors.NewSwap())},
+		{name: collectors.CgroupName, disabled: true, build: buildCgroup},
+		{name: collectors.ProcessName, disabled: true, build: buildProcess},
+		{name: collectors.TextfileName, disabled: true, build: buildTextfile},
+		{name: collectors.ExecName, disabled: true, build: buildExec},
+	}
+}
+
+// withoutOptions возвращает функцию создания сборщика, не имеющего параметров.
+func withoutOptions(c agent.Collector) func(json.RawMessage) (agent.Collector, error) {
+	return func(options json.RawMessage) (agent.Collector, error) {
+		if len(options) != 0 {
+			return nil, errors.New("collector has no options")
+		}
+
+		return c, nil
+	}
+}
+
+func buildCgroup(data json.RawMessage) (agent.Collector, error) {
+	var options cgroupOptions
+	if err := decodeOptions(data, &options); err != nil {
+		return nil, err
+	}
+
+	return collectors.NewCgroup(options.Root, options.Group), nil
+}
+
+func buildProcess(data json.RawMessage) (agent.Collector, error) {
+	var options processOptions
+	if err := decodeOptions(data, &options); err != nil {
+		return nil, err
+	}
+
+	return collectors.NewProcess(options.PIDs, options.Patterns)
+}
+
+func buildTextfile(data json.RawMessage) (agent.Collector, error) {
+	var options textfileOptions
+	if err := decodeOptions(data, &options); err != nil {
+		return nil, err
+	}
+
+	maxAge, err := parseOptionalDuration(options.MaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max age: %w", err)
+	}
+
+	return collectors.NewTextfile(options.Directory, maxAge)
+}
+
+func buildExec(data json.RawMessage) (agent.Collector, error) {
+	var options execOptions
+	if err := decodeOptions(data, &options); err != nil {
+		return nil, err
+	}
+
+	maxAge, err := parseOptionalDuration(options.MaxAge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max age: %w", err)
+	}
+
+	commands := make([]collectors.ExecCommand, 0, len(options.Commands))
+	for _, c := range options.Commands {
+		timeout, err := parseOptionalDuration(c.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse command %s timeout: %w", c.Name, err)
+		}
+
+		commands = append(commands, collectors.ExecCommand{
+			Name:    c.Name,
+			Path:    c.Path,
+			Args:    c.Args,
+			Format:  c.Format,
+			Timeout: timeout,
+		})
+	}
+
+	return collectors.NewExec(commands, maxAge)
+}
+
+// parseOptionalDuration разбирает длительность, пустая строка соответствует нулю.
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(s)
+}
+
+// decodeOptions разбирает параметры сборщика, отклоняя неизвестные поля.
+func decodeOptions(data json.RawMessage, options any) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(options); err != nil {
+		return fmt.Errorf("failed to parse options: %w", err)
+	}
+
+	return nil
+}
+
+// initCollectors создает реестр встроенных сборщиков. По умолчанию сборщики, кроме cgroup,
+// process, textfile и exec, включены и опрашиваются с периодом pollInterval, настройки
+// из файла configPath их переопределяют. Выключенные сборщики не создаются.
+func initCollectors(pollInterval time.Duration, configPath string) (*agent.Registry, error) {
+	var fileConfig collectorsFileConfig
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read collectors config: %w", err)
+		}
+		if err = json.Unmarshal(data, &fileConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse collectors config: %w", err)
+		}
+	}
+
+	registry := agent.NewRegistry()
+	known := make(map[string]struct{})
+	for _, c := range builtinCollectors() {
+		known[c.name] = struct{}{}
+
+		cfg := fileConfig.Collectors[c.name]
+		config, err := collectorConfig(cfg, !c.disabled, pollInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collector %s config: %w", c.name, err)
+		}
+
+		if !config.Enabled {
+			continue
+		}
+
+		collector, err := c.build(cfg.Options)
+		if err != nil {
+			return nil, fmt.Errorf("invalid collector %s config: %w", c.name, err)
+		}
+
+		if err = registry.Register(c.name, collector, config); err != nil {
+			return nil, err
+		}
+	}
+
+	for name := range fileConfig.Collectors {
+		if _, exist := known[name]; !exist {
+			return nil, fmt.Errorf("unknown collector %s", name)
+		}
+	}
+
+	return registry, nil
+}
+
+func collectorConfig(fileConfig collectorFileConfig, enabled bool, pollInterval time.Duration) (agent.CollectorConfig, error) {
+	config := agent.CollectorConfig{
+		Enabled:  enabled,
+		Interval: pollInterval,
+	}
+
+	if fileConfig.Enabled != nil {
+		config.Enabled = *fileConfig.Enabled
+	}
+
+	if fileConfig.Interval != "" {
+		interval, err := time.ParseDuration(fileConfig.Interval)
+		if err != nil {
+			return agent.CollectorConfig{}, fmt.Errorf("failed to parse interval: %w", err)
+		}
+		config.Interval = interval
+	}
+
+	if fileConfig.Timeout != "" {
+		timeout, err := time.ParseDuration(fileConfig.Timeout)
+		if err != nil {
+			return agent.CollectorConfig{}, fmt.Errorf("failed to parse timeout: %w", err)
+		}
+		config.Timeout = timeout
+	}
+
+	return config, nil
+}
@@ -6,7 +6,18 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// protocol протокол отправки метрик на сервер.
+type protocol string
+
+const (
+	httpProtocol protocol = "http"
+	grpcProtocol protocol = "grpc"
 )
 
 type flags struct {
@@ -14,31 +25,44 @@ type flags struct {
 	reportInterval  time.Duration
 	pollInterval    time.Duration
 	cryptKey        string
+	cryptoKeyPath   string
+	tlsCAPath       string
+	tlsCertPath     string
+	tlsKeyPath      string
 	maxGoroutineNum int64
+	labels          domain.Labels
+	agentName       string
+	protocol        protocol
+
+	collectorsConfigPath string
 }
 
 func initFlags() (flags, error) {
-	scheme := "http"
-	serverAddr := url.URL{
-		Scheme: scheme,
-		Host:   "localhost:8080",
-	}
-	flag.Func("a", "metric server address", func(address string) error {
+	serverHost := "localhost:8080"
+	flag.Func("a", "metric server address: host:port or https://host:port", func(address string) error {
 		if address == "" {
 			return nil
 		}
 
-		serverAddr = url.URL{
-			Scheme: scheme,
-			Host:   address,
-		}
+		serverHost = address
 
 		return nil
 	})
 	reportInterval := flag.Int64("r", 10, "report interval duration")
 	pollInterval := flag.Int64("p", 2, "report poll duration")
 	cryptKey := flag.String("k", "", "crypt request key")
+	cryptoKeyPath := flag.String("crypto-key", "", "path to PEM server public key to encrypt requests with")
+	useTLS := flag.Bool("tls", false, "send metrics over TLS, verifying server with system CAs unless -tls-ca is set")
+	tlsCAPath := flag.String("tls-ca", "", "path to PEM CA certificates to verify server with, system CAs by default")
+	tlsCertPath := flag.String("tls-cert", "", "path to PEM client certificate to identify agent with")
+	tlsKeyPath := flag.String("tls-key", "", "path to PEM private key of client certificate")
 	maxGoroutineNum := flag.Int64("l", 0, "max goroutine sender num")
+	labelsValue := flag.String("labels", "",
+		"comma separated labels to add to every metric, e.g. env=prod,dc=eu; instance label defaults to agent name")
+	agentName := flag.String("name", "", "agent instance name, hostname by default")
+	collectorsConfigPath := flag.String("collectors-config", "",
+		"path to JSON config of collectors: enabled, interval and timeout by collector name")
+	protocolValue := flag.String("protocol", string(httpProtocol), "protocol to send metrics with: http or grpc")
 
 	flag.Parse()
 
@@ -47,10 +71,7 @@ func initFlags() (flags, error) {
 			return flags{}, fmt.Errorf("ADDRESS environment variable not set")
 		}
 
-		serverAddr = url.URL{
-			Scheme: scheme,
-			Host:   value,
-		}
+		serverHost = value
 	}
 
 	reportIntervalKey := "REPORT_INTERVAL"
@@ -88,6 +109,55 @@ func initFlags() (flags, error) {
 		cryptKey = &value
 	}
 
+	cryptoKeyPathKey := "CRYPTO_KEY"
+	if value, exist := os.LookupEnv(cryptoKeyPathKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", cryptoKeyPathKey)
+		}
+
+		cryptoKeyPath = &value
+	}
+
+	for key, value := range map[string]*string{
+		"TLS_CA":   tlsCAPath,
+		"TLS_CERT": tlsCertPath,
+		"TLS_KEY":  tlsKeyPath,
+	} {
+		envValue, exist := os.LookupEnv(key)
+		if !exist {
+			continue
+		}
+		if envValue == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", key)
+		}
+
+		*value = envValue
+	}
+
+	useTLSKey := "TLS"
+	if value, exist := os.LookupEnv(useTLSKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", useTLSKey)
+		}
+
+		boolValue, err := strconv.ParseBool(value)
+		if err != nil {
+			return flags{}, fmt.Errorf("failed to parse %s: %w", useTLSKey, err)
+		}
+
+		useTLS = &boolValue
+	}
+
+	if (*tlsCertPath == "") != (*tlsKeyPath == "") {
+		return flags{}, fmt.Errorf("tls certificate and key must be set together")
+	}
+
+	// при заданных параметрах TLS метрики отправляются по https
+	serverAddr, err := serverURL(serverHost, *useTLS || *tlsCAPath != "" || *tlsCertPath != "")
+	if err != nil {
+		return flags{}, err
+	}
+
 	maxGoroutineNumKey := "RATE_LIMIT"
 	if value, exist := os.LookupEnv(maxGoroutineNumKey); exist {
 		if value == "" {
@@ -102,12 +172,130 @@ func initFlags() (flags, error) {
 		maxGoroutineNum = &intValue
 	}
 
+	labelsKey := "LABELS"
+	if value, exist := os.LookupEnv(labelsKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", labelsKey)
+		}
+
+		labelsValue = &value
+	}
+
+	agentNameKey := "AGENT_NAME"
+	if value, exist := os.LookupEnv(agentNameKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", agentNameKey)
+		}
+
+		agentName = &value
+	}
+
+	collectorsConfigPathKey := "COLLECTORS_CONFIG"
+	if value, exist := os.LookupEnv(collectorsConfigPathKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", collectorsConfigPathKey)
+		}
+
+		collectorsConfigPath = &value
+	}
+
+	protocolKey := "PROTOCOL"
+	if value, exist := os.LookupEnv(protocolKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", protocolKey)
+		}
+
+		protocolValue = &value
+	}
+
+	switch protocol(*protocolValue) {
+	case httpProtocol:
+	case grpcProtocol:
+		if *cryptoKeyPath != "" {
+			return flags{}, fmt.Errorf("crypto key is supported only for %s protocol", httpProtocol)
+		}
+	default:
+		return flags{}, fmt.Errorf("unknown protocol %q", *protocolValue)
+	}
+
+	labels, err := domain.ParseLabels(*labelsValue)
+	if err != nil {
+		return flags{}, fmt.Errorf("failed to parse labels: %w", err)
+	}
+
+	if *agentName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return flags{}, fmt.Errorf("failed to get hostname: %w", err)
+		}
+
+		agentName = &hostname
+	}
+	labels = instanceLabels(labels, *agentName)
+
 	return flags{
 		serverAddr:      serverAddr,
 		reportInterval:  time.Duration(*reportInterval) * time.Second,
 		pollInterval:    time.Duration(*pollInterval) * time.Second,
 		cryptKey:        *cryptKey,
+		cryptoKeyPath:   *cryptoKeyPath,
+		tlsCAPath:       *tlsCAPath,
+		tlsCertPath:     *tlsCertPath,
+		tlsKeyPath:      *tlsKeyPath,
 		maxGoroutineNum: *maxGoroutineNum,
+		labels:          labels,
+		agentName:       *agentName,
+		protocol:        protocol(*protocolValue),
+
+		collectorsConfigPath: *collectorsConfigPath,
+	}, nil
+}
+
+// instanceLabel метка с именем агента, разделяющая одноименные метрики разных агентов.
+const instanceLabel = "instance"
+
+// instanceLabels возвращает метки labels с меткой instance, равной имени агента,
+// если она не задана явно.
+func instanceLabels(labels domain.Labels, agentName string) domain.Labels {
+	res := labels.Copy()
+	if res == nil {
+		res = make(domain.Labels, 1)
+	}
+	if _, exist := res[instanceLabel]; !exist {
+		res[instanceLabel] = agentName
+	}
+
+	return res
+}
+
+// serverURL возвращает адрес сервера по значению вида host:port или scheme://host:port.
+// Схема https выбирается явно в адресе или при useTLS.
+func serverURL(address string, useTLS bool) (url.URL, error) {
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+
+	host := address
+	if addressScheme, rest, found := strings.Cut(address, "://"); found {
+		switch {
+		case addressScheme == "https":
+			scheme = addressScheme
+		case addressScheme == "http" && useTLS:
+			return url.URL{}, fmt.Errorf("server address %s uses http while tls is enabled", address)
+		case addressScheme != "http":
+			return url.URL{}, fmt.Errorf("unsupported server address scheme %q", addressScheme)
+		}
+		host = strings.TrimSuffix(rest, "/")
+	}
+
+	if host == "" || strings.Contains(host, "/") {
+		return url.URL{}, fmt.Errorf("invalid server address %q", address)
+	}
+
+	return url.URL{
+		Scheme: scheme,
+		Host:   host,
 	}, nil
 }
 
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/memory"
+)
+
+func TestServerURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		address string
+		useTLS  bool
+		want    url.URL
+		wantErr bool
+	}{
+		{
+			name:    "host without tls",
+			address: "localhost:8080",
+			want:    url.URL{Scheme: "http", Host: "localhost:8080"},
+		},
+		{
+			name:    "host with tls switch",
+			address: "metrics.example.com:443",
+			useTLS:  true,
+			want:    url.URL{Scheme: "https", Host: "metrics.example.com:443"},
+		},
+		{
+			name:    "https scheme",
+			address: "https://metrics.example.com/",
+			want:    url.URL{Scheme: "https", Host: "metrics.example.com"},
+		},
+		{
+			name:    "https scheme with tls switch",
+			address: "https://metrics.example.com:8443",
+			useTLS:  true,
+			want:    url.URL{Scheme: "https", Host: "metrics.example.com:8443"},
+		},
+		{
+			name:    "http scheme",
+			address: "http://localhost:8080",
+			want:    url.URL{Scheme: "http", Host: "localhost:8080"},
+		},
+		{
+			name:    "http scheme with tls switch",
+			address: "http://localhost:8080",
+			useTLS:  true,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			address: "ftp://localhost:8080",
+			wantErr: true,
+		},
+		{
+			name:    "path",
+			address: "https://localhost:8080/update",
+			wantErr: true,
+		},
+		{
+			name:    "empty host",
+			address: "https://",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := serverURL(tt.address, tt.useTLS)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("serverURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("serverURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceLabels(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		labels    domain.Labels
+		agentName string
+		want      domain.Labels
+	}{
+		{
+			name:      "no labels",
+			agentName: "host-a",
+			want:      domain.Labels{"instance": "host-a"},
+		},
+		{
+			name:      "other labels",
+			labels:    domain.Labels{"env": "prod"},
+			agentName: "host-a",
+			want:      domain.Labels{"env": "prod", "instance": "host-a"},
+		},
+		{
+			name:      "explicit instance",
+			labels:    domain.Labels{"instance": "web-1"},
+			agentName: "host-a",
+			want:      domain.Labels{"instance": "web-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := instanceLabels(tt.labels, tt.agentName); got.String() != tt.want.String() {
+				t.Errorf("instanceLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceLabels_twoAgents(t *testing.T) {
+	t.Parallel()
+
+	storage, err := memory.NewStorage(t.Context(), "", 0, false)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	// агенты с настройками по умолчанию отправляют одноименную метрику без меток
+	agents := map[string]float64{"host-a": 1, "host-b": 2}
+	for name, value := range agents {
+		err = storage.UpdateGauge(t.Context(), name, domain.MetricValue{
+			Name:       "HeapAlloc",
+			GaugeValue: value,
+			Labels:     instanceLabels(nil, name),
+		})
+		if err != nil {
+			t.Fatalf("UpdateGauge() error = %v", err)
+		}
+	}
+
+	for name, want := range agents {
+		got, err := storage.GetGaugeValue(t.Context(), "HeapAlloc", domain.Labels{"instance": name})
+		if err != nil {
+			t.Fatalf("GetGaugeValue(%s) error = %v", name, err)
+		}
+		if got != want {
+			t.Errorf("GetGaugeValue(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
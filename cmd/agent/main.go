@@ -2,41 +2,142 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
+	metricsGRPC "github.com/kdv2001/onlyMetrics/internal/clients/metrics/grpc"
 	metricsHTTP "github.com/kdv2001/onlyMetrics/internal/clients/metrics/http"
+	"github.com/kdv2001/onlyMetrics/internal/domain"
 	"github.com/kdv2001/onlyMetrics/internal/usecases/agent"
+	"github.com/kdv2001/onlyMetrics/pkg/graceful"
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
 	"github.com/kdv2001/onlyMetrics/pkg/logger"
+	"github.com/kdv2001/onlyMetrics/pkg/tlsutil"
 )
 
+// grpcStreamBatchSize размер части набора метрик, начиная с которого набор отправляется в потоке.
+const grpcStreamBatchSize = 500
+
+type sendClient interface {
+	SendGauge(ctx context.Context, value domain.MetricValue) error
+	SendCounter(ctx context.Context, value domain.MetricValue) error
+	SendMetrics(ctx context.Context, values []domain.MetricValue) error
+}
+
 func main() {
-	httpClient := &http.Client{
-		Timeout: time.Second * 5,
-	}
 	zapLog, err := zap.NewDevelopment()
 	if err != nil {
 		log.Fatal("failed to init logger: %w", err)
 	}
 
-	ctx := logger.ToContext(context.Background(), zapLog.Sugar())
+	// по сигналу агент прекращает сбор, отправляет метрики последний раз и завершает работу
+	ctx, stop := graceful.NotifyContext(logger.ToContext(context.Background(), zapLog.Sugar()))
+	defer stop()
 
 	parsedFlags, err := initFlags()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	metric := agent.NewMetricsUpdater(ctx, parsedFlags.pollInterval)
-	metricsHTTPClient := metricsHTTP.NewBodyClient(
-		httpClient,
-		parsedFlags.serverAddr,
-		metricsHTTP.CompresGZIPOpt(),
-		metricsHTTP.WithSHA256Opt(parsedFlags.cryptKey),
-	)
+	registry, err := initCollectors(parsedFlags.pollInterval, parsedFlags.collectorsConfigPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	metric := agent.NewMetricsUpdater(ctx, registry)
+
+	realIP, err := outboundIP(parsedFlags.serverAddr.Host)
+	if err != nil {
+		logger.Errorf(ctx, "failed to get outbound ip: %v", err)
+	}
+
+	var tlsConfig *tls.Config
+	if parsedFlags.serverAddr.Scheme == "https" {
+		tlsConfig, err = tlsutil.ClientConfig(parsedFlags.tlsCAPath, parsedFlags.tlsCertPath, parsedFlags.tlsKeyPath)
+		if err != nil {
+			log.Fatalf("failed to init tls: %v", err)
+		}
+	}
+
+	var metricsClient sendClient
+	switch parsedFlags.protocol {
+	case grpcProtocol:
+		transportCredentials := insecure.NewCredentials()
+		if tlsConfig != nil {
+			transportCredentials = credentials.NewTLS(tlsConfig)
+		}
+
+		conn, err := grpc.NewClient(parsedFlags.serverAddr.Host,
+			grpc.WithTransportCredentials(transportCredentials))
+		if err != nil {
+			log.Fatalf("failed to init grpc client: %v", err)
+		}
+		defer conn.Close()
+
+		metricsClient = metricsGRPC.NewClient(
+			conn,
+			metricsGRPC.CompresGZIPOpt(),
+			metricsGRPC.WithLabelsOpt(parsedFlags.labels),
+			metricsGRPC.WithAgentNameOpt(parsedFlags.agentName),
+			metricsGRPC.WithRealIPOpt(realIP),
+			metricsGRPC.WithStreamBatchSizeOpt(grpcStreamBatchSize),
+		)
+	default:
+		var publicKey *rsa.PublicKey
+		if parsedFlags.cryptoKeyPath != "" {
+			publicKey, err = hybrid.LoadPublicKey(parsedFlags.cryptoKeyPath)
+			if err != nil {
+				log.Fatalf("failed to load crypto key: %v", err)
+			}
+		}
+
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient := &http.Client{
+			Transport: transport,
+			Timeout:   time.Second * 5,
+		}
+		metricsClient = metricsHTTP.NewBodyClient(
+			httpClient,
+			parsedFlags.serverAddr,
+			metricsHTTP.CompresGZIPOpt(),
+			metricsHTTP.WithSHA256Opt(parsedFlags.cryptKey),
+			metricsHTTP.WithLabelsOpt(parsedFlags.labels),
+			metricsHTTP.WithAgentNameOpt(parsedFlags.agentName),
+			metricsHTTP.WithRealIPOpt(realIP),
+			metricsHTTP.WithEncryptionOpt(publicKey),
+		)
+	}
+
+	metricsUC := agent.NewUseCase(metricsClient, metric, parsedFlags.reportInterval, parsedFlags.maxGoroutineNum)
+	if err = metricsUC.SendMetrics(ctx); err != nil {
+		logger.Errorf(ctx, "error send metrics: %v", err)
+	}
+	logger.Infof(ctx, "agent stopped")
+}
+
+// outboundIP возвращает адрес интерфейса, через который агент обращается к серверу.
+// UDP соединение не отправляет пакетов, а только выбирает маршрут.
+func outboundIP(serverHost string) (string, error) {
+	conn, err := net.Dial("udp", serverHost)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	addr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok {
+		return "", fmt.Errorf("unexpected local address %v", conn.LocalAddr())
+	}
 
-	metricsUC := agent.NewUseCase(metricsHTTPClient, metric, parsedFlags.reportInterval, parsedFlags.maxGoroutineNum)
-	_ = metricsUC.SendMetrics(context.TODO())
+	return addr.IP.String(), nil
 }
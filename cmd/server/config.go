@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// fileConfig настройки сервера из файла конфигурации в формате JSON.
+type fileConfig struct {
+	Address         string `json:"address"`
+	GRPCAddress     string `json:"grpc_address"`
+	Restore         *bool  `json:"restore"`
+	StoreInterval   string `json:"store_interval"`
+	StoreFile       string `json:"store_file"`
+	DatabaseDSN     string `json:"database_dsn"`
+	Storage         string `json:"storage"`
+	CryptoKey       string `json:"crypto_key"`
+	TrustedSubnet   string `json:"trusted_subnet"`
+	TLSCert         string `json:"tls_cert"`
+	TLSKey          string `json:"tls_key"`
+	TLSClientCA     string `json:"tls_client_ca"`
+	ShutdownTimeout string `json:"shutdown_timeout"`
+}
+
+// applyConfigFile задает флагам, не указанным явно, значения из файла конфигурации.
+// Значения флагов и переменных окружения имеют приоритет над файлом.
+func applyConfigFile(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg fileConfig
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	values := map[string]string{
+		"a":             cfg.Address,
+		"grpc-address":  cfg.GRPCAddress,
+		"f":             cfg.StoreFile,
+		"d":             cfg.DatabaseDSN,
+		"storage":       cfg.Storage,
+		"crypto-key":    cfg.CryptoKey,
+		"t":             cfg.TrustedSubnet,
+		"tls-cert":      cfg.TLSCert,
+		"tls-key":       cfg.TLSKey,
+		"tls-client-ca": cfg.TLSClientCA,
+	}
+	if cfg.Restore != nil {
+		values["r"] = strconv.FormatBool(*cfg.Restore)
+	}
+	if cfg.StoreInterval != "" {
+		interval, err := time.ParseDuration(cfg.StoreInterval)
+		if err != nil {
+			return fmt.Errorf("failed to parse config store_interval: %w", err)
+		}
+		values["i"] = strconv.FormatInt(int64(interval/time.Second), 10)
+	}
+	if cfg.ShutdownTimeout != "" {
+		timeout, err := time.ParseDuration(cfg.ShutdownTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to parse config shutdown_timeout: %w", err)
+		}
+		values["shutdown-timeout"] = strconv.FormatInt(int64(timeout/time.Second), 10)
+	}
+
+	set := make(map[string]struct{})
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = struct{}{}
+	})
+
+	for name, value := range values {
+		if _, exist := set[name]; exist || value == "" {
+			continue
+		}
+
+		if err = fs.Set(name, value); err != nil {
+			return fmt.Errorf("failed to set %s from config: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -1,46 +1,146 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"math"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// storageKind тип хранилища метрик.
+type storageKind string
+
+const (
+	memoryStorageKind   storageKind = "memory"
+	postgresStorageKind storageKind = "postgres"
+	embeddedStorageKind storageKind = "embedded"
+)
+
 type flags struct {
 	serverAddr      string
+	grpcAddr        string
+	trustedSubnet   *net.IPNet
+	storageKind     storageKind
+	embeddedPath    string
 	storeInterval   time.Duration
 	fileStoragePath string
 	restoreData     bool
 	postgresDSN     string
 	cryptKey        string
+	cryptoKeyPath   string
+	tlsCertPath     string
+	tlsKeyPath      string
+	tlsClientCAPath string
+	rulesPath       string
+	rulesInterval   time.Duration
+	webhookURLs     []string
+	notifyFilePath  string
+	notifyRepeat    time.Duration
+	notifyGroupBy   string
+
+	historyRetention  time.Duration
+	historyResolution time.Duration
+	shutdownTimeout   time.Duration
+
+	dbMaintenanceInterval time.Duration
+	dbRawRetention        time.Duration
+	dbMinuteRetention     time.Duration
+	dbRetention           time.Duration
+	dbMaxConns            int32
+	dbConnectTimeout      time.Duration
+	dbQueryTimeout        time.Duration
 }
 
 func initFlags() (flags, error) {
 	serverAddr := flag.String("a", ":8080", "The address to bind the server to")
-	storeInterval := flag.Int64("i", 300, "The interval to save data to file")
+	grpcAddr := flag.String("grpc-address", "", "The address to bind the gRPC server to, empty disables gRPC")
+	storeInterval := flag.Int64("i", 300, "The interval to save metrics snapshot to file, 0 syncs every update to the write-ahead log")
 	fileStoragePath := flag.String("f", "data.txt", "The address to metric file")
 	restore := flag.Bool("r", false, "The flag to restore data from file")
 	postgresDSN := flag.String("d", "", "The flag to Postgres DSN")
+	storage := flag.String("storage", "",
+		"The metric storage: memory, postgres or embedded:<dir>, by default postgres if DSN is set, otherwise memory")
 	cryptKey := flag.String("k", "", "crypt request key")
+	cryptoKeyPath := flag.String("crypto-key", "", "The path to PEM private key to decrypt agent requests with")
+	tlsCertPath := flag.String("tls-cert", "", "The path to PEM certificate to serve HTTPS and gRPC over TLS with, reloaded on SIGHUP")
+	tlsKeyPath := flag.String("tls-key", "", "The path to PEM private key of TLS certificate")
+	tlsClientCAPath := flag.String("tls-client-ca", "",
+		"The path to PEM CA certificates to verify agent certificates with, empty disables client verification")
+	trustedSubnet := flag.String("t", "", "The CIDR of agents allowed to send metrics, empty allows all")
+	configPath := flag.String("c", "", "The path to JSON config file, flags and environment variables take precedence")
+	flag.StringVar(configPath, "config", "", "The same as -c")
+	rulesPath := flag.String("rules", "", "The path to alerting rules file")
+	rulesInterval := flag.Int64("rules-interval", 15, "The interval to evaluate alerting rules")
+	webhookURLs := flag.String("webhook", "", "Comma separated webhook URLs to send alert notifications to")
+	notifyFilePath := flag.String("notify-file", "", "The path to file to append alert notifications to")
+	notifyRepeat := flag.Int64("notify-repeat", 3600, "The interval to repeat notifications for firing alerts")
+	notifyGroupBy := flag.String("notify-group-by", "rule", "The way to group alerts: rule, metric or all")
+	historyRetention := flag.Int64("history-retention", 86400,
+		"The interval to keep in memory and embedded metric history for, 0 disables history")
+	historyResolution := flag.Int64("history-resolution", 10,
+		"The interval to aggregate in memory and embedded metric history by")
+	shutdownTimeout := flag.Int64("shutdown-timeout", 10,
+		"The timeout to finish in-flight requests on SIGINT, SIGTERM or SIGQUIT before storage is flushed")
+	dbMaintenanceInterval := flag.Int64("db-maintenance-interval", 300,
+		"The interval to roll up and expire Postgres metric values, 0 disables maintenance")
+	dbRawRetention := flag.Int64("db-raw-retention", 86400,
+		"The interval to keep raw Postgres metric values for before rolling them up by minute")
+	dbMinuteRetention := flag.Int64("db-minute-retention", 604800,
+		"The interval to keep minute Postgres rollups for before rolling them up by hour")
+	dbRetention := flag.Int64("db-retention", 7776000, "The interval to keep hour Postgres rollups for")
+	dbMaxConns := flag.Int64("db-max-conns", 10, "The max number of Postgres connections")
+	dbConnectTimeout := flag.Int64("db-connect-timeout", 5, "The timeout to connect to Postgres")
+	dbQueryTimeout := flag.Int64("db-query-timeout", 5, "The timeout of a single Postgres query attempt")
 
 	flag.Parse()
 
+	configKey := "CONFIG"
+	if value, exist := os.LookupEnv(configKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", configKey)
+		}
+
+		configPath = &value
+	}
+
+	if *configPath != "" {
+		if err := applyConfigFile(flag.CommandLine, *configPath); err != nil {
+			return flags{}, err
+		}
+	}
+
 	if value := os.Getenv("ADDRESS"); value != "" {
 		serverAddr = &value
 	}
 
+	grpcAddrKey := "GRPC_ADDRESS"
+	if value, exist := os.LookupEnv(grpcAddrKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", grpcAddrKey)
+		}
+
+		grpcAddr = &value
+	}
+
 	storeIntervalKey := "STORE_INTERVAL"
 	if value, exist := os.LookupEnv(storeIntervalKey); exist {
 		if value == "" {
 			return flags{}, fmt.Errorf("%s environment variable not set", storeIntervalKey)
 		}
 
-		val, err := parseIntervalValue(value)
+		// нулевой интервал включает синхронную запись журнала
+		val, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
 			return flags{}, fmt.Errorf("failed to parse %s: %w", storeIntervalKey, err)
 		}
+		if val < 0 {
+			return flags{}, fmt.Errorf("invalid %s: %s", storeIntervalKey, value)
+		}
 		storeInterval = &val
 	}
 
@@ -76,6 +176,20 @@ func initFlags() (flags, error) {
 		postgresDSN = &value
 	}
 
+	storageKey := "STORAGE"
+	if value, exist := os.LookupEnv(storageKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", storageKey)
+		}
+
+		storage = &value
+	}
+
+	kind, embeddedPath, err := parseStorage(*storage, *postgresDSN)
+	if err != nil {
+		return flags{}, err
+	}
+
 	cryptKeyKey := "KEY"
 	if value, exist := os.LookupEnv(cryptKeyKey); exist {
 		if value == "" {
@@ -85,24 +199,299 @@ func initFlags() (flags, error) {
 		cryptKey = &value
 	}
 
+	cryptoKeyPathKey := "CRYPTO_KEY"
+	if value, exist := os.LookupEnv(cryptoKeyPathKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", cryptoKeyPathKey)
+		}
+
+		cryptoKeyPath = &value
+	}
+
+	for key, value := range map[string]*string{
+		"TLS_CERT":      tlsCertPath,
+		"TLS_KEY":       tlsKeyPath,
+		"TLS_CLIENT_CA": tlsClientCAPath,
+	} {
+		envValue, exist := os.LookupEnv(key)
+		if !exist {
+			continue
+		}
+		if envValue == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", key)
+		}
+
+		*value = envValue
+	}
+
+	if (*tlsCertPath == "") != (*tlsKeyPath == "") {
+		return flags{}, errors.New("tls certificate and key must be set together")
+	}
+	if *tlsClientCAPath != "" && *tlsCertPath == "" {
+		return flags{}, errors.New("tls client CA requires tls certificate")
+	}
+
+	trustedSubnetKey := "TRUSTED_SUBNET"
+	if value, exist := os.LookupEnv(trustedSubnetKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", trustedSubnetKey)
+		}
+
+		trustedSubnet = &value
+	}
+
+	var subnet *net.IPNet
+	if *trustedSubnet != "" {
+		var err error
+		_, subnet, err = net.ParseCIDR(*trustedSubnet)
+		if err != nil {
+			return flags{}, fmt.Errorf("failed to parse trusted subnet: %w", err)
+		}
+	}
+
+	rulesPathKey := "RULES_FILE"
+	if value, exist := os.LookupEnv(rulesPathKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", rulesPathKey)
+		}
+
+		rulesPath = &value
+	}
+
+	rulesIntervalKey := "RULES_INTERVAL"
+	if value, exist := os.LookupEnv(rulesIntervalKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", rulesIntervalKey)
+		}
+
+		val, err := parseIntervalValue(rulesIntervalKey, value)
+		if err != nil {
+			return flags{}, err
+		}
+		rulesInterval = &val
+	}
+
+	webhookURLsKey := "WEBHOOK_URLS"
+	if value, exist := os.LookupEnv(webhookURLsKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", webhookURLsKey)
+		}
+
+		webhookURLs = &value
+	}
+
+	notifyFilePathKey := "NOTIFY_FILE"
+	if value, exist := os.LookupEnv(notifyFilePathKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", notifyFilePathKey)
+		}
+
+		notifyFilePath = &value
+	}
+
+	notifyRepeatKey := "NOTIFY_REPEAT_INTERVAL"
+	if value, exist := os.LookupEnv(notifyRepeatKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", notifyRepeatKey)
+		}
+
+		val, err := parseIntervalValue(notifyRepeatKey, value)
+		if err != nil {
+			return flags{}, err
+		}
+		notifyRepeat = &val
+	}
+
+	notifyGroupByKey := "NOTIFY_GROUP_BY"
+	if value, exist := os.LookupEnv(notifyGroupByKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", notifyGroupByKey)
+		}
+
+		notifyGroupBy = &value
+	}
+
+	historyRetentionKey := "HISTORY_RETENTION"
+	if value, exist := os.LookupEnv(historyRetentionKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", historyRetentionKey)
+		}
+
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return flags{}, fmt.Errorf("failed to parse %s: %w", historyRetentionKey, err)
+		}
+		historyRetention = &val
+	}
+
+	historyResolutionKey := "HISTORY_RESOLUTION"
+	if value, exist := os.LookupEnv(historyResolutionKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", historyResolutionKey)
+		}
+
+		val, err := parseIntervalValue(historyResolutionKey, value)
+		if err != nil {
+			return flags{}, err
+		}
+		historyResolution = &val
+	}
+
+	shutdownTimeoutKey := "SHUTDOWN_TIMEOUT"
+	if value, exist := os.LookupEnv(shutdownTimeoutKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", shutdownTimeoutKey)
+		}
+
+		val, err := parseIntervalValue(shutdownTimeoutKey, value)
+		if err != nil {
+			return flags{}, err
+		}
+		shutdownTimeout = &val
+	}
+
+	dbMaintenanceIntervalKey := "DATABASE_MAINTENANCE_INTERVAL"
+	if value, exist := os.LookupEnv(dbMaintenanceIntervalKey); exist {
+		if value == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", dbMaintenanceIntervalKey)
+		}
+
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return flags{}, fmt.Errorf("failed to parse %s: %w", dbMaintenanceIntervalKey, err)
+		}
+		dbMaintenanceInterval = &val
+	}
+
+	for key, value := range map[string]*int64{
+		"DATABASE_RAW_RETENTION":    dbRawRetention,
+		"DATABASE_MINUTE_RETENTION": dbMinuteRetention,
+		"DATABASE_RETENTION":        dbRetention,
+		"DATABASE_MAX_CONNS":        dbMaxConns,
+		"DATABASE_CONNECT_TIMEOUT":  dbConnectTimeout,
+		"DATABASE_QUERY_TIMEOUT":    dbQueryTimeout,
+	} {
+		envValue, exist := os.LookupEnv(key)
+		if !exist {
+			continue
+		}
+		if envValue == "" {
+			return flags{}, fmt.Errorf("%s environment variable not set", key)
+		}
+
+		val, err := parseIntervalValue(key, envValue)
+		if err != nil {
+			return flags{}, err
+		}
+		*value = val
+	}
+
+	if *dbMaxConns <= 0 || *dbMaxConns > math.MaxInt32 {
+		return flags{}, fmt.Errorf("invalid db max conns: %d", *dbMaxConns)
+	}
+
+	if *historyRetention < 0 {
+		return flags{}, fmt.Errorf("invalid history retention: %d", *historyRetention)
+	}
+
+	if *historyResolution <= 0 {
+		return flags{}, fmt.Errorf("invalid history resolution: %d", *historyResolution)
+	}
+
+	if *shutdownTimeout <= 0 {
+		return flags{}, fmt.Errorf("invalid shutdown timeout: %d", *shutdownTimeout)
+	}
+
+	if *rulesInterval <= 0 {
+		return flags{}, fmt.Errorf("invalid rules interval: %d", *rulesInterval)
+	}
+
 	return flags{
 		serverAddr:      *serverAddr,
+		grpcAddr:        *grpcAddr,
+		trustedSubnet:   subnet,
+		storageKind:     kind,
+		embeddedPath:    embeddedPath,
 		storeInterval:   time.Duration(*storeInterval) * time.Second,
 		fileStoragePath: *fileStoragePath,
 		restoreData:     *restore,
 		postgresDSN:     *postgresDSN,
 		cryptKey:        *cryptKey,
+		cryptoKeyPath:   *cryptoKeyPath,
+		tlsCertPath:     *tlsCertPath,
+		tlsKeyPath:      *tlsKeyPath,
+		tlsClientCAPath: *tlsClientCAPath,
+		rulesPath:       *rulesPath,
+		rulesInterval:   time.Duration(*rulesInterval) * time.Second,
+		webhookURLs:     splitList(*webhookURLs),
+		notifyFilePath:  *notifyFilePath,
+		notifyRepeat:    time.Duration(*notifyRepeat) * time.Second,
+		notifyGroupBy:   *notifyGroupBy,
+
+		historyRetention:  time.Duration(*historyRetention) * time.Second,
+		historyResolution: time.Duration(*historyResolution) * time.Second,
+		shutdownTimeout:   time.Duration(*shutdownTimeout) * time.Second,
+
+		dbMaintenanceInterval: time.Duration(*dbMaintenanceInterval) * time.Second,
+		dbRawRetention:        time.Duration(*dbRawRetention) * time.Second,
+		dbMinuteRetention:     time.Duration(*dbMinuteRetention) * time.Second,
+		dbRetention:           time.Duration(*dbRetention) * time.Second,
+		dbMaxConns:            int32(*dbMaxConns),
+		dbConnectTimeout:      time.Duration(*dbConnectTimeout) * time.Second,
+		dbQueryTimeout:        time.Duration(*dbQueryTimeout) * time.Second,
 	}, nil
 }
 
-func parseIntervalValue(value string) (int64, error) {
+// parseIntervalValue разбирает положительное значение переменной окружения key.
+func parseIntervalValue(key, value string) (int64, error) {
 	intValue, err := strconv.ParseInt(value, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse %s: %w", value, err)
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
 	}
 	if intValue <= 0 {
-		return 0, fmt.Errorf("invalid POLL_INTERVAL: %s", value)
+		return 0, fmt.Errorf("invalid %s: %s", key, value)
 	}
 
 	return intValue, nil
 }
+
+// parseStorage разбирает тип хранилища вида memory, postgres или embedded:<dir>.
+func parseStorage(value, postgresDSN string) (storageKind, string, error) {
+	kind, path, _ := strings.Cut(value, ":")
+	switch storageKind(kind) {
+	case "":
+		if postgresDSN != "" {
+			return postgresStorageKind, "", nil
+		}
+		return memoryStorageKind, "", nil
+	case memoryStorageKind:
+		return memoryStorageKind, "", nil
+	case postgresStorageKind:
+		if postgresDSN == "" {
+			return "", "", errors.New("postgres storage requires DSN")
+		}
+		return postgresStorageKind, "", nil
+	case embeddedStorageKind:
+		if path == "" {
+			return "", "", errors.New("embedded storage requires dir: embedded:<dir>")
+		}
+		return embeddedStorageKind, path, nil
+	}
+
+	return "", "", fmt.Errorf("unknown storage %q", value)
+}
+
+// splitList разбирает список значений, разделенных запятой.
+func splitList(value string) []string {
+	res := make([]string, 0)
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		res = append(res, v)
+	}
+
+	return res
+}
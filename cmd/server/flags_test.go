@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestParseIntervalValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    int64
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			key:   "DATABASE_QUERY_TIMEOUT",
+			value: "5",
+			want:  5,
+		},
+		{
+			name:    "not a number",
+			key:     "DATABASE_MAX_CONNS",
+			value:   "ten",
+			wantErr: `failed to parse DATABASE_MAX_CONNS: strconv.ParseInt: parsing "ten": invalid syntax`,
+		},
+		{
+			name:    "not positive",
+			key:     "DATABASE_RETENTION",
+			value:   "0",
+			wantErr: "invalid DATABASE_RETENTION: 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseIntervalValue(tt.key, tt.value)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("parseIntervalValue() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseIntervalValue() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
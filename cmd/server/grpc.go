@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	// регистрирует gzip для запросов агентов, отправляющих метрики со сжатием
+	_ "google.golang.org/grpc/encoding/gzip"
+
+	serviceGRPC "github.com/kdv2001/onlyMetrics/internal/handlers/grpc"
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// startGRPCServer запускает grpc сервер метрик на адресе addr.
+// При заданной подсети метрики принимаются только от агентов из нее.
+// При непустом tlsConfig сервер принимает только TLS соединения.
+func startGRPCServer(ctx context.Context, addr string, trustedSubnet *net.IPNet, tlsConfig *tls.Config,
+	metricsUC *metrics.UseCases, sugarLogger *zap.SugaredLogger) (*grpc.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen grpc address: %w", err)
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			serviceGRPC.LoggerUnaryInterceptor(sugarLogger),
+			serviceGRPC.TLSAgentUnaryInterceptor(),
+			serviceGRPC.TrustedSubnetUnaryInterceptor(trustedSubnet),
+		),
+		grpc.ChainStreamInterceptor(
+			serviceGRPC.LoggerStreamInterceptor(sugarLogger),
+			serviceGRPC.TLSAgentStreamInterceptor(),
+			serviceGRPC.TrustedSubnetStreamInterceptor(trustedSubnet),
+		),
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+
+	server := grpc.NewServer(opts...)
+	metricspb.RegisterMetricsServer(server, serviceGRPC.NewHandlers(metricsUC))
+
+	logger.Infof(ctx, "serving grpc metrics on %s", lis.Addr())
+	go func() {
+		if err := server.Serve(lis); err != nil {
+			logger.Errorf(ctx, "error serve grpc: %v", err)
+		}
+	}()
+
+	return server, nil
+}
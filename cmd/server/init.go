@@ -4,17 +4,23 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/jackc/pgx/v5"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 	"go.uber.org/zap"
 
 	_ "github.com/kdv2001/onlyMetrics/docs"
+	notificationsClients "github.com/kdv2001/onlyMetrics/internal/clients/notifications"
 	sericeHttp "github.com/kdv2001/onlyMetrics/internal/handlers/http"
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/embedded"
 	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/memory"
 	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/postgres"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/alerts"
 	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/notifications"
+	"github.com/kdv2001/onlyMetrics/pkg/graceful"
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
 	"github.com/kdv2001/onlyMetrics/pkg/logger"
 )
 
@@ -25,27 +31,52 @@ func initService() error {
 		return fmt.Errorf("failed to init flags: %w", err)
 	}
 
+	// сигналы перехватываются до инициализации, чтобы при завершении во время запуска
+	// выполнялись отложенные вызовы и сохранялось хранилище. Хранилище использует ctx,
+	// так как должно принимать метрики до вызова Close
+	shutdownCtx, stop := graceful.NotifyContext(ctx)
+	defer stop()
+
 	var metricsStorage metrics.MetricStorage
-	if parsedFlags.postgresDSN != "" {
-		conn, err := pgx.Connect(ctx, parsedFlags.postgresDSN)
+	switch parsedFlags.storageKind {
+	case postgresStorageKind:
+		pool, err := postgres.NewPool(ctx, parsedFlags.postgresDSN, postgres.PoolConfig{
+			MaxConns:       parsedFlags.dbMaxConns,
+			ConnectTimeout: parsedFlags.dbConnectTimeout,
+		})
 		if err != nil {
 			return err
 		}
-		defer conn.Close(ctx)
 
-		err = conn.Ping(ctx)
+		postgresStorage, err := postgres.NewStorage(ctx, pool,
+			postgres.WithQueryTimeoutOpt(parsedFlags.dbQueryTimeout),
+			postgres.WithMaintenanceOpt(postgres.MaintenanceConfig{
+				Interval:        parsedFlags.dbMaintenanceInterval,
+				RawRetention:    parsedFlags.dbRawRetention,
+				MinuteRetention: parsedFlags.dbMinuteRetention,
+				Retention:       parsedFlags.dbRetention,
+			}))
 		if err != nil {
+			pool.Close()
 			return err
 		}
-		postgresStorage, err := postgres.NewStorage(ctx, conn)
+		defer postgresStorage.Close(ctx)
+		metricsStorage = postgresStorage
+	case embeddedStorageKind:
+		embeddedStorage, err := embedded.NewStorage(ctx, parsedFlags.embeddedPath,
+			embedded.WithHistoryOpt(parsedFlags.historyRetention, parsedFlags.historyResolution))
+		if err != nil {
+			return err
+		}
+		defer embeddedStorage.Close(ctx)
+		metricsStorage = embeddedStorage
+	default:
+		memoryStorage, err := memory.NewStorage(ctx, parsedFlags.fileStoragePath,
+			parsedFlags.storeInterval, parsedFlags.restoreData,
+			memory.WithHistoryOpt(parsedFlags.historyRetention, parsedFlags.historyResolution))
 		if err != nil {
 			return err
 		}
-		defer postgresStorage.Close(ctx)
-		metricsStorage = postgresStorage
-	} else {
-		memoryStorage := memory.NewStorage(ctx, parsedFlags.fileStoragePath,
-			parsedFlags.storeInterval, parsedFlags.restoreData)
 		defer memoryStorage.Close(ctx)
 		metricsStorage = memoryStorage
 	}
@@ -53,17 +84,54 @@ func initService() error {
 	metricsUC := metrics.NewUseCases(metricsStorage)
 	httpHandlers := sericeHttp.NewHandlers(metricsUC)
 
-	chiMux := chi.NewMux()
+	alertsOpts, err := initNotifications(shutdownCtx, parsedFlags)
+	if err != nil {
+		return fmt.Errorf("failed to init notifications: %w", err)
+	}
+
+	alertsUC, err := alerts.NewUseCases(shutdownCtx, metricsStorage, parsedFlags.rulesPath,
+		parsedFlags.rulesInterval, alertsOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to init alerts: %w", err)
+	}
+	alertHandlers := sericeHttp.NewAlertHandlers(alertsUC)
+
 	log, err := zap.NewDevelopment()
 	if err != nil {
 		return fmt.Errorf("failed to init looger: %w", err)
 	}
+	sugarLogger := log.Sugar()
+
+	tlsConfig, err := initTLS(ctx, parsedFlags)
+	if err != nil {
+		return err
+	}
+
+	if parsedFlags.grpcAddr != "" {
+		grpcServer, err := startGRPCServer(ctx, parsedFlags.grpcAddr, parsedFlags.trustedSubnet,
+			tlsConfig, metricsUC, sugarLogger)
+		if err != nil {
+			return err
+		}
+		// сервер останавливается до закрытия хранилища, чтобы принятые метрики были сохранены
+		defer graceful.StopGRPC(grpcServer, parsedFlags.shutdownTimeout)
+	}
+
+	chiMux := chi.NewMux()
 	if parsedFlags.cryptKey != "" {
 		chiMux.Use(sericeHttp.NewSha256Middleware(parsedFlags.cryptKey))
 	}
+	if parsedFlags.cryptoKeyPath != "" {
+		privateKey, err := hybrid.LoadPrivateKey(parsedFlags.cryptoKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to load crypto key: %w", err)
+		}
+		// тело расшифровывается до распаковки в DecompressMiddleware
+		chiMux.Use(sericeHttp.DecryptMiddleware(privateKey))
+	}
 
-	sugarLogger := log.Sugar()
 	chiMux.Use(
+		sericeHttp.TLSAgentMiddleware(),
 		sericeHttp.CompressMiddleware(sericeHttp.GetDefaultAcceptedEncodingData()),
 		sericeHttp.DecompressMiddleware(),
 		sericeHttp.AddLoggerToContextMiddleware(sugarLogger),
@@ -71,16 +139,19 @@ func initService() error {
 		sericeHttp.RequestMiddleware())
 
 	chiMux.Get("/", httpHandlers.GetAllMetric)
+	chiMux.Get("/metrics", httpHandlers.GetPrometheusMetrics)
 
 	chiMux.Route("/ping", func(r chi.Router) {
 		r.Get("/", httpHandlers.GetPing)
 	})
 
 	chiMux.Route("/updates", func(r chi.Router) {
+		r.Use(sericeHttp.TrustedSubnetMiddleware(parsedFlags.trustedSubnet))
 		r.Post("/", httpHandlers.UpdateMetrics)
 	})
 
 	chiMux.Route("/update", func(r chi.Router) {
+		r.Use(sericeHttp.TrustedSubnetMiddleware(parsedFlags.trustedSubnet))
 		r.Post("/", httpHandlers.CollectBodyMetric)
 		r.Route(fmt.Sprintf("/{%s}/{%s}/{%s}",
 			sericeHttp.MetricTypePathKey,
@@ -101,14 +172,67 @@ func initService() error {
 		})
 	})
 
+	chiMux.Route("/api/v1", func(r chi.Router) {
+		r.Get("/alerts", alertHandlers.GetAlerts)
+		r.Get("/agents", httpHandlers.GetAgents)
+		r.Get(fmt.Sprintf("/agents/{%s}", sericeHttp.AgentNamePathKey), httpHandlers.GetAgent)
+		r.Get("/query_range", httpHandlers.QueryRange)
+	})
+
 	chiMux.Get("/swagger/*", httpSwagger.Handler())
 
+	if shutdownCtx.Err() != nil {
+		logger.Infof(ctx, "shutting down server")
+		return nil
+	}
+
 	logger.Infof(ctx, "serving metrics on port %s", parsedFlags.serverAddr)
 
-	err = http.ListenAndServe(parsedFlags.serverAddr, chiMux)
+	server := &http.Server{
+		Addr:      parsedFlags.serverAddr,
+		Handler:   chiMux,
+		TLSConfig: tlsConfig,
+	}
+	serve := server.ListenAndServe
+	if tlsConfig != nil {
+		// сертификат берется из TLSConfig, что позволяет перечитывать его без перезапуска
+		serve = func() error {
+			return server.ListenAndServeTLS("", "")
+		}
+	}
+
+	// по сигналу сервер дожидается обработки принятых запросов, после чего
+	// отложенные вызовы останавливают grpc сервер и сохраняют хранилище
+	err = graceful.Serve(shutdownCtx, server, serve, parsedFlags.shutdownTimeout)
+	logger.Infof(ctx, "shutting down server")
+
+	return err
+}
+
+// initNotifications создает опции алертинга для рассылки уведомлений на настроенные webhook и в файл.
+func initNotifications(ctx context.Context, parsedFlags flags) ([]alerts.Option, error) {
+	groupBy, err := notifications.NewGroupByFromString(parsedFlags.notifyGroupBy)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Timeout: time.Second * 5,
 	}
 
-	return nil
+	senders := make([]notifications.Sender, 0, len(parsedFlags.webhookURLs)+1)
+	for _, u := range parsedFlags.webhookURLs {
+		senders = append(senders, notificationsClients.NewWebhookClient(httpClient, u))
+	}
+	if parsedFlags.notifyFilePath != "" {
+		senders = append(senders, notificationsClients.NewFileClient(parsedFlags.notifyFilePath))
+	}
+
+	if len(senders) == 0 {
+		return nil, nil
+	}
+
+	notificationsUC := notifications.NewUseCases(ctx, groupBy, parsedFlags.notifyRepeat, senders...)
+
+	return []alerts.Option{alerts.WithNotifierOpt(notificationsUC)}, nil
 }
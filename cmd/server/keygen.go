@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
+)
+
+const keygenUsage = "usage: server keygen [-bits 4096] [-private private.pem] [-public public.pem]"
+
+// runKeygen выполняет подкоманду keygen: создание пары ключей RSA для шифрования запросов агентов.
+// Закрытый ключ передается серверу, открытый - агентам в флаге -crypto-key.
+func runKeygen(args []string) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	bits := fs.Int("bits", 4096, "The RSA key size in bits")
+	privatePath := fs.String("private", "private.pem", "The path to write server private key to")
+	publicPath := fs.String("public", "public.pem", "The path to write agent public key to")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return errors.New(keygenUsage)
+	}
+
+	if err := hybrid.GenerateKeyFiles(*privatePath, *publicPath, *bits); err != nil {
+		return err
+	}
+
+	fmt.Printf("private key: %s\npublic key: %s\n", *privatePath, *publicPath)
+
+	return nil
+}
@@ -1,8 +1,25 @@
 package main
 
-import "log"
+import (
+	"log"
+	"os"
+)
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("failed to migrate: %v", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		if err := runKeygen(os.Args[2:]); err != nil {
+			log.Fatalf("failed to generate keys: %v", err)
+		}
+		return
+	}
+
 	if err := initService(); err != nil {
 		log.Fatalf("failed to initialize service: %v", err)
 	}
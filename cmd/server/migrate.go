@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/postgres"
+)
+
+const migrateUsage = "usage: server migrate [-d dsn] up | down [steps] | status"
+
+// runMigrate выполняет подкоманду migrate: применение, откат или вывод состояния миграций схемы БД.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	postgresDSN := fs.String("d", "", "The flag to Postgres DSN")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	dataBaseDSNKey := "DATABASE_DSN"
+	if value, exist := os.LookupEnv(dataBaseDSNKey); exist {
+		if value == "" {
+			return fmt.Errorf("%s environment variable not set", dataBaseDSNKey)
+		}
+
+		postgresDSN = &value
+	}
+
+	if *postgresDSN == "" {
+		return errors.New("postgres DSN not set")
+	}
+
+	if fs.NArg() == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	ctx := context.Background()
+	pool, err := postgres.NewPool(ctx, *postgresDSN, postgres.PoolConfig{MaxConns: 1})
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	migrator, err := postgres.NewMigrator(pool)
+	if err != nil {
+		return err
+	}
+
+	switch fs.Arg(0) {
+	case "up":
+		if err = migrator.Check(ctx); err != nil {
+			return err
+		}
+
+		return migrator.Up(ctx)
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			steps, err = strconv.Atoi(fs.Arg(1))
+			if err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q", fs.Arg(1))
+			}
+		}
+
+		return migrator.Down(ctx, steps)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			if !s.AppliedAt.IsZero() {
+				state = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+		return migrator.Check(ctx)
+	}
+
+	return errors.New(migrateUsage)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+	"github.com/kdv2001/onlyMetrics/pkg/tlsutil"
+)
+
+// initTLS загружает сертификат сервера и перечитывает его по сигналу SIGHUP.
+// Возвращает nil, если TLS не настроен.
+func initTLS(ctx context.Context, parsedFlags flags) (*tls.Config, error) {
+	if parsedFlags.tlsCertPath == "" {
+		return nil, nil
+	}
+
+	reloader, err := tlsutil.NewCertReloader(parsedFlags.tlsCertPath, parsedFlags.tlsKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+
+	cfg, err := tlsutil.ServerConfig(reloader, parsedFlags.tlsClientCAPath)
+	if err != nil {
+		return nil, err
+	}
+
+	go reloadOnSighup(ctx, reloader)
+
+	return cfg, nil
+}
+
+// reloadOnSighup перечитывает сертификат при получении SIGHUP.
+func reloadOnSighup(ctx context.Context, reloader *tlsutil.CertReloader) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			if err := reloader.Reload(); err != nil {
+				logger.Errorf(ctx, "failed to reload tls certificate: %v", err)
+				continue
+			}
+			logger.Infof(ctx, "tls certificate reloaded")
+		}
+	}
+}
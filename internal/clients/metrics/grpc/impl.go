@@ -0,0 +1,207 @@
+// Package grpc предоставляет методы для отправки метрик на сервер по grpc.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+const retryNums = 3
+
+const (
+	// agentNameMetadataKey ключ метаданных с именем агента.
+	agentNameMetadataKey = "x-agent-name"
+	// realIPMetadataKey ключ метаданных с адресом агента.
+	realIPMetadataKey = "x-real-ip"
+)
+
+// Client клиент для отправки метрик по grpc.
+type Client struct {
+	client metricspb.MetricsClient
+
+	callOpts  []grpc.CallOption
+	labels    domain.Labels
+	agentName string
+	realIP    string
+	batchSize int
+}
+
+// clientOption опция клиента.
+type clientOption func(c *Client)
+
+// CompresGZIPOpt включает gzip сжатие.
+func CompresGZIPOpt() clientOption {
+	return func(c *Client) {
+		c.callOpts = append(c.callOpts, grpc.UseCompressor(gzip.Name))
+	}
+}
+
+// WithLabelsOpt добавляет метки ко всем отправляемым метрикам.
+// Метки самой метрики имеют приоритет над общими.
+func WithLabelsOpt(labels domain.Labels) clientOption {
+	return func(c *Client) {
+		c.labels = labels.Copy()
+	}
+}
+
+// WithAgentNameOpt задает имя агента, передаваемое серверу в метаданных x-agent-name.
+func WithAgentNameOpt(name string) clientOption {
+	return func(c *Client) {
+		c.agentName = name
+	}
+}
+
+// WithRealIPOpt задает адрес агента, передаваемый серверу в метаданных x-real-ip.
+func WithRealIPOpt(ip string) clientOption {
+	return func(c *Client) {
+		c.realIP = ip
+	}
+}
+
+// WithStreamBatchSizeOpt включает отправку наборов больше size метрик частями по size метрик в потоке.
+func WithStreamBatchSizeOpt(size int) clientOption {
+	return func(c *Client) {
+		c.batchSize = size
+	}
+}
+
+// NewClient создает клиент для отправки метрик по grpc.
+func NewClient(conn grpc.ClientConnInterface, opts ...clientOption) *Client {
+	c := &Client{
+		client: metricspb.NewMetricsClient(conn),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// SendGauge отправляет метрику типа "Градусник".
+func (c *Client) SendGauge(ctx context.Context, value domain.MetricValue) error {
+	return c.SendMetrics(ctx, []domain.MetricValue{value})
+}
+
+// SendCounter отправляет метрику типа "Счетчик".
+func (c *Client) SendCounter(ctx context.Context, value domain.MetricValue) error {
+	return c.SendMetrics(ctx, []domain.MetricValue{value})
+}
+
+// SendMetrics отправляет набор метрик.
+func (c *Client) SendMetrics(ctx context.Context, metrics []domain.MetricValue) error {
+	res := make([]*metricspb.Metric, 0, len(metrics))
+	for _, dm := range metrics {
+		m, err := c.metricFromDomain(dm)
+		if err != nil {
+			return err
+		}
+
+		res = append(res, m)
+	}
+
+	if c.agentName != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, agentNameMetadataKey, c.agentName)
+	}
+	if c.realIP != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, realIPMetadataKey, c.realIP)
+	}
+
+	send := c.sendUnary
+	if c.batchSize > 0 && len(res) > c.batchSize {
+		send = c.sendStream
+	}
+
+	var timeSleep time.Duration
+	for i := 0; i < retryNums; i++ {
+		time.Sleep(timeSleep)
+		err := send(ctx, res)
+		switch status.Code(err) {
+		case codes.OK:
+			return nil
+		case codes.Unavailable:
+			timeSleep = time.Duration(i*2+1) * time.Second
+			continue
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) sendUnary(ctx context.Context, metrics []*metricspb.Metric) error {
+	_, err := c.client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{
+		Metrics: metrics,
+	}, c.callOpts...)
+
+	return err
+}
+
+func (c *Client) sendStream(ctx context.Context, metrics []*metricspb.Metric) error {
+	stream, err := c.client.UpdateMetricsStream(ctx, c.callOpts...)
+	if err != nil {
+		return err
+	}
+
+	for len(metrics) > 0 {
+		part := metrics[:min(c.batchSize, len(metrics))]
+		metrics = metrics[len(part):]
+
+		if err = stream.Send(&metricspb.UpdateMetricsRequest{Metrics: part}); err != nil {
+			// причина ошибки отправки возвращается при закрытии потока
+			break
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+
+	return err
+}
+
+func (c *Client) metricFromDomain(v domain.MetricValue) (*metricspb.Metric, error) {
+	m := &metricspb.Metric{
+		Name:   v.Name,
+		Labels: c.mergeLabels(v.Labels),
+	}
+
+	switch v.Type {
+	case domain.GaugeMetricType:
+		m.Type = metricspb.MetricType_METRIC_TYPE_GAUGE
+		m.Value = &metricspb.Metric_Gauge{Gauge: v.GaugeValue}
+	case domain.CounterMetricType:
+		m.Type = metricspb.MetricType_METRIC_TYPE_COUNTER
+		m.Value = &metricspb.Metric_Delta{Delta: v.CounterValue}
+	default:
+		return nil, fmt.Errorf("unknown metric type: %v", v.Type)
+	}
+
+	return m, nil
+}
+
+// mergeLabels объединяет общие метки клиента с метками метрики.
+func (c *Client) mergeLabels(labels domain.Labels) map[string]string {
+	if len(c.labels) == 0 {
+		return labels
+	}
+
+	res := make(map[string]string, len(c.labels)+len(labels))
+	for name, value := range c.labels {
+		res[name] = value
+	}
+	for name, value := range labels {
+		res[name] = value
+	}
+
+	return res
+}
@@ -0,0 +1,165 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+func newTestConn(t *testing.T, srv metricspb.MetricsServer) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	metricspb.RegisterMetricsServer(s, srv)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return conn
+}
+
+func TestClient_SendMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := make([]domain.MetricValue, 0, 5)
+	for i := range 4 {
+		metrics = append(metrics, domain.MetricValue{
+			Type:       domain.GaugeMetricType,
+			Name:       "Alloc",
+			Labels:     domain.Labels{"core": string(rune('0' + i))},
+			GaugeValue: float64(i),
+		})
+	}
+	metrics = append(metrics, domain.MetricValue{
+		Type:         domain.CounterMetricType,
+		Name:         "PollCount",
+		CounterValue: 1,
+	})
+
+	tests := []struct {
+		name      string
+		server    *metricsServerMock
+		opts      []clientOption
+		metrics   []domain.MetricValue
+		wantErr   codes.Code
+		wantUnary int
+		wantParts int
+	}{
+		{
+			name:   "unary",
+			server: &metricsServerMock{},
+			opts: []clientOption{
+				CompresGZIPOpt(),
+				WithAgentNameOpt("agent-1"),
+				WithLabelsOpt(domain.Labels{"env": "prod", "core": "x"}),
+			},
+			metrics:   metrics,
+			wantUnary: 1,
+		},
+		{
+			name:      "stream",
+			server:    &metricsServerMock{},
+			opts:      []clientOption{WithStreamBatchSizeOpt(2)},
+			metrics:   metrics,
+			wantParts: 3,
+		},
+		{
+			name:      "small batch is sent unary",
+			server:    &metricsServerMock{},
+			opts:      []clientOption{WithStreamBatchSizeOpt(10)},
+			metrics:   metrics,
+			wantUnary: 1,
+		},
+		{
+			name:    "unknown metric type",
+			server:  &metricsServerMock{},
+			metrics: []domain.MetricValue{{Name: "Alloc"}},
+			wantErr: codes.Unknown,
+		},
+		{
+			name:    "server error",
+			server:  &metricsServerMock{err: status.Error(codes.InvalidArgument, "invalid")},
+			metrics: metrics,
+			wantErr: codes.InvalidArgument,
+		},
+		{
+			name:    "stream server error",
+			server:  &metricsServerMock{err: status.Error(codes.InvalidArgument, "invalid")},
+			opts:    []clientOption{WithStreamBatchSizeOpt(2)},
+			metrics: metrics,
+			wantErr: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := NewClient(newTestConn(t, tt.server), tt.opts...)
+			err := c.SendMetrics(context.Background(), tt.metrics)
+			if tt.wantErr != codes.OK {
+				if err == nil || status.Code(err) != tt.wantErr {
+					t.Fatalf("SendMetrics() error = %v, want code %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendMetrics() error = %v", err)
+			}
+
+			if tt.server.unary != tt.wantUnary || tt.server.parts != tt.wantParts {
+				t.Errorf("unary = %d, parts = %d, want %d, %d",
+					tt.server.unary, tt.server.parts, tt.wantUnary, tt.wantParts)
+			}
+			if len(tt.server.metrics) != len(tt.metrics) {
+				t.Fatalf("got %d metrics, want %d", len(tt.server.metrics), len(tt.metrics))
+			}
+		})
+	}
+
+	t.Run("agent name, real ip and labels", func(t *testing.T) {
+		t.Parallel()
+
+		server := &metricsServerMock{}
+		c := NewClient(newTestConn(t, server),
+			WithAgentNameOpt("agent-1"),
+			WithRealIPOpt("192.168.1.10"),
+			WithLabelsOpt(domain.Labels{"env": "prod", "core": "x"}))
+		if err := c.SendMetrics(context.Background(), metrics[:1]); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(server.agent) != 1 || server.agent[0] != "agent-1" {
+			t.Errorf("agent = %v, want [agent-1]", server.agent)
+		}
+		if len(server.realIP) != 1 || server.realIP[0] != "192.168.1.10" {
+			t.Errorf("real ip = %v, want [192.168.1.10]", server.realIP)
+		}
+		want := domain.Labels{"env": "prod", "core": "0"}
+		if got := domain.Labels(server.metrics[0].GetLabels()); !got.Equal(want) {
+			t.Errorf("labels = %v, want %v", got, want)
+		}
+	})
+}
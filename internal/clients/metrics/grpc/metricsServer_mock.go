@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+type metricsServerMock struct {
+	metricspb.UnimplementedMetricsServer
+	err error
+
+	mu      sync.Mutex
+	agent   []string
+	realIP  []string
+	unary   int
+	parts   int
+	metrics []*metricspb.Metric
+}
+
+func (m *metricsServerMock) UpdateMetrics(ctx context.Context,
+	req *metricspb.UpdateMetricsRequest) (*metricspb.UpdateMetricsResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	md, _ := metadata.FromIncomingContext(ctx)
+	m.agent = md.Get(agentNameMetadataKey)
+	m.realIP = md.Get(realIPMetadataKey)
+	m.unary++
+	m.metrics = append(m.metrics, req.GetMetrics()...)
+
+	return &metricspb.UpdateMetricsResponse{}, nil
+}
+
+func (m *metricsServerMock) UpdateMetricsStream(stream metricspb.Metrics_UpdateMetricsStreamServer) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&metricspb.UpdateMetricsResponse{})
+		}
+		if err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		m.parts++
+		m.metrics = append(m.metrics, req.GetMetrics()...)
+		m.mu.Unlock()
+	}
+}
@@ -5,9 +5,12 @@ import "net/http"
 type httpClientMock struct {
 	err      error
 	response http.Response
+	// req последний отправленный запрос.
+	req *http.Request
 }
 
-func (c *httpClientMock) Do(_ *http.Request) (*http.Response, error) {
+func (c *httpClientMock) Do(req *http.Request) (*http.Response, error) {
+	c.req = req
 	if c.err != nil {
 		return nil, c.err
 	}
@@ -6,6 +6,7 @@ import (
 	"compress/gzip"
 	"context"
 	"crypto/hmac"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
 )
 
 const retryNums = 3
@@ -58,6 +60,14 @@ func (c *Client) send(ctx context.Context, value domain.MetricValue) error {
 		return fmt.Errorf("unknown metric type: %v", value.Type)
 	}
 
+	if len(value.Labels) > 0 {
+		query := make(url.Values, len(value.Labels))
+		for name, v := range value.Labels {
+			query.Set(name, v)
+		}
+		sendMetricURL.RawQuery = query.Encode()
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendMetricURL.String(), nil)
 	if err != nil {
 		return err
@@ -78,13 +88,26 @@ func (c *Client) send(ctx context.Context, value domain.MetricValue) error {
 	return nil
 }
 
+// metric JSON представление метрики.
+type metric struct {
+	ID     string            `json:"id"`               // Имя метрики
+	MType  string            `json:"type"`             // параметр, принимающий значение gauge или counter
+	Labels map[string]string `json:"labels,omitempty"` // Метки метрики
+	Delta  *int64            `json:"delta,omitempty"`  // Значение метрики в случае передачи counter
+	Value  *float64          `json:"value,omitempty"`  // Значение метрики в случае передачи gauge
+}
+
 // BodyClient клиент для отправки метрик на сервер в теле запроса в формате JSON.
 type BodyClient struct {
 	client    httpClient
 	serverURL url.URL
 
-	withGzip bool
-	hh       func([]byte) ([]byte, error)
+	withGzip  bool
+	hh        func([]byte) ([]byte, error)
+	labels    domain.Labels
+	agentName string
+	realIP    string
+	publicKey *rsa.PublicKey
 }
 
 // clientOption опция клиента.
@@ -117,6 +140,67 @@ func WithSHA256Opt(key string) clientOption {
 	}
 }
 
+// WithLabelsOpt добавляет метки ко всем отправляемым метрикам.
+// Метки самой метрики имеют приоритет над общими.
+func WithLabelsOpt(labels domain.Labels) clientOption {
+	return func(c *BodyClient) {
+		c.labels = labels.Copy()
+	}
+}
+
+// WithAgentNameOpt задает имя агента, передаваемое серверу в заголовке X-Agent-Name.
+func WithAgentNameOpt(name string) clientOption {
+	return func(c *BodyClient) {
+		c.agentName = name
+	}
+}
+
+// WithRealIPOpt задает адрес агента, передаваемый серверу в заголовке X-Real-IP.
+func WithRealIPOpt(ip string) clientOption {
+	return func(c *BodyClient) {
+		c.realIP = ip
+	}
+}
+
+// WithEncryptionOpt включает шифрование тела запроса открытым ключом сервера.
+// При nil ключе тело отправляется без шифрования.
+func WithEncryptionOpt(key *rsa.PublicKey) clientOption {
+	return func(c *BodyClient) {
+		c.publicKey = key
+	}
+}
+
+// encrypt шифрует тело запроса, если задан открытый ключ сервера.
+func (c *BodyClient) encrypt(buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if c.publicKey == nil {
+		return buf, nil
+	}
+
+	data, err := hybrid.Encrypt(c.publicKey, buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewBuffer(data), nil
+}
+
+// mergeLabels объединяет общие метки клиента с метками метрики.
+func (c *BodyClient) mergeLabels(labels domain.Labels) map[string]string {
+	if len(c.labels) == 0 {
+		return labels
+	}
+
+	res := make(map[string]string, len(c.labels)+len(labels))
+	for name, value := range c.labels {
+		res[name] = value
+	}
+	for name, value := range labels {
+		res[name] = value
+	}
+
+	return res
+}
+
 // NewBodyClient создает клиент для отправки метрик на сервер в теле запроса в формате JSON.
 func NewBodyClient(client httpClient, serverURL url.URL, opts ...clientOption) *BodyClient {
 	bc := &BodyClient{
@@ -143,16 +227,11 @@ func (c *BodyClient) SendCounter(ctx context.Context, value domain.MetricValue)
 
 func (c *BodyClient) send(ctx context.Context, value domain.MetricValue) error {
 	sendMetricURL := c.serverURL.JoinPath("update")
-	type metric struct {
-		ID    string   `json:"id"`              // Имя метрики
-		MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-		Delta *int64   `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-		Value *float64 `json:"value,omitempty"` // Значение метрики в случае передачи gauge
-	}
 
 	m := metric{
-		ID:    value.Name,
-		MType: value.Type.String(),
+		ID:     value.Name,
+		MType:  value.Type.String(),
+		Labels: c.mergeLabels(value.Labels),
 	}
 
 	switch value.Type {
@@ -186,6 +265,11 @@ func (c *BodyClient) send(ctx context.Context, value domain.MetricValue) error {
 		buf = bytes.NewBuffer(b)
 	}
 
+	buf, err = c.encrypt(buf)
+	if err != nil {
+		return err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendMetricURL.String(), buf)
 	if err != nil {
 		return err
@@ -193,6 +277,15 @@ func (c *BodyClient) send(ctx context.Context, value domain.MetricValue) error {
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Content-Encoding", "gzip")
+	if c.publicKey != nil {
+		req.Header.Set("X-Encryption", hybrid.Scheme)
+	}
+	if c.agentName != "" {
+		req.Header.Set("X-Agent-Name", c.agentName)
+	}
+	if c.realIP != "" {
+		req.Header.Set("X-Real-IP", c.realIP)
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return err
@@ -211,18 +304,13 @@ func (c *BodyClient) send(ctx context.Context, value domain.MetricValue) error {
 // SendMetrics отправляет набор метрик.
 func (c *BodyClient) SendMetrics(ctx context.Context, metrics []domain.MetricValue) error {
 	sendMetricURL := c.serverURL.JoinPath("updates")
-	type metric struct {
-		ID    string   `json:"id"`              // Имя метрики
-		MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-		Delta *int64   `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-		Value *float64 `json:"value,omitempty"` // Значение метрики в случае передачи gauge
-	}
 
 	res := make([]metric, 0, len(metrics))
 	for _, dm := range metrics {
 		m := metric{
-			ID:    dm.Name,
-			MType: dm.Type.String(),
+			ID:     dm.Name,
+			MType:  dm.Type.String(),
+			Labels: c.mergeLabels(dm.Labels),
 		}
 
 		switch dm.Type {
@@ -259,6 +347,11 @@ func (c *BodyClient) SendMetrics(ctx context.Context, metrics []domain.MetricVal
 		buf = bytes.NewBuffer(b)
 	}
 
+	buf, err = c.encrypt(buf)
+	if err != nil {
+		return err
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendMetricURL.String(), buf)
 	if err != nil {
 		return err
@@ -266,6 +359,15 @@ func (c *BodyClient) SendMetrics(ctx context.Context, metrics []domain.MetricVal
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Content-Encoding", "gzip")
+	if c.publicKey != nil {
+		req.Header.Set("X-Encryption", hybrid.Scheme)
+	}
+	if c.agentName != "" {
+		req.Header.Set("X-Agent-Name", c.agentName)
+	}
+	if c.realIP != "" {
+		req.Header.Set("X-Real-IP", c.realIP)
+	}
 
 	if c.hh != nil {
 		bufSHA, err := c.hh(buf.Bytes())
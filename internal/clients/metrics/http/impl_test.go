@@ -2,7 +2,10 @@ package http
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
 	"io"
 	"net/http"
@@ -10,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
 )
 
 func TestClient_send(t *testing.T) {
@@ -140,3 +144,142 @@ func TestClient_send(t *testing.T) {
 		})
 	}
 }
+
+func TestBodyClient_SendMetrics_agentName(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		agentName string
+	}{
+		{
+			name:      "with agent name",
+			agentName: "host-1",
+		},
+		{
+			name:      "without agent name",
+			agentName: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := &httpClientMock{
+				response: http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				},
+			}
+			c := NewBodyClient(client, url.URL{}, WithAgentNameOpt(tt.agentName))
+			err := c.SendMetrics(context.Background(), []domain.MetricValue{
+				{
+					Type:       domain.GaugeMetricType,
+					Name:       "some metric",
+					GaugeValue: 100,
+				},
+			})
+			if err != nil {
+				t.Fatalf("SendMetrics() error = %v", err)
+			}
+
+			if got := client.req.Header.Get("X-Agent-Name"); got != tt.agentName {
+				t.Errorf("X-Agent-Name got = %q, want %q", got, tt.agentName)
+			}
+		})
+	}
+}
+
+func TestBodyClient_SendMetrics_realIP(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		realIP string
+	}{
+		{
+			name:   "with real ip",
+			realIP: "192.168.1.10",
+		},
+		{
+			name:   "without real ip",
+			realIP: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			client := &httpClientMock{
+				response: http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				},
+			}
+			c := NewBodyClient(client, url.URL{}, WithRealIPOpt(tt.realIP))
+			err := c.SendMetrics(context.Background(), []domain.MetricValue{
+				{
+					Type:       domain.GaugeMetricType,
+					Name:       "some metric",
+					GaugeValue: 100,
+				},
+			})
+			if err != nil {
+				t.Fatalf("SendMetrics() error = %v", err)
+			}
+
+			if got := client.req.Header.Get("X-Real-IP"); got != tt.realIP {
+				t.Errorf("X-Real-IP got = %q, want %q", got, tt.realIP)
+			}
+		})
+	}
+}
+
+func TestBodyClient_SendMetrics_encryption(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, hybrid.MinKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &httpClientMock{
+		response: http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+		},
+	}
+	c := NewBodyClient(client, url.URL{}, CompresGZIPOpt(), WithEncryptionOpt(&key.PublicKey))
+	err = c.SendMetrics(context.Background(), []domain.MetricValue{
+		{
+			Type:       domain.GaugeMetricType,
+			Name:       "some metric",
+			GaugeValue: 100,
+		},
+	})
+	if err != nil {
+		t.Fatalf("SendMetrics() error = %v", err)
+	}
+
+	if got := client.req.Header.Get("X-Encryption"); got != hybrid.Scheme {
+		t.Errorf("X-Encryption got = %q, want %q", got, hybrid.Scheme)
+	}
+
+	body, err := io.ReadAll(client.req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := hybrid.Decrypt(key, body)
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"id":"some metric","type":"gauge","value":100}]`
+	if string(got) != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+package notifications
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// FileClient клиент для записи уведомлений в файл в формате JSONL.
+type FileClient struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileClient создает клиент для записи уведомлений в файл.
+func NewFileClient(path string) *FileClient {
+	return &FileClient{
+		path: path,
+	}
+}
+
+// Send дописывает уведомление в конец файла.
+func (c *FileClient) Send(_ context.Context, n domain.Notification) error {
+	b, err := marshalNotification(n, time.Now())
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	file, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+// Package notifications предоставляет методы для доставки уведомлений об алертах получателям:
+// по http на webhook и в локальный файл в формате JSONL.
+package notifications
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// alert JSON представление алерта в уведомлении, совпадает с представлением в API сервера.
+type alert struct {
+	Rule        string            `json:"rule"`
+	Description string            `json:"description,omitempty"`
+	State       string            `json:"state"`
+	MType       string            `json:"type"`
+	ID          string            `json:"id"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Value       float64           `json:"value"`
+	ActiveAt    time.Time         `json:"activeAt"`
+	FiredAt     *time.Time        `json:"firedAt,omitempty"`
+	ResolvedAt  *time.Time        `json:"resolvedAt,omitempty"`
+}
+
+// notification JSON представление уведомления.
+type notification struct {
+	GroupKey string    `json:"groupKey"`
+	Status   string    `json:"status"`
+	SentAt   time.Time `json:"sentAt"`
+	Alerts   []alert   `json:"alerts"`
+}
+
+func marshalNotification(n domain.Notification, sentAt time.Time) ([]byte, error) {
+	res := notification{
+		GroupKey: n.GroupKey,
+		Status:   n.Status.String(),
+		SentAt:   sentAt.UTC(),
+		Alerts:   make([]alert, 0, len(n.Alerts)),
+	}
+
+	for _, v := range n.Alerts {
+		a := alert{
+			Rule:        v.RuleName,
+			Description: v.Description,
+			State:       v.State.String(),
+			MType:       v.MetricType.String(),
+			ID:          v.MetricName,
+			Labels:      v.Labels,
+			Value:       v.Value,
+			ActiveAt:    v.ActiveAt,
+		}
+		if !v.FiredAt.IsZero() {
+			a.FiredAt = &v.FiredAt
+		}
+		if !v.ResolvedAt.IsZero() {
+			a.ResolvedAt = &v.ResolvedAt
+		}
+
+		res.Alerts = append(res.Alerts, a)
+	}
+
+	return json.Marshal(res)
+}
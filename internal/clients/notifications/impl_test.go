@@ -0,0 +1,122 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func testNotification() domain.Notification {
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return domain.Notification{
+		GroupKey: "rule=HighHeap",
+		Status:   domain.AlertStateFiring,
+		Alerts: []domain.Alert{
+			{
+				RuleName:   "HighHeap",
+				State:      domain.AlertStateFiring,
+				MetricType: domain.GaugeMetricType,
+				MetricName: "HeapAlloc",
+				Value:      100,
+				ActiveAt:   activeAt,
+				FiredAt:    activeAt.Add(time.Minute),
+			},
+		},
+	}
+}
+
+func TestWebhookClient_Send(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		client  httpClient
+		wantErr bool
+	}{
+		{
+			name: "success",
+			client: &httpClientMock{
+				response: http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "accepted",
+			client: &httpClientMock{
+				response: http.Response{
+					StatusCode: http.StatusAccepted,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "server error",
+			client: &httpClientMock{
+				response: http.Response{
+					StatusCode: http.StatusInternalServerError,
+					Body:       io.NopCloser(bytes.NewBufferString(`{}`)),
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "client error",
+			client: &httpClientMock{
+				err: errors.New("some error"),
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := NewWebhookClient(tt.client, "http://localhost/hook")
+			if err := c.Send(context.Background(), testNotification()); (err != nil) != tt.wantErr {
+				t.Errorf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFileClient_Send(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "alerts.jsonl")
+	c := NewFileClient(path)
+
+	for i := 0; i < 2; i++ {
+		if err := c.Send(context.Background(), testNotification()); err != nil {
+			t.Fatalf("Send() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+
+	var n notification
+	if err = json.Unmarshal([]byte(lines[0]), &n); err != nil {
+		t.Fatalf("error unmarshal line: %v", err)
+	}
+	if n.Status != "firing" || len(n.Alerts) != 1 || n.Alerts[0].Rule != "HighHeap" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+}
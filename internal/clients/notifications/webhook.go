@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// WebhookClient клиент для отправки уведомлений POST запросом на webhook.
+type WebhookClient struct {
+	client httpClient
+	url    string
+}
+
+// NewWebhookClient создает клиент для отправки уведомлений на webhook.
+func NewWebhookClient(client httpClient, url string) *WebhookClient {
+	return &WebhookClient{
+		client: client,
+		url:    url,
+	}
+}
+
+// Send отправляет уведомление.
+func (c *WebhookClient) Send(ctx context.Context, n domain.Notification) error {
+	b, err := marshalNotification(n, time.Now())
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewBuffer(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook %s responded with status %d", c.url, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// DefaultCgroupRoot точка монтирования иерархии cgroup v2.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// cgroupMemoryStats поля memory.stat, отправляемые с меткой stat.
+var cgroupMemoryStats = map[string]struct{}{
+	"anon":       {},
+	"file":       {},
+	"kernel":     {},
+	"shmem":      {},
+	"sock":       {},
+	"slab":       {},
+	"pgfault":    {},
+	"pgmajfault": {},
+}
+
+// cgroupCPUStats метрики полей cpu.stat, время переводится из микросекунд в секунды.
+var cgroupCPUStats = []struct {
+	key     string
+	name    string
+	seconds bool
+}{
+	{key: "usage_usec", name: "CgroupCPUUsageSeconds", seconds: true},
+	{key: "user_usec", name: "CgroupCPUUserSeconds", seconds: true},
+	{key: "system_usec", name: "CgroupCPUSystemSeconds", seconds: true},
+	{key: "nr_periods", name: "CgroupCPUPeriods"},
+	{key: "nr_throttled", name: "CgroupCPUThrottledPeriods"},
+	{key: "throttled_usec", name: "CgroupCPUThrottledSeconds", seconds: true},
+}
+
+// cgroupIOStats метрики полей io.stat.
+var cgroupIOStats = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReads",
+	"wios":   "CgroupIOWrites",
+}
+
+// Cgroup сборщик потребления памяти, процессора и ввода-вывода контрольной группой cgroup v2.
+type Cgroup struct {
+	root  string
+	group string
+}
+
+// NewCgroup создает сборщик метрик группы group иерархии cgroup v2, смонтированной в root.
+// При пустом group используется группа агента из /proc/self/cgroup, в контейнере
+// с собственным пространством имен cgroup это корень иерархии.
+func NewCgroup(root, group string) *Cgroup {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+
+	return &Cgroup{
+		root:  root,
+		group: group,
+	}
+}
+
+// Collect возвращает метрики группы с меткой cgroup. Метрики контроллеров,
+// не включенных для группы, пропускаются.
+func (c *Cgroup) Collect(_ context.Context) ([]domain.MetricValue, error) {
+	group := c.group
+	if group == "" {
+		data, err := os.ReadFile("/proc/self/cgroup")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read own cgroup: %w", err)
+		}
+
+		group, err = parseCgroupV2Path(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(c.root, group)
+	if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+		return nil, fmt.Errorf("%s is not a cgroup v2 group: %w", dir, err)
+	}
+
+	labels := domain.Labels{"cgroup": group}
+	res := make([]domain.MetricValue, 0)
+
+	if v, ok := readCgroupValue(dir, "memory.current"); ok {
+		res = append(res, gaugeValue("CgroupMemoryUsage", v, labels))
+	}
+	if v, ok := readCgroupValue(dir, "memory.max"); ok {
+		res = append(res, gaugeValue("CgroupMemoryLimit", v, labels))
+	}
+	if v, ok := readCgroupValue(dir, "memory.swap.current"); ok {
+		res = append(res, gaugeValue("CgroupSwapUsage", v, labels))
+	}
+
+	memoryStat := readCgroupFlatKeyed(dir, "memory.stat")
+	stats := make([]string, 0, len(cgroupMemoryStats))
+	for key := range memoryStat {
+		if _, exist := cgroupMemoryStats[key]; exist {
+			stats = append(stats, key)
+		}
+	}
+	sort.Strings(stats)
+	for _, key := range stats {
+		res = append(res, gaugeValue("CgroupMemoryStat", memoryStat[key],
+			domain.Labels{"cgroup": group, "stat": key}))
+	}
+
+	memoryEvents := readCgroupFlatKeyed(dir, "memory.events")
+	if v, exist := memoryEvents["oom"]; exist {
+		res = append(res, gaugeValue("CgroupMemoryOOM", v, labels))
+	}
+	if v, exist := memoryEvents["oom_kill"]; exist {
+		res = append(res, gaugeValue("CgroupMemoryOOMKill", v, labels))
+	}
+
+	cpuStat := readCgroupFlatKeyed(dir, "cpu.stat")
+	for _, s := range cgroupCPUStats {
+		v, exist := cpuStat[s.key]
+		if !exist {
+			continue
+		}
+		if s.seconds {
+			v /= 1e6
+		}
+		res = append(res, gaugeValue(s.name, v, labels))
+	}
+
+	if limit, ok := readCgroupCPULimit(dir); ok {
+		res = append(res, gaugeValue("CgroupCPULimit", limit, labels))
+	}
+
+	ioStat := readCgroupNestedKeyed(dir, "io.stat")
+	devices := make([]string, 0, len(ioStat))
+	for device := range ioStat {
+		devices = append(devices, device)
+	}
+	sort.Strings(devices)
+	for _, device := range devices {
+		deviceLabels := domain.Labels{"cgroup": group, "device": device}
+		for _, key := range []string{"rbytes", "wbytes", "rios", "wios"} {
+			if v, exist := ioStat[device][key]; exist {
+				res = append(res, gaugeValue(cgroupIOStats[key], v, deviceLabels))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// parseCgroupV2Path возвращает путь группы cgroup v2 из содержимого /proc/<pid>/cgroup.
+func parseCgroupV2Path(data []byte) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// в cgroup v2 единственная строка имеет вид 0::<путь>
+		path, found := strings.CutPrefix(scanner.Text(), "0::")
+		if found {
+			return path, nil
+		}
+	}
+
+	return "", errors.New("cgroup v2 group not found")
+}
+
+// readCgroupValue читает файл с единственным числом. Значение max считается отсутствующим.
+func readCgroupValue(dir, name string) (float64, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return v, true
+}
+
+// readCgroupCPULimit читает cpu.max и возвращает ограничение в числе ядер.
+func readCgroupCPULimit(dir string) (float64, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, "cpu.max"))
+	if err != nil {
+		return 0, false
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 || fields[0] == "max" {
+		return 0, false
+	}
+
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || period <= 0 {
+		return 0, false
+	}
+
+	return quota / period, true
+}
+
+// readCgroupFlatKeyed читает файл из строк вида "ключ значение".
+func readCgroupFlatKeyed(dir, name string) map[string]float64 {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil
+	}
+
+	res := make(map[string]float64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			continue
+		}
+		res[fields[0]] = v
+	}
+
+	return res
+}
+
+// readCgroupNestedKeyed читает файл из строк вида "устройство ключ=значение ...".
+func readCgroupNestedKeyed(dir, name string) map[string]map[string]float64 {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil
+	}
+
+	res := make(map[string]map[string]float64)
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		values := make(map[string]float64, len(fields)-1)
+		for _, field := range fields[1:] {
+			key, value, found := strings.Cut(field, "=")
+			if !found {
+				continue
+			}
+
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			values[key] = v
+		}
+		res[fields[0]] = values
+	}
+
+	return res
+}
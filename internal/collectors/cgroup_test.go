@@ -0,0 +1,179 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// cgroupFixture записывает файлы группы group во временную иерархию cgroup и возвращает ее корень.
+func cgroupFixture(t *testing.T, group string, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	dir := filepath.Join(root, group)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestCgroup_Collect(t *testing.T) {
+	t.Parallel()
+
+	const group = "/system.slice/agent.service"
+	root := cgroupFixture(t, group, map[string]string{
+		"cgroup.controllers": "cpu io memory pids\n",
+		"memory.current":     "104857600\n",
+		"memory.max":         "536870912\n",
+		"memory.stat": `anon 52428800
+file 41943040
+kernel 2097152
+shmem 0
+sock 4096
+slab 1048576
+active_anon 1024
+pgfault 1500
+pgmajfault 3
+`,
+		"memory.events": `low 0
+high 0
+max 2
+oom 1
+oom_kill 1
+`,
+		"cpu.stat": `usage_usec 2500000
+user_usec 2000000
+system_usec 500000
+nr_periods 100
+nr_throttled 5
+throttled_usec 250000
+`,
+		"cpu.max": "150000 100000\n",
+		"io.stat": `8:16 rbytes=4096 wbytes=0 rios=1 wios=0 dbytes=0 dios=0
+8:0 rbytes=1048576 wbytes=2097152 rios=10 wios=20 dbytes=0 dios=0
+`,
+	})
+
+	got, err := NewCgroup(root, group).Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	labels := domain.Labels{"cgroup": group}
+	stat := func(name string) domain.Labels {
+		return domain.Labels{"cgroup": group, "stat": name}
+	}
+	sda := domain.Labels{"cgroup": group, "device": "8:0"}
+	sdb := domain.Labels{"cgroup": group, "device": "8:16"}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("CgroupMemoryUsage", labels):            104857600,
+		domain.SeriesKey("CgroupMemoryLimit", labels):            536870912,
+		domain.SeriesKey("CgroupMemoryStat", stat("anon")):       52428800,
+		domain.SeriesKey("CgroupMemoryStat", stat("file")):       41943040,
+		domain.SeriesKey("CgroupMemoryStat", stat("pgmajfault")): 3,
+		domain.SeriesKey("CgroupMemoryOOM", labels):              1,
+		domain.SeriesKey("CgroupMemoryOOMKill", labels):          1,
+		domain.SeriesKey("CgroupCPUUsageSeconds", labels):        2.5,
+		domain.SeriesKey("CgroupCPUUserSeconds", labels):         2,
+		domain.SeriesKey("CgroupCPUSystemSeconds", labels):       0.5,
+		domain.SeriesKey("CgroupCPUPeriods", labels):             100,
+		domain.SeriesKey("CgroupCPUThrottledPeriods", labels):    5,
+		domain.SeriesKey("CgroupCPUThrottledSeconds", labels):    0.25,
+		domain.SeriesKey("CgroupCPULimit", labels):               1.5,
+		domain.SeriesKey("CgroupIOReadBytes", sda):               1048576,
+		domain.SeriesKey("CgroupIOWriteBytes", sda):              2097152,
+		domain.SeriesKey("CgroupIOReads", sda):                   10,
+		domain.SeriesKey("CgroupIOWrites", sda):                  20,
+		domain.SeriesKey("CgroupIOReadBytes", sdb):               4096,
+	})
+
+	// 2 метрики памяти, 8 полей memory.stat, 2 события, 6 полей cpu.stat, лимит и по 4 метрики двух устройств
+	if want := 2 + 8 + 2 + 6 + 1 + 2*4; len(got) != want {
+		t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), want, got)
+	}
+}
+
+func TestCgroup_Collect_unlimited(t *testing.T) {
+	t.Parallel()
+
+	// корень иерархии в контейнере с собственным пространством имен cgroup
+	root := cgroupFixture(t, "/", map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"memory.current":     "1024\n",
+		"memory.max":         "max\n",
+		"cpu.max":            "max 100000\n",
+	})
+
+	got, err := NewCgroup(root, "/").Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	labels := domain.Labels{"cgroup": "/"}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("CgroupMemoryUsage", labels): 1024,
+	})
+	if len(got) != 1 {
+		t.Errorf("Collect() = %v, want only memory usage without limits", got)
+	}
+}
+
+func TestCgroup_Collect_notCgroupV2(t *testing.T) {
+	t.Parallel()
+
+	root := cgroupFixture(t, "/", map[string]string{
+		"memory.current": "1024\n",
+	})
+	if _, err := NewCgroup(root, "/").Collect(t.Context()); err == nil {
+		t.Error("Collect() without cgroup.controllers error = nil")
+	}
+}
+
+func TestParseCgroupV2Path(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "unified hierarchy",
+			data: "0::/system.slice/agent.service\n",
+			want: "/system.slice/agent.service",
+		},
+		{
+			name: "hybrid hierarchy",
+			data: "12:memory:/docker/abc\n1:name=systemd:/docker/abc\n0::/docker/abc\n",
+			want: "/docker/abc",
+		},
+		{
+			name:    "cgroup v1 only",
+			data:    "12:memory:/docker/abc\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCgroupV2Path([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCgroupV2Path() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseCgroupV2Path() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package collectors содержит сборщики метрик агента.
+//
+// Сборщики метрик хоста читают данные из /proc и /sys с помощью gopsutil. Корневые
+// директории переопределяются переменными окружения HOST_PROC и HOST_SYS или значением
+// common.EnvKey в контексте, что позволяет собирать метрики хоста из контейнера.
+//
+// Сборщики Textfile и Exec принимают метрики приложений в текстовом формате Prometheus
+// или в формате JSON API сервера.
+package collectors
+
+import "github.com/kdv2001/onlyMetrics/internal/domain"
+
+// Имена встроенных сборщиков, используются в настройках агента.
+const (
+	RuntimeName  = "runtime"
+	MemoryName   = "memory"
+	PollName     = "poll"
+	CPUName      = "cpu"
+	LoadName     = "load"
+	DiskName     = "disk"
+	NetName      = "net"
+	SwapName     = "swap"
+	CgroupName   = "cgroup"
+	ProcessName  = "process"
+	TextfileName = "textfile"
+	ExecName     = "exec"
+)
+
+// gaugeValue создает значение метрики типа gauge.
+func gaugeValue(name string, value float64, labels domain.Labels) domain.MetricValue {
+	return domain.MetricValue{
+		Name:       name,
+		GaugeValue: value,
+		Type:       domain.GaugeMetricType,
+		Labels:     labels,
+	}
+}
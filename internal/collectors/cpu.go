@@ -0,0 +1,73 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// CPU сборщик загрузки ядер процессора.
+type CPU struct {
+	mu   sync.Mutex
+	prev map[string]cpu.TimesStat
+}
+
+// NewCPU создает сборщик загрузки ядер процессора.
+func NewCPU() *CPU {
+	return &CPU{}
+}
+
+// Collect возвращает загрузку каждого ядра в процентах CPUutilization1..N за время
+// с предыдущего сбора. Первый сбор возвращает среднюю загрузку с момента запуска системы.
+func (c *CPU) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	times, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, errors.New("no cpu times")
+	}
+
+	c.mu.Lock()
+	prev := c.prev
+	c.prev = make(map[string]cpu.TimesStat, len(times))
+	for _, t := range times {
+		c.prev[t.CPU] = t
+	}
+	c.mu.Unlock()
+
+	res := make([]domain.MetricValue, 0, len(times))
+	for i, t := range times {
+		res = append(res, domain.MetricValue{
+			Name:       "CPUutilization" + strconv.Itoa(i+1),
+			GaugeValue: busyPercent(prev[t.CPU], t),
+			Type:       domain.GaugeMetricType,
+		})
+	}
+
+	return res, nil
+}
+
+// busyPercent возвращает долю времени работы ядра между двумя замерами в процентах.
+func busyPercent(prev, cur cpu.TimesStat) float64 {
+	prevBusy, prevTotal := cpuTimes(prev)
+	curBusy, curTotal := cpuTimes(cur)
+	if curTotal <= prevTotal || curBusy < prevBusy {
+		return 0
+	}
+
+	return min(100, (curBusy-prevBusy)/(curTotal-prevTotal)*100)
+}
+
+// cpuTimes возвращает время работы и общее время ядра. Время гостевых систем
+// уже учтено в User и Nice.
+func cpuTimes(t cpu.TimesStat) (float64, float64) {
+	total := t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+
+	return total - t.Idle - t.Iowait, total
+}
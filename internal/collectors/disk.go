@@ -0,0 +1,83 @@
+package collectors
+
+import (
+	"context"
+	"sort"
+
+	"github.com/shirou/gopsutil/v4/disk"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// Disk сборщик заполненности и ввода-вывода дисков.
+type Disk struct{}
+
+// NewDisk создает сборщик метрик дисков.
+func NewDisk() *Disk {
+	return &Disk{}
+}
+
+// Collect возвращает заполненность каждой точки монтирования физических устройств
+// с метками mountpoint, device и fstype и счетчики ввода-вывода устройств с меткой device.
+// Если счетчики ввода-вывода недоступны, возвращается только заполненность.
+func (c *Disk) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.MetricValue, 0, len(partitions)*4)
+	mountpoints := make(map[string]struct{}, len(partitions))
+	for _, p := range partitions {
+		if _, exist := mountpoints[p.Mountpoint]; exist {
+			continue
+		}
+		mountpoints[p.Mountpoint] = struct{}{}
+
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil {
+			// недоступная точка монтирования не мешает сбору остальных
+			continue
+		}
+
+		labels := domain.Labels{
+			"mountpoint": p.Mountpoint,
+			"device":     p.Device,
+			"fstype":     p.Fstype,
+		}
+		res = append(res,
+			gaugeValue("DiskTotal", float64(usage.Total), labels),
+			gaugeValue("DiskFree", float64(usage.Free), labels),
+			gaugeValue("DiskUsed", float64(usage.Used), labels),
+			gaugeValue("DiskUsedPercent", usage.UsedPercent, labels),
+		)
+	}
+
+	counters, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		// счетчики ввода-вывода могут быть недоступны, например в контейнере без /proc/diskstats
+		logger.Errorf(ctx, "error collect disk io counters: %v", err)
+		return res, nil
+	}
+
+	devices := make([]string, 0, len(counters))
+	for name := range counters {
+		devices = append(devices, name)
+	}
+	sort.Strings(devices)
+
+	for _, name := range devices {
+		io := counters[name]
+		labels := domain.Labels{"device": name}
+		res = append(res,
+			gaugeValue("DiskReadBytes", float64(io.ReadBytes), labels),
+			gaugeValue("DiskWriteBytes", float64(io.WriteBytes), labels),
+			gaugeValue("DiskReadCount", float64(io.ReadCount), labels),
+			gaugeValue("DiskWriteCount", float64(io.WriteCount), labels),
+			gaugeValue("DiskIOTime", float64(io.IoTime), labels),
+		)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,208 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// execWaitDelay время ожидания закрытия вывода после завершения команды, необходимо,
+// чтобы запущенные командой дочерние процессы не задерживали сбор.
+const execWaitDelay = time.Second
+
+// ExecCommand команда, выводящая метрики в stdout.
+type ExecCommand struct {
+	// Name имя команды, используется в метке command.
+	Name string
+	// Path путь к исполняемому файлу.
+	Path string
+	// Args аргументы команды.
+	Args []string
+	// Format формат вывода, по умолчанию PrometheusFormat.
+	Format string
+	// Timeout максимальное время выполнения, по умолчанию ограничено временем сбора.
+	Timeout time.Duration
+}
+
+// execResult последний успешный результат команды.
+type execResult struct {
+	values []domain.MetricValue
+	at     time.Time
+}
+
+// Exec сборщик метрик из вывода команд.
+type Exec struct {
+	commands []ExecCommand
+	maxAge   time.Duration
+
+	mu   sync.Mutex
+	last map[string]execResult
+	// conflicts ряды, полученные от нескольких команд при прошлом сборе, по имени команды
+	// и ключу ряда, чтобы конфликт записывался в журнал один раз.
+	conflicts map[string]struct{}
+}
+
+// NewExec создает сборщик метрик из вывода команд commands. Если команда завершилась
+// с ошибкой, отправляется ее последний успешный результат, пока он не старше maxAge.
+// Нулевой maxAge отключает отправку прежних результатов.
+func NewExec(commands []ExecCommand, maxAge time.Duration) (*Exec, error) {
+	if len(commands) == 0 {
+		return nil, errors.New("no commands configured")
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("invalid exec max age: %s", maxAge)
+	}
+
+	names := make(map[string]struct{}, len(commands))
+	res := make([]ExecCommand, 0, len(commands))
+	for _, cmd := range commands {
+		switch {
+		case cmd.Name == "":
+			return nil, errors.New("command name is empty")
+		case cmd.Path == "":
+			return nil, fmt.Errorf("command %s path is empty", cmd.Name)
+		case cmd.Timeout < 0:
+			return nil, fmt.Errorf("invalid command %s timeout: %s", cmd.Name, cmd.Timeout)
+		}
+		if _, exist := names[cmd.Name]; exist {
+			return nil, fmt.Errorf("command %s already configured", cmd.Name)
+		}
+		names[cmd.Name] = struct{}{}
+
+		if cmd.Format == "" {
+			cmd.Format = PrometheusFormat
+		}
+		if cmd.Format != PrometheusFormat && cmd.Format != JSONFormat {
+			return nil, fmt.Errorf("command %s: unknown format %q", cmd.Name, cmd.Format)
+		}
+
+		res = append(res, cmd)
+	}
+
+	return &Exec{
+		commands: res,
+		maxAge:   maxAge,
+		last:     make(map[string]execResult, len(res)),
+	}, nil
+}
+
+// Collect параллельно выполняет команды и возвращает метрики из их вывода в порядке
+// настройки команд. Для каждой команды также отправляются ExecSuccess и
+// ExecDurationSeconds с меткой command. Ряд, полученный от нескольких команд, отправляется
+// от первой из них в порядке настройки, у остальных он пропускается.
+func (c *Exec) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	type commandResult struct {
+		values   []domain.MetricValue
+		duration time.Duration
+		err      error
+	}
+
+	results := make([]commandResult, len(c.commands))
+	wg := sync.WaitGroup{}
+	for i, cmd := range c.commands {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			values, err := runCommand(ctx, cmd)
+			results[i] = commandResult{values: values, duration: time.Since(start), err: err}
+		}()
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	res := make([]domain.MetricValue, 0)
+	series := make(map[string]string)
+	conflicts := make(map[string]struct{})
+	for i, cmd := range c.commands {
+		r := results[i]
+		values := r.values
+		success := 1.0
+		if r.err != nil {
+			logger.Errorf(ctx, "command %s: %v", cmd.Name, r.err)
+			success = 0
+
+			values = nil
+			if last, exist := c.last[cmd.Name]; exist && c.maxAge > 0 && now.Sub(last.at) <= c.maxAge {
+				values = withoutCounters(last.values)
+			}
+		} else {
+			c.last[cmd.Name] = execResult{values: r.values, at: now}
+		}
+
+		for _, v := range values {
+			key := v.SeriesKey()
+			if owner, exist := series[key]; exist {
+				conflict := cmd.Name + "\x00" + key
+				if _, logged := c.conflicts[conflict]; !logged {
+					logger.Errorf(ctx, "command %s: series %s already received from command %s", cmd.Name, key, owner)
+				}
+				conflicts[conflict] = struct{}{}
+				continue
+			}
+			series[key] = cmd.Name
+			res = append(res, v)
+		}
+
+		labels := domain.Labels{"command": cmd.Name}
+		res = append(res,
+			gaugeValue("ExecSuccess", success, labels),
+			gaugeValue("ExecDurationSeconds", r.duration.Seconds(), labels),
+		)
+	}
+	c.conflicts = conflicts
+
+	return res, nil
+}
+
+// withoutCounters возвращает метрики, кроме счетчиков: повторная отправка приращения
+// счетчика увеличила бы его на сервере еще раз.
+func withoutCounters(values []domain.MetricValue) []domain.MetricValue {
+	res := make([]domain.MetricValue, 0, len(values))
+	for _, v := range values {
+		if v.Type != domain.CounterMetricType {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// runCommand выполняет команду и разбирает метрики из ее вывода.
+func runCommand(ctx context.Context, command ExecCommand) ([]domain.MetricValue, error) {
+	if command.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, command.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command.Path, command.Args...)
+	cmd.WaitDelay = execWaitDelay
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("command timed out: %w", ctx.Err())
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return nil, err
+	}
+
+	return parseMetrics(out, command.Format)
+}
@@ -0,0 +1,200 @@
+package collectors
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// shellCommand возвращает команду, выполняющую скрипт script оболочкой sh.
+func shellCommand(t *testing.T, name, script string) ExecCommand {
+	t.Helper()
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	return ExecCommand{
+		Name: name,
+		Path: sh,
+		Args: []string{"-c", script},
+	}
+}
+
+func TestExec_Collect(t *testing.T) {
+	t.Parallel()
+
+	jsonCmd := shellCommand(t, "json", `echo '[{"id":"jobs_done","type":"counter","delta":5}]'`)
+	jsonCmd.Format = JSONFormat
+	slow := shellCommand(t, "slow", "sleep 5; echo slow_metric 1")
+	slow.Timeout = 50 * time.Millisecond
+
+	c, err := NewExec([]ExecCommand{
+		shellCommand(t, "prom", `echo 'queue_size{queue="default"} 12'`),
+		jsonCmd,
+		shellCommand(t, "failing", "echo failure >&2; exit 1"),
+		shellCommand(t, "invalid", "echo queue-size 1"),
+		slow,
+		shellCommand(t, "conflict", `echo 'queue_size{queue="default"} 13'`),
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewExec() error = %v", err)
+	}
+
+	start := time.Now()
+	got, err := c.Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Collect() took %s, command timeout not applied", elapsed)
+	}
+
+	command := func(name string) domain.Labels {
+		return domain.Labels{"command": name}
+	}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("queue_size", domain.Labels{"queue": "default"}): 12,
+		"jobs_done": 5,
+		domain.SeriesKey("ExecSuccess", command("prom")):     1,
+		domain.SeriesKey("ExecSuccess", command("json")):     1,
+		domain.SeriesKey("ExecSuccess", command("failing")):  0,
+		domain.SeriesKey("ExecSuccess", command("invalid")):  0,
+		domain.SeriesKey("ExecSuccess", command("slow")):     0,
+		domain.SeriesKey("ExecSuccess", command("conflict")): 1,
+	})
+
+	// 2 метрики команд и по 2 служебные метрики на каждую из 6 команд
+	if want := 2 + 6*2; len(got) != want {
+		t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), want, got)
+	}
+}
+
+func TestExec_Collect_conflict(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewExec([]ExecCommand{
+		shellCommand(t, "first", "echo 'queue_size 1'; echo 'first_only 1'"),
+		shellCommand(t, "second", "echo 'queue_size 2'; echo 'second_only 2'"),
+	}, time.Hour)
+	if err != nil {
+		t.Fatalf("NewExec() error = %v", err)
+	}
+
+	// ряд принадлежит первой команде, остальные ряды второй команды отправляются при каждом сборе
+	for range 2 {
+		got, err := c.Collect(t.Context())
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		assertGauges(t, got, map[string]float64{
+			"queue_size":  1,
+			"first_only":  1,
+			"second_only": 2,
+			domain.SeriesKey("ExecSuccess", domain.Labels{"command": "second"}): 1,
+		})
+		if want := 3 + 2*2; len(got) != want {
+			t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), want, got)
+		}
+	}
+}
+
+func TestExec_Collect_counters(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewExec([]ExecCommand{
+		shellCommand(t, "app", "printf '# TYPE jobs_total counter\\njobs_total 10\\n'"),
+	}, 0)
+	if err != nil {
+		t.Fatalf("NewExec() error = %v", err)
+	}
+
+	// одинаковый вывод команды при каждом сборе дает тот же накопленный итог
+	for range 2 {
+		got, err := c.Collect(t.Context())
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		assertGauges(t, got, map[string]float64{"jobs_total": 10})
+	}
+}
+
+func TestExec_Collect_stale(t *testing.T) {
+	t.Parallel()
+
+	cmd := shellCommand(t, "app", "exit 1")
+
+	tests := []struct {
+		name      string
+		maxAge    time.Duration
+		wantAppUp bool
+	}{
+		{name: "previous result kept", maxAge: time.Hour, wantAppUp: true},
+		{name: "previous result expired", maxAge: time.Nanosecond},
+		{name: "previous result not kept"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := NewExec([]ExecCommand{cmd}, tt.maxAge)
+			if err != nil {
+				t.Fatalf("NewExec() error = %v", err)
+			}
+			// результат предыдущего успешного выполнения команды
+			c.last["app"] = execResult{
+				values: []domain.MetricValue{
+					gaugeValue("app_up", 1, nil),
+					{Type: domain.CounterMetricType, Name: "app_requests", CounterValue: 3},
+				},
+				at: time.Now().Add(-time.Millisecond),
+			}
+
+			got, err := c.Collect(t.Context())
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+			byKey := metricsByKey(got)
+			if _, exist := byKey["app_up"]; exist != tt.wantAppUp {
+				t.Errorf("app_up collected = %v, want %v", exist, tt.wantAppUp)
+			}
+			// приращение счетчика из прежнего результата не отправляется повторно
+			if _, exist := byKey["app_requests"]; exist {
+				t.Error("Collect() replayed counter from previous result")
+			}
+		})
+	}
+}
+
+func TestNewExec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		commands []ExecCommand
+		maxAge   time.Duration
+	}{
+		{name: "no commands"},
+		{name: "empty name", commands: []ExecCommand{{Path: "/bin/true"}}},
+		{name: "empty path", commands: []ExecCommand{{Name: "a"}}},
+		{
+			name:     "duplicate name",
+			commands: []ExecCommand{{Name: "a", Path: "/bin/true"}, {Name: "a", Path: "/bin/true"}},
+		},
+		{name: "unknown format", commands: []ExecCommand{{Name: "a", Path: "/bin/true", Format: "yaml"}}},
+		{name: "negative timeout", commands: []ExecCommand{{Name: "a", Path: "/bin/true", Timeout: -1}}},
+		{name: "negative max age", commands: []ExecCommand{{Name: "a", Path: "/bin/true"}}, maxAge: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := NewExec(tt.commands, tt.maxAge); err == nil {
+				t.Error("NewExec() error = nil")
+			}
+		})
+	}
+}
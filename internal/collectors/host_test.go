@@ -0,0 +1,299 @@
+package collectors
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v4/common"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// procFixture записывает файлы дерева /proc во временную директорию и возвращает
+// контекст, в котором gopsutil читает данные из нее.
+func procFixture(t *testing.T, root string, files map[string]string) context.Context {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return context.WithValue(t.Context(), common.EnvKey, common.EnvMap{
+		common.HostProcEnvKey: root,
+		common.HostSysEnvKey:  filepath.Join(root, "sys"),
+		common.HostDevEnvKey:  filepath.Join(root, "dev"),
+		common.HostRunEnvKey:  filepath.Join(root, "run"),
+	})
+}
+
+// metricsByKey индексирует метрики по имени и меткам.
+func metricsByKey(values []domain.MetricValue) map[string]domain.MetricValue {
+	res := make(map[string]domain.MetricValue, len(values))
+	for _, v := range values {
+		res[v.SeriesKey()] = v
+	}
+
+	return res
+}
+
+func assertGauges(t *testing.T, got []domain.MetricValue, want map[string]float64) {
+	t.Helper()
+
+	byKey := metricsByKey(got)
+	if len(byKey) != len(got) {
+		t.Errorf("Collect() returned duplicate series: %v", got)
+	}
+	for key, value := range want {
+		v, exist := byKey[key]
+		if !exist {
+			t.Errorf("metric %s not collected, got %v", key, got)
+			continue
+		}
+		if v.Type != domain.GaugeMetricType || math.Abs(v.GaugeValue-value) > 1e-9 {
+			t.Errorf("metric %s = %v, want gauge %v", key, v.GaugeValue, value)
+		}
+	}
+}
+
+func TestCPU_Collect(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	ctx := procFixture(t, root, map[string]string{
+		"stat": `cpu  300 0 100 600 0 0 0 0 0 0
+cpu0 100 0 50 250 0 0 0 0 0 0
+cpu1 200 0 50 350 0 0 0 0 0 0
+intr 0
+`,
+	})
+
+	c := NewCPU()
+	got, err := c.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	// первый сбор возвращает загрузку с момента запуска системы
+	assertGauges(t, got, map[string]float64{
+		"CPUutilization1": 37.5,
+		"CPUutilization2": 41.66666666666667,
+	})
+
+	procFixture(t, root, map[string]string{
+		"stat": `cpu  400 0 150 750 0 0 0 0 0 0
+cpu0 150 0 75 275 100 0 0 0 0 0
+cpu1 250 0 75 575 0 0 0 0 0 0
+intr 0
+`,
+	})
+	got, err = c.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Collect() = %v, want 2 cores", got)
+	}
+	// cpu0: 75 из 200, время ожидания ввода-вывода не считается работой; cpu1: 75 из 300
+	assertGauges(t, got, map[string]float64{
+		"CPUutilization1": 37.5,
+		"CPUutilization2": 25,
+	})
+}
+
+func TestCPU_Collect_noStat(t *testing.T) {
+	t.Parallel()
+
+	ctx := procFixture(t, t.TempDir(), nil)
+	if _, err := NewCPU().Collect(ctx); err == nil {
+		t.Error("Collect() without /proc/stat error = nil")
+	}
+}
+
+func TestLoad_Collect(t *testing.T) {
+	t.Parallel()
+
+	ctx := procFixture(t, t.TempDir(), map[string]string{
+		"loadavg": "0.50 1.25 2.00 3/512 12345\n",
+	})
+
+	got, err := NewLoad().Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	assertGauges(t, got, map[string]float64{
+		"LoadAverage1":  0.5,
+		"LoadAverage5":  1.25,
+		"LoadAverage15": 2,
+	})
+}
+
+func TestSwap_Collect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		meminfo string
+		want    map[string]float64
+	}{
+		{
+			name: "swap enabled",
+			meminfo: `MemTotal:        8192 kB
+MemFree:         4096 kB
+MemAvailable:    6144 kB
+SwapTotal:       2048 kB
+SwapFree:         512 kB
+`,
+			want: map[string]float64{
+				"SwapTotal":       2048 * 1024,
+				"SwapFree":        512 * 1024,
+				"SwapUsed":        1536 * 1024,
+				"SwapUsedPercent": 75,
+			},
+		},
+		{
+			name: "swap disabled",
+			meminfo: `MemTotal:        8192 kB
+MemFree:         4096 kB
+MemAvailable:    6144 kB
+SwapTotal:          0 kB
+SwapFree:           0 kB
+`,
+			want: map[string]float64{
+				"SwapTotal":       0,
+				"SwapFree":        0,
+				"SwapUsed":        0,
+				"SwapUsedPercent": 0,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := procFixture(t, t.TempDir(), map[string]string{"meminfo": tt.meminfo})
+			got, err := NewSwap().Collect(ctx)
+			if err != nil {
+				t.Fatalf("Collect() error = %v", err)
+			}
+			assertGauges(t, got, tt.want)
+		})
+	}
+}
+
+func TestDisk_Collect(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	// точка монтирования должна существовать, так как заполненность читается через statfs
+	mountpoint := t.TempDir()
+	ctx := procFixture(t, root, map[string]string{
+		"1/mounts": "/dev/sda1 " + mountpoint + ` ext4 rw,relatime 0 0
+/dev/sda1 ` + mountpoint + ` ext4 rw,relatime 0 0
+proc /proc proc rw,nosuid 0 0
+/dev/sdb1 /missing ext4 rw 0 0
+`,
+		"filesystems": "\text4\nnodev\tproc\n",
+		"diskstats": `   8       0 sda 100 0 200 30 50 0 400 60 0 70 90
+   8       1 sda1 90 0 180 25 40 0 300 50 0 60 80
+   7       0 loop0 0 0 0 0 0 0 0 0 0 0 0
+`,
+	})
+
+	got, err := NewDisk().Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	labels := domain.Labels{"mountpoint": mountpoint, "device": "/dev/sda1", "fstype": "ext4"}
+	byKey := metricsByKey(got)
+	for _, name := range []string{"DiskTotal", "DiskFree", "DiskUsed", "DiskUsedPercent"} {
+		if _, exist := byKey[domain.SeriesKey(name, labels)]; !exist {
+			t.Errorf("metric %s for %s not collected", name, mountpoint)
+		}
+	}
+	if total := byKey[domain.SeriesKey("DiskTotal", labels)].GaugeValue; total <= 0 {
+		t.Errorf("DiskTotal = %v, want positive", total)
+	}
+
+	sda := domain.Labels{"device": "sda"}
+	sda1 := domain.Labels{"device": "sda1"}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("DiskReadBytes", sda):   200 * 512,
+		domain.SeriesKey("DiskWriteBytes", sda):  400 * 512,
+		domain.SeriesKey("DiskReadCount", sda):   100,
+		domain.SeriesKey("DiskWriteCount", sda):  50,
+		domain.SeriesKey("DiskIOTime", sda):      70,
+		domain.SeriesKey("DiskReadCount", sda1):  90,
+		domain.SeriesKey("DiskWriteBytes", sda1): 300 * 512,
+	})
+
+	// 4 метрики точки монтирования и по 5 метрик двух устройств с ненулевыми счетчиками
+	if len(got) != 4+2*5 {
+		t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), 4+2*5, got)
+	}
+}
+
+func TestDisk_Collect_noDiskstats(t *testing.T) {
+	t.Parallel()
+
+	mountpoint := t.TempDir()
+	ctx := procFixture(t, t.TempDir(), map[string]string{
+		"1/mounts":    "/dev/sda1 " + mountpoint + " ext4 rw,relatime 0 0\n",
+		"filesystems": "\text4\n",
+	})
+
+	got, err := NewDisk().Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	labels := domain.Labels{"mountpoint": mountpoint, "device": "/dev/sda1", "fstype": "ext4"}
+	byKey := metricsByKey(got)
+	for _, name := range []string{"DiskTotal", "DiskFree", "DiskUsed", "DiskUsedPercent"} {
+		if _, exist := byKey[domain.SeriesKey(name, labels)]; !exist {
+			t.Errorf("metric %s for %s not collected", name, mountpoint)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("Collect() returned %d metrics, want 4: %v", len(got), got)
+	}
+}
+
+func TestNet_Collect(t *testing.T) {
+	t.Parallel()
+
+	ctx := procFixture(t, t.TempDir(), map[string]string{
+		"net/dev": `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1000      10    0    0    0     0          0         0     1000      10    0    0    0     0       0          0
+  eth0:    5000      50    1    0    0     0          0         0     7000      70    0    0    0     0       0          0
+`,
+	})
+
+	got, err := NewNet().Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	lo := domain.Labels{"interface": "lo"}
+	eth0 := domain.Labels{"interface": "eth0"}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("NetBytesRecv", lo):     1000,
+		domain.SeriesKey("NetBytesSent", lo):     1000,
+		domain.SeriesKey("NetBytesRecv", eth0):   5000,
+		domain.SeriesKey("NetBytesSent", eth0):   7000,
+		domain.SeriesKey("NetPacketsRecv", eth0): 50,
+		domain.SeriesKey("NetPacketsSent", eth0): 70,
+	})
+	if len(got) != 8 {
+		t.Errorf("Collect() returned %d metrics, want 8", len(got))
+	}
+}
@@ -0,0 +1,31 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/load"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Load сборщик средней загрузки системы.
+type Load struct{}
+
+// NewLoad создает сборщик средней загрузки системы.
+func NewLoad() *Load {
+	return &Load{}
+}
+
+// Collect возвращает среднюю загрузку за 1, 5 и 15 минут.
+func (c *Load) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []domain.MetricValue{
+		gaugeValue("LoadAverage1", avg.Load1, nil),
+		gaugeValue("LoadAverage5", avg.Load5, nil),
+		gaugeValue("LoadAverage15", avg.Load15, nil),
+	}, nil
+}
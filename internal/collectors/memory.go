@@ -0,0 +1,38 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/mem"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Memory сборщик метрик оперативной памяти хоста.
+type Memory struct{}
+
+// NewMemory создает сборщик метрик оперативной памяти.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// Collect возвращает общий и свободный объем памяти.
+func (c *Memory) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []domain.MetricValue{
+		{
+			Name:       "TotalMemory",
+			GaugeValue: float64(vm.Total),
+			Type:       domain.GaugeMetricType,
+		},
+		{
+			Name:       "FreeMemory",
+			GaugeValue: float64(vm.Free),
+			Type:       domain.GaugeMetricType,
+		},
+	}, nil
+}
@@ -0,0 +1,39 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/net"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Net сборщик сетевого трафика.
+type Net struct{}
+
+// NewNet создает сборщик сетевого трафика.
+func NewNet() *Net {
+	return &Net{}
+}
+
+// Collect возвращает число байт и пакетов, отправленных и полученных каждым
+// сетевым интерфейсом, с меткой interface.
+func (c *Net) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	counters, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.MetricValue, 0, len(counters)*4)
+	for _, io := range counters {
+		labels := domain.Labels{"interface": io.Name}
+		res = append(res,
+			gaugeValue("NetBytesSent", float64(io.BytesSent), labels),
+			gaugeValue("NetBytesRecv", float64(io.BytesRecv), labels),
+			gaugeValue("NetPacketsSent", float64(io.PacketsSent), labels),
+			gaugeValue("NetPacketsRecv", float64(io.PacketsRecv), labels),
+		)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,297 @@
+package collectors
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Форматы метрик приложений.
+const (
+	// PrometheusFormat текстовый формат Prometheus.
+	PrometheusFormat = "prom"
+	// JSONFormat массив метрик в формате JSON API сервера.
+	JSONFormat = "json"
+)
+
+// metricNameRegexp допустимое имя метрики.
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// jsonMetric метрика в формате JSON API сервера.
+type jsonMetric struct {
+	ID     string            `json:"id"`
+	MType  string            `json:"type"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Delta  *int64            `json:"delta,omitempty"`
+	Value  *float64          `json:"value,omitempty"`
+}
+
+// parseMetrics разбирает метрики в формате format и проверяет их.
+// Повторяющиеся ряды считаются ошибкой. Файлы и вывод команд перечитываются при каждом
+// сборе, поэтому счетчики содержат накопленный итог и возвращаются как gauge: отправка
+// итога как приращения счетчика сервера увеличивала бы его при каждой отправке.
+func parseMetrics(data []byte, format string) ([]domain.MetricValue, error) {
+	var (
+		values []domain.MetricValue
+		err    error
+	)
+	switch format {
+	case PrometheusFormat:
+		values, err = parsePrometheusText(data)
+	case JSONFormat:
+		values, err = parseJSONMetrics(data)
+	default:
+		return nil, fmt.Errorf("unknown metrics format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		if err = validateMetric(v); err != nil {
+			return nil, err
+		}
+
+		key := v.SeriesKey()
+		if _, exist := series[key]; exist {
+			return nil, fmt.Errorf("duplicate series %s", key)
+		}
+		series[key] = struct{}{}
+	}
+
+	return values, nil
+}
+
+// validateMetric проверяет имя, метки и значение метрики.
+func validateMetric(v domain.MetricValue) error {
+	if !metricNameRegexp.MatchString(v.Name) {
+		return fmt.Errorf("invalid metric name %q", v.Name)
+	}
+	if err := v.Labels.Validate(); err != nil {
+		return fmt.Errorf("metric %s: %w", v.Name, err)
+	}
+
+	switch v.Type {
+	case domain.GaugeMetricType:
+		if math.IsNaN(v.GaugeValue) || math.IsInf(v.GaugeValue, 0) {
+			return fmt.Errorf("metric %s: value %v is not finite", v.Name, v.GaugeValue)
+		}
+	case domain.CounterMetricType:
+		if v.CounterValue < 0 {
+			return fmt.Errorf("metric %s: negative counter value %d", v.Name, v.CounterValue)
+		}
+	default:
+		return fmt.Errorf("metric %s: unknown type %q", v.Name, v.Type)
+	}
+
+	return nil
+}
+
+// parseJSONMetrics разбирает массив метрик в формате JSON API сервера.
+func parseJSONMetrics(data []byte) ([]domain.MetricValue, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var metrics []jsonMetric
+	if err := decoder.Decode(&metrics); err != nil {
+		return nil, fmt.Errorf("failed to parse json metrics: %w", err)
+	}
+
+	res := make([]domain.MetricValue, 0, len(metrics))
+	for _, m := range metrics {
+		v := domain.MetricValue{
+			Name:   m.ID,
+			Labels: domain.Labels(m.Labels).Copy(),
+		}
+
+		switch m.MType {
+		case domain.GaugeMetricType.String():
+			if m.Value == nil {
+				return nil, fmt.Errorf("gauge %s has no value", m.ID)
+			}
+			v.Type = domain.GaugeMetricType
+			v.GaugeValue = *m.Value
+		case domain.CounterMetricType.String():
+			if m.Delta == nil {
+				return nil, fmt.Errorf("counter %s has no delta", m.ID)
+			}
+			if *m.Delta < 0 {
+				return nil, fmt.Errorf("counter %s: negative value %d", m.ID, *m.Delta)
+			}
+			v.Type = domain.GaugeMetricType
+			v.GaugeValue = float64(*m.Delta)
+		default:
+			return nil, fmt.Errorf("metric %s: unknown type %q", m.ID, m.MType)
+		}
+
+		res = append(res, v)
+	}
+
+	return res, nil
+}
+
+// parsePrometheusText разбирает метрики в текстовом формате Prometheus. Поддерживаются
+// типы gauge, counter и untyped, все они отправляются как gauge. Метки времени
+// не поддерживаются, так как время метрики определяет агент.
+func parsePrometheusText(data []byte) ([]domain.MetricValue, error) {
+	counters := make(map[string]bool)
+	res := make([]domain.MetricValue, 0)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if comment, found := strings.CutPrefix(line, "#"); found {
+			fields := strings.Fields(comment)
+			if len(fields) < 3 || fields[0] != "TYPE" {
+				// HELP и произвольные комментарии игнорируются
+				continue
+			}
+
+			switch fields[2] {
+			case "gauge", "untyped":
+				counters[fields[1]] = false
+			case "counter":
+				counters[fields[1]] = true
+			default:
+				return nil, fmt.Errorf("line %d: unsupported type %s of metric %s", lineNum, fields[2], fields[1])
+			}
+			continue
+		}
+
+		v, err := parsePrometheusSample(line, counters)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		res = append(res, v)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// parsePrometheusSample разбирает строку вида name{a="1"} value. Значения счетчиков
+// из counters не могут быть отрицательными.
+func parsePrometheusSample(line string, counters map[string]bool) (domain.MetricValue, error) {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return domain.MetricValue{}, fmt.Errorf("invalid sample %q", line)
+	}
+
+	v := domain.MetricValue{
+		Name: line[:nameEnd],
+		Type: domain.GaugeMetricType,
+	}
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		labels, tail, err := parsePrometheusLabels(rest[1:])
+		if err != nil {
+			return domain.MetricValue{}, fmt.Errorf("metric %s: %w", v.Name, err)
+		}
+		v.Labels = labels
+		rest = tail
+	}
+
+	fields := strings.Fields(rest)
+	switch {
+	case len(fields) == 0:
+		return domain.MetricValue{}, fmt.Errorf("metric %s has no value", v.Name)
+	case len(fields) > 1:
+		return domain.MetricValue{}, fmt.Errorf("metric %s: timestamps are not supported", v.Name)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return domain.MetricValue{}, fmt.Errorf("metric %s: invalid value %q", v.Name, fields[0])
+	}
+	if counters[v.Name] && value < 0 {
+		return domain.MetricValue{}, fmt.Errorf("metric %s: negative counter value %v", v.Name, value)
+	}
+	v.GaugeValue = value
+
+	return v, nil
+}
+
+// parsePrometheusLabels разбирает метки после открывающей скобки и возвращает остаток строки.
+func parsePrometheusLabels(s string) (domain.Labels, string, error) {
+	labels := make(domain.Labels)
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if rest, found := strings.CutPrefix(s, "}"); found {
+			if len(labels) == 0 {
+				labels = nil
+			}
+			return labels, rest, nil
+		}
+
+		name, rest, found := strings.Cut(s, "=")
+		if !found {
+			return nil, "", errors.New("invalid labels")
+		}
+		name = strings.TrimSpace(name)
+
+		rest = strings.TrimLeft(rest, " \t")
+		if !strings.HasPrefix(rest, `"`) {
+			return nil, "", fmt.Errorf("label %s value is not quoted", name)
+		}
+
+		value, tail, err := unquoteLabelValue(rest[1:])
+		if err != nil {
+			return nil, "", fmt.Errorf("label %s: %w", name, err)
+		}
+		if _, exist := labels[name]; exist {
+			return nil, "", fmt.Errorf("duplicate label %s", name)
+		}
+		labels[name] = value
+
+		s = strings.TrimLeft(tail, " \t")
+		if rest, found := strings.CutPrefix(s, ","); found {
+			s = rest
+		} else if !strings.HasPrefix(s, "}") {
+			return nil, "", errors.New("invalid labels")
+		}
+	}
+}
+
+// unquoteLabelValue читает значение метки до закрывающей кавычки с учетом
+// экранирования \\, \" и \n и возвращает остаток строки.
+func unquoteLabelValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", errors.New("unterminated escape")
+			}
+			i++
+			switch s[i] {
+			case '\\', '"':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			default:
+				return "", "", fmt.Errorf("invalid escape \\%c", s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", "", errors.New("unterminated label value")
+}
@@ -0,0 +1,141 @@
+package collectors
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestParseMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		want    []domain.MetricValue
+		wantErr bool
+	}{
+		{
+			name:   "prometheus text",
+			format: PrometheusFormat,
+			data: `# HELP queue_size Number of queued jobs.
+# TYPE queue_size gauge
+queue_size{queue="default",env="prod"} 12.5
+queue_size{queue="mail\"s\\n"} 3
+
+# TYPE jobs_done counter
+jobs_done 42
+build_info{version="1.2.3"} 1
+`,
+			want: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "queue_size", GaugeValue: 12.5,
+					Labels: domain.Labels{"queue": "default", "env": "prod"}},
+				{Type: domain.GaugeMetricType, Name: "queue_size", GaugeValue: 3,
+					Labels: domain.Labels{"queue": `mail"s\n`}},
+				{Type: domain.GaugeMetricType, Name: "jobs_done", GaugeValue: 42},
+				{Type: domain.GaugeMetricType, Name: "build_info", GaugeValue: 1,
+					Labels: domain.Labels{"version": "1.2.3"}},
+			},
+		},
+		{
+			name:   "json",
+			format: JSONFormat,
+			data: `[{"id":"queue_size","type":"gauge","value":7,"labels":{"queue":"default"}},
+{"id":"jobs_done","type":"counter","delta":5}]`,
+			want: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "queue_size", GaugeValue: 7,
+					Labels: domain.Labels{"queue": "default"}},
+				{Type: domain.GaugeMetricType, Name: "jobs_done", GaugeValue: 5},
+			},
+		},
+		{
+			name:    "timestamp",
+			format:  PrometheusFormat,
+			data:    "queue_size 1 1700000000000\n",
+			wantErr: true,
+		},
+		{
+			name:    "histogram",
+			format:  PrometheusFormat,
+			data:    "# TYPE latency histogram\nlatency_bucket{le=\"1\"} 1\n",
+			wantErr: true,
+		},
+		{
+			name:   "fractional counter",
+			format: PrometheusFormat,
+			data:   "# TYPE process_cpu_seconds_total counter\nprocess_cpu_seconds_total 12.34\n",
+			want: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "process_cpu_seconds_total", GaugeValue: 12.34},
+			},
+		},
+		{
+			name:    "negative prometheus counter",
+			format:  PrometheusFormat,
+			data:    "# TYPE jobs_done counter\njobs_done -1\n",
+			wantErr: true,
+		},
+		{
+			name:    "not finite gauge",
+			format:  PrometheusFormat,
+			data:    "queue_size NaN\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid metric name",
+			format:  PrometheusFormat,
+			data:    "queue-size 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid label name",
+			format:  JSONFormat,
+			data:    `[{"id":"queue_size","type":"gauge","value":1,"labels":{"queue-name":"a"}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unquoted label value",
+			format:  PrometheusFormat,
+			data:    "queue_size{queue=default} 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate series",
+			format:  PrometheusFormat,
+			data:    "queue_size{queue=\"a\"} 1\nqueue_size{queue=\"a\"} 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "gauge without value",
+			format:  JSONFormat,
+			data:    `[{"id":"queue_size","type":"gauge"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "negative counter",
+			format:  JSONFormat,
+			data:    `[{"id":"jobs_done","type":"counter","delta":-1}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			format:  "yaml",
+			data:    "queue_size: 1\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseMetrics([]byte(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
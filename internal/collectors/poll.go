@@ -0,0 +1,35 @@
+package collectors
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Poll сборщик счетчика опросов и случайного значения.
+type Poll struct {
+	pollCount atomic.Int64
+}
+
+// NewPoll создает сборщик счетчика опросов.
+func NewPoll() *Poll {
+	return &Poll{}
+}
+
+// Collect увеличивает счетчик опросов и возвращает его вместе со случайным значением.
+func (c *Poll) Collect(_ context.Context) ([]domain.MetricValue, error) {
+	return []domain.MetricValue{
+		{
+			Name:         "PollCount",
+			CounterValue: c.pollCount.Add(1),
+			Type:         domain.CounterMetricType,
+		},
+		{
+			Name:       "RandomValue",
+			GaugeValue: rand.Float64(),
+			Type:       domain.GaugeMetricType,
+		},
+	}, nil
+}
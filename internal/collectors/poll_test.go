@@ -0,0 +1,29 @@
+package collectors
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestPoll_Collect(t *testing.T) {
+	t.Parallel()
+
+	c := NewPoll()
+	for want := int64(1); want <= 3; want++ {
+		got, err := c.Collect(context.Background())
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("Collect() = %v, want PollCount and RandomValue", got)
+		}
+		if got[0].Name != "PollCount" || got[0].Type != domain.CounterMetricType || got[0].CounterValue != want {
+			t.Errorf("PollCount = %v, want %d", got[0], want)
+		}
+		if got[1].Name != "RandomValue" || got[1].GaugeValue < 0 || got[1].GaugeValue >= 1 {
+			t.Errorf("RandomValue = %v", got[1])
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/shirou/gopsutil/v4/process"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Process сборщик потребления ресурсов выбранными процессами.
+type Process struct {
+	pids     []int32
+	patterns []*regexp.Regexp
+}
+
+// NewProcess создает сборщик метрик процессов с идентификаторами pids и процессов,
+// имя которых содержит совпадение с одним из регулярных выражений patterns.
+func NewProcess(pids []int32, patterns []string) (*Process, error) {
+	c := &Process{
+		pids:     pids,
+		patterns: make([]*regexp.Regexp, 0, len(patterns)),
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid process pattern %q: %w", p, err)
+		}
+		c.patterns = append(c.patterns, re)
+	}
+
+	return c, nil
+}
+
+// Collect возвращает RSS, процессорное время, число открытых дескрипторов и потоков
+// каждого выбранного процесса с метками pid и name. Завершившиеся процессы пропускаются.
+func (c *Process) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	selected := slices.Clone(c.pids)
+	if len(c.patterns) > 0 {
+		pids, err := process.PidsWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pid := range pids {
+			// процесс создается напрямую, так как NewProcess проверяет существование
+			// сигналом, что неверно при чтении /proc хоста из контейнера
+			name, err := (&process.Process{Pid: pid}).NameWithContext(ctx)
+			if err != nil {
+				continue
+			}
+			if c.matches(name) {
+				selected = append(selected, pid)
+			}
+		}
+	}
+	slices.Sort(selected)
+	selected = slices.Compact(selected)
+
+	res := make([]domain.MetricValue, 0, len(selected)*5)
+	for _, pid := range selected {
+		values, err := collectProcess(ctx, &process.Process{Pid: pid})
+		if err != nil {
+			continue
+		}
+		res = append(res, values...)
+	}
+
+	return res, nil
+}
+
+func (c *Process) matches(name string) bool {
+	for _, re := range c.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// collectProcess возвращает метрики процесса p.
+func collectProcess(ctx context.Context, p *process.Process) ([]domain.MetricValue, error) {
+	name, err := p.NameWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	times, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	fds, err := p.NumFDsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := p.NumThreadsWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := domain.Labels{
+		"pid":  strconv.Itoa(int(p.Pid)),
+		"name": name,
+	}
+
+	return []domain.MetricValue{
+		gaugeValue("ProcessRSS", float64(memory.RSS), labels),
+		gaugeValue("ProcessCPUUserSeconds", times.User, labels),
+		gaugeValue("ProcessCPUSystemSeconds", times.System, labels),
+		gaugeValue("ProcessOpenFDs", float64(fds), labels),
+		gaugeValue("ProcessThreads", float64(threads), labels),
+	}, nil
+}
@@ -0,0 +1,98 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// procStat возвращает строку /proc/<pid>/stat процесса с временем utime и stime в тиках.
+func procStat(pid, comm, utime, stime string) string {
+	return pid + " (" + comm + ") S 1 " + pid + " " + pid + " 0 -1 4194560 1200 0 3 0 " +
+		utime + " " + stime + " 0 0 20 0 4 0 5000 123456789 300 18446744073709551615 " +
+		"1 1 0 0 0 0 0 0 0 0 0 0 17 0 0 0 7 0 0 0 0 0 0 0 0 0 0\n"
+}
+
+// procStatus возвращает содержимое /proc/<pid>/status с числом потоков threads.
+func procStatus(name, threads string) string {
+	return "Name:\t" + name + "\nState:\tS (sleeping)\nTgid:\t1\nPid:\t1\nPPid:\t0\n" +
+		"Uid:\t0\t0\t0\t0\nGid:\t0\t0\t0\t0\nThreads:\t" + threads + "\n"
+}
+
+func TestProcess_Collect(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	ctx := procFixture(t, root, map[string]string{
+		"100/comm":   "nginx\n",
+		"100/stat":   procStat("100", "nginx", "250", "50"),
+		"100/statm":  "5000 300 100 50 0 400 0\n",
+		"100/status": procStatus("nginx", "4"),
+		"200/comm":   "nginx\n",
+		"200/stat":   procStat("200", "nginx", "100", "100"),
+		"200/statm":  "5000 200 100 50 0 400 0\n",
+		"200/status": procStatus("nginx", "1"),
+		"300/comm":   "postgres\n",
+		"300/stat":   procStat("300", "postgres", "1000", "0"),
+		"300/statm":  "9000 1000 100 50 0 400 0\n",
+		"300/status": procStatus("postgres", "2"),
+		"400/comm":   "bash\n",
+		"400/stat":   procStat("400", "bash", "1", "1"),
+		"400/statm":  "1000 10 10 10 0 10 0\n",
+		"400/status": procStatus("bash", "1"),
+	})
+	for fd, pid := range map[string]string{"0": "100", "1": "100", "2": "100", "3": "200", "4": "300"} {
+		dir := filepath.Join(root, pid, "fd")
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink("/dev/null", filepath.Join(dir, fd)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "400", "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// процесс 999 из настроек не существует и пропускается
+	c, err := NewProcess([]int32{300, 999}, []string{"^nginx$"})
+	if err != nil {
+		t.Fatalf("NewProcess() error = %v", err)
+	}
+	got, err := c.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	pageSize := float64(os.Getpagesize())
+	nginx := domain.Labels{"pid": "100", "name": "nginx"}
+	nginxWorker := domain.Labels{"pid": "200", "name": "nginx"}
+	postgres := domain.Labels{"pid": "300", "name": "postgres"}
+	assertGauges(t, got, map[string]float64{
+		domain.SeriesKey("ProcessRSS", nginx):               300 * pageSize,
+		domain.SeriesKey("ProcessCPUUserSeconds", nginx):    2.5,
+		domain.SeriesKey("ProcessCPUSystemSeconds", nginx):  0.5,
+		domain.SeriesKey("ProcessOpenFDs", nginx):           3,
+		domain.SeriesKey("ProcessThreads", nginx):           4,
+		domain.SeriesKey("ProcessRSS", nginxWorker):         200 * pageSize,
+		domain.SeriesKey("ProcessOpenFDs", nginxWorker):     1,
+		domain.SeriesKey("ProcessThreads", nginxWorker):     1,
+		domain.SeriesKey("ProcessRSS", postgres):            1000 * pageSize,
+		domain.SeriesKey("ProcessCPUUserSeconds", postgres): 10,
+		domain.SeriesKey("ProcessOpenFDs", postgres):        1,
+		domain.SeriesKey("ProcessThreads", postgres):        2,
+	})
+	if len(got) != 3*5 {
+		t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), 3*5, got)
+	}
+}
+
+func TestNewProcess_invalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewProcess(nil, []string{"("}); err == nil {
+		t.Error("NewProcess() with invalid pattern error = nil")
+	}
+}
@@ -0,0 +1,246 @@
+package collectors
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// runtimeQuantiles квантили, отправляемые для гистограмм, квантиль 1 соответствует максимуму.
+var runtimeQuantiles = []float64{0.5, 0.9, 0.99, 1}
+
+// runtimeAcronyms части имен runtime/metrics, записываемые заглавными буквами.
+var runtimeAcronyms = map[string]string{
+	"gc":  "GC",
+	"cpu": "CPU",
+	"os":  "OS",
+}
+
+// memStatsCompat соответствие полей runtime.MemStats метрикам runtime/metrics,
+// значение поля равно сумме перечисленных метрик.
+var memStatsCompat = []struct {
+	name    string
+	metrics []string
+}{
+	{name: "Alloc", metrics: []string{"/memory/classes/heap/objects:bytes"}},
+	{name: "TotalAlloc", metrics: []string{"/gc/heap/allocs:bytes"}},
+	{name: "Sys", metrics: []string{"/memory/classes/total:bytes"}},
+	{name: "Mallocs", metrics: []string{"/gc/heap/allocs:objects", "/gc/heap/tiny/allocs:objects"}},
+	{name: "Frees", metrics: []string{"/gc/heap/frees:objects", "/gc/heap/tiny/allocs:objects"}},
+	{name: "HeapAlloc", metrics: []string{"/memory/classes/heap/objects:bytes"}},
+	{name: "HeapSys", metrics: []string{
+		"/memory/classes/heap/objects:bytes",
+		"/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/free:bytes",
+		"/memory/classes/heap/released:bytes",
+	}},
+	{name: "HeapIdle", metrics: []string{"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes"}},
+	{name: "HeapInuse", metrics: []string{"/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"}},
+	{name: "HeapReleased", metrics: []string{"/memory/classes/heap/released:bytes"}},
+	{name: "HeapObjects", metrics: []string{"/gc/heap/objects:objects"}},
+	{name: "StackInuse", metrics: []string{"/memory/classes/heap/stacks:bytes"}},
+	{name: "StackSys", metrics: []string{"/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"}},
+	{name: "MSpanInuse", metrics: []string{"/memory/classes/metadata/mspan/inuse:bytes"}},
+	{name: "MSpanSys", metrics: []string{
+		"/memory/classes/metadata/mspan/inuse:bytes",
+		"/memory/classes/metadata/mspan/free:bytes",
+	}},
+	{name: "MCacheInuse", metrics: []string{"/memory/classes/metadata/mcache/inuse:bytes"}},
+	{name: "MCacheSys", metrics: []string{
+		"/memory/classes/metadata/mcache/inuse:bytes",
+		"/memory/classes/metadata/mcache/free:bytes",
+	}},
+	{name: "BuckHashSys", metrics: []string{"/memory/classes/profiling/buckets:bytes"}},
+	{name: "GCSys", metrics: []string{"/memory/classes/metadata/other:bytes"}},
+	{name: "OtherSys", metrics: []string{"/memory/classes/other:bytes"}},
+	{name: "NextGC", metrics: []string{"/gc/heap/goal:bytes"}},
+	{name: "NumGC", metrics: []string{"/gc/cycles/total:gc-cycles"}},
+	{name: "NumForcedGC", metrics: []string{"/gc/cycles/forced:gc-cycles"}},
+}
+
+// Runtime сборщик метрик среды выполнения Go из пакета runtime/metrics. В отличие
+// от runtime.ReadMemStats чтение не останавливает программу.
+type Runtime struct {
+	descriptions []metrics.Description
+}
+
+// NewRuntime создает сборщик метрик среды выполнения.
+func NewRuntime() *Runtime {
+	descriptions := make([]metrics.Description, 0)
+	for _, d := range metrics.All() {
+		// счетчики отдельных настроек GODEBUG нужны только при переходе между версиями Go
+		if strings.HasPrefix(d.Name, "/godebug/") {
+			continue
+		}
+		descriptions = append(descriptions, d)
+	}
+
+	return &Runtime{
+		descriptions: descriptions,
+	}
+}
+
+// Collect возвращает все метрики runtime/metrics с именами вида GoSchedGoroutines,
+// гистограммы отправляются квантилями с меткой quantile и числом наблюдений.
+// Также возвращаются поля runtime.MemStats под прежними именами.
+func (c *Runtime) Collect(_ context.Context) ([]domain.MetricValue, error) {
+	samples := make([]metrics.Sample, len(c.descriptions))
+	for i, d := range c.descriptions {
+		samples[i].Name = d.Name
+	}
+	metrics.Read(samples)
+
+	res := make([]domain.MetricValue, 0, len(samples)+len(memStatsCompat))
+	values := make(map[string]float64, len(samples))
+	for _, s := range samples {
+		name := runtimeMetricName(s.Name)
+
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			v := float64(s.Value.Uint64())
+			values[s.Name] = v
+			res = append(res, gaugeValue(name, v, nil))
+		case metrics.KindFloat64:
+			v := s.Value.Float64()
+			values[s.Name] = v
+			res = append(res, gaugeValue(name, v, nil))
+		case metrics.KindFloat64Histogram:
+			res = append(res, histogramValues(name, s.Value.Float64Histogram())...)
+		}
+	}
+
+	return append(res, memStatsValues(values)...), nil
+}
+
+// histogramValues возвращает квантили и число наблюдений гистограммы h.
+func histogramValues(name string, h *metrics.Float64Histogram) []domain.MetricValue {
+	var count uint64
+	for _, n := range h.Counts {
+		count += n
+	}
+
+	res := make([]domain.MetricValue, 0, len(runtimeQuantiles)+1)
+	for _, q := range runtimeQuantiles {
+		labels := domain.Labels{"quantile": strconv.FormatFloat(q, 'g', -1, 64)}
+		res = append(res, gaugeValue(name, histogramQuantile(h, count, q), labels))
+	}
+
+	return append(res, gaugeValue(name+"Count", float64(count), nil))
+}
+
+// histogramQuantile оценивает квантиль q гистограммы h с числом наблюдений count
+// верхней границей корзины, в которую он попадает.
+func histogramQuantile(h *metrics.Float64Histogram, count uint64, q float64) float64 {
+	if count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(count)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var cumulative uint64
+	for i, n := range h.Counts {
+		cumulative += n
+		if cumulative < rank {
+			continue
+		}
+
+		// крайние корзины могут быть не ограничены, тогда берется конечная граница
+		if upper := h.Buckets[i+1]; !math.IsInf(upper, 0) {
+			return upper
+		}
+		if lower := h.Buckets[i]; !math.IsInf(lower, 0) {
+			return lower
+		}
+
+		return 0
+	}
+
+	return 0
+}
+
+// memStatsValues возвращает поля runtime.MemStats, вычисленные по значениям метрик
+// runtime/metrics, для совместимости с прежними именами метрик агента.
+func memStatsValues(values map[string]float64) []domain.MetricValue {
+	res := make([]domain.MetricValue, 0, len(memStatsCompat)+4)
+	for _, compat := range memStatsCompat {
+		var sum float64
+		found := true
+		for _, name := range compat.metrics {
+			v, exist := values[name]
+			if !exist {
+				found = false
+				break
+			}
+			sum += v
+		}
+		if found {
+			res = append(res, gaugeValue(compat.name, sum, nil))
+		}
+	}
+
+	// оценка runtime/metrics обновляется при сборках мусора и близка к значению MemStats
+	gcCPU, gcExist := values["/cpu/classes/gc/total:cpu-seconds"]
+	totalCPU, totalExist := values["/cpu/classes/total:cpu-seconds"]
+	if gcExist && totalExist {
+		var fraction float64
+		if totalCPU > 0 {
+			fraction = gcCPU / totalCPU
+		}
+		res = append(res, gaugeValue("GCCPUFraction", fraction, nil))
+	}
+
+	// время последней сборки и суммарная пауза есть только в статистике сборщика мусора,
+	// ее чтение не останавливает программу
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+	var lastGC float64
+	if !gcStats.LastGC.IsZero() {
+		lastGC = float64(gcStats.LastGC.UnixNano())
+	}
+
+	return append(res,
+		gaugeValue("LastGC", lastGC, nil),
+		gaugeValue("PauseTotalNs", float64(gcStats.PauseTotal.Nanoseconds()), nil),
+		// поиск указателей среды выполнения больше не ведется, поле всегда равно нулю
+		gaugeValue("Lookups", 0, nil),
+	)
+}
+
+// runtimeMetricName преобразует имя метрики runtime/metrics вида /sched/goroutines:goroutines
+// в имя вида GoSchedGoroutines. Единица измерения опускается, если совпадает с последней
+// частью имени.
+func runtimeMetricName(name string) string {
+	path, unit, _ := strings.Cut(name, ":")
+	isSeparator := func(r rune) bool {
+		return r == '/' || r == '-' || r == '_'
+	}
+
+	parts := strings.FieldsFunc(path, isSeparator)
+	if len(parts) == 0 || parts[len(parts)-1] != unit {
+		parts = append(parts, strings.FieldsFunc(unit, isSeparator)...)
+	}
+
+	b := strings.Builder{}
+	b.WriteString("Go")
+	for _, p := range parts {
+		if acronym, exist := runtimeAcronyms[p]; exist {
+			b.WriteString(acronym)
+			continue
+		}
+
+		r := []rune(p)
+		r[0] = unicode.ToUpper(r[0])
+		b.WriteString(string(r))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,126 @@
+package collectors
+
+import (
+	"math"
+	"runtime"
+	"runtime/metrics"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestRuntimeMetricName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "/sched/goroutines:goroutines", want: "GoSchedGoroutines"},
+		{name: "/sched/latencies:seconds", want: "GoSchedLatenciesSeconds"},
+		{name: "/gc/heap/allocs:bytes", want: "GoGCHeapAllocsBytes"},
+		{name: "/gc/cycles/total:gc-cycles", want: "GoGCCyclesTotalGCCycles"},
+		{name: "/cpu/classes/gc/mark/assist:cpu-seconds", want: "GoCPUClassesGCMarkAssistCPUSeconds"},
+		{name: "/memory/classes/os-stacks:bytes", want: "GoMemoryClassesOSStacksBytes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := runtimeMetricName(tt.name); got != tt.want {
+				t.Errorf("runtimeMetricName() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	t.Parallel()
+
+	h := &metrics.Float64Histogram{
+		Counts:  []uint64{0, 5, 4, 1},
+		Buckets: []float64{math.Inf(-1), 1, 2, 4, math.Inf(1)},
+	}
+	tests := []struct {
+		name     string
+		h        *metrics.Float64Histogram
+		quantile float64
+		want     float64
+	}{
+		{name: "median", h: h, quantile: 0.5, want: 2},
+		{name: "p90", h: h, quantile: 0.9, want: 4},
+		{name: "max in unbounded bucket", h: h, quantile: 1, want: 4},
+		{
+			name:     "empty",
+			h:        &metrics.Float64Histogram{Counts: []uint64{0}, Buckets: []float64{0, 1}},
+			quantile: 0.5,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var count uint64
+			for _, n := range tt.h.Counts {
+				count += n
+			}
+			if got := histogramQuantile(tt.h, count, tt.quantile); got != tt.want {
+				t.Errorf("histogramQuantile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntime_Collect(t *testing.T) {
+	t.Parallel()
+
+	runtime.GC()
+	got, err := NewRuntime().Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	byKey := metricsByKey(got)
+	if len(byKey) != len(got) {
+		t.Errorf("Collect() returned duplicate series")
+	}
+
+	// прежние имена полей runtime.MemStats
+	for _, name := range []string{
+		"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle",
+		"HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups", "MCacheInuse",
+		"MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
+		"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc",
+	} {
+		if _, exist := byKey[name]; !exist {
+			t.Errorf("Collect() has no MemStats field %s", name)
+		}
+	}
+	if byKey["NumGC"].GaugeValue < 1 || byKey["LastGC"].GaugeValue <= 0 {
+		t.Errorf("NumGC = %v, LastGC = %v after runtime.GC()", byKey["NumGC"].GaugeValue, byKey["LastGC"].GaugeValue)
+	}
+	if byKey["HeapSys"].GaugeValue < byKey["HeapInuse"].GaugeValue {
+		t.Errorf("HeapSys = %v less than HeapInuse = %v", byKey["HeapSys"].GaugeValue, byKey["HeapInuse"].GaugeValue)
+	}
+
+	if v := byKey["GoSchedGoroutines"]; v.GaugeValue < 1 {
+		t.Errorf("GoSchedGoroutines = %v, want positive", v.GaugeValue)
+	}
+	for _, q := range []string{"0.5", "0.9", "0.99", "1"} {
+		key := domain.SeriesKey("GoSchedLatenciesSeconds", domain.Labels{"quantile": q})
+		if _, exist := byKey[key]; !exist {
+			t.Errorf("Collect() has no %s", key)
+		}
+	}
+	if _, exist := byKey["GoSchedLatenciesSecondsCount"]; !exist {
+		t.Error("Collect() has no GoSchedLatenciesSecondsCount")
+	}
+}
+
+func BenchmarkRuntime_Collect(b *testing.B) {
+	c := NewRuntime()
+	for b.Loop() {
+		_, _ = c.Collect(b.Context())
+	}
+}
@@ -0,0 +1,39 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/shirou/gopsutil/v4/mem"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Swap сборщик метрик файла подкачки.
+type Swap struct{}
+
+// NewSwap создает сборщик метрик файла подкачки.
+func NewSwap() *Swap {
+	return &Swap{}
+}
+
+// Collect возвращает общий, свободный и занятый объем файла подкачки.
+func (c *Swap) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	// объем подкачки берется из meminfo, а не sysinfo, чтобы учитывался HOST_PROC
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	used := vm.SwapTotal - vm.SwapFree
+	var usedPercent float64
+	if vm.SwapTotal > 0 {
+		usedPercent = float64(used) / float64(vm.SwapTotal) * 100
+	}
+
+	return []domain.MetricValue{
+		gaugeValue("SwapTotal", float64(vm.SwapTotal), nil),
+		gaugeValue("SwapFree", float64(vm.SwapFree), nil),
+		gaugeValue("SwapUsed", float64(used), nil),
+		gaugeValue("SwapUsedPercent", usedPercent, nil),
+	}, nil
+}
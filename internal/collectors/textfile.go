@@ -0,0 +1,127 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// textfileFormats форматы файлов по расширению.
+var textfileFormats = map[string]string{
+	".prom": PrometheusFormat,
+	".json": JSONFormat,
+}
+
+// Textfile сборщик метрик приложений из файлов *.prom и *.json в директории.
+// Приложения должны записывать файл во временный и переименовывать его, чтобы
+// сборщик не прочитал файл частично.
+type Textfile struct {
+	dir    string
+	maxAge time.Duration
+}
+
+// NewTextfile создает сборщик метрик из файлов директории dir. Файлы, которые
+// не изменялись дольше maxAge, считаются устаревшими, нулевой maxAge отключает проверку.
+func NewTextfile(dir string, maxAge time.Duration) (*Textfile, error) {
+	if dir == "" {
+		return nil, errors.New("textfile directory is not set")
+	}
+	if maxAge < 0 {
+		return nil, fmt.Errorf("invalid textfile max age: %s", maxAge)
+	}
+
+	return &Textfile{
+		dir:    dir,
+		maxAge: maxAge,
+	}, nil
+}
+
+// Collect возвращает метрики из файлов директории. Для каждого файла также отправляются
+// TextfileMtimeSeconds, TextfileStale и TextfileError с меткой file. Метрики устаревших
+// и некорректных файлов, а также ряды, уже полученные из другого файла, пропускаются.
+func (c *Textfile) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]domain.MetricValue, 0)
+	series := make(map[string]string)
+	for _, entry := range entries {
+		format, exist := textfileFormats[filepath.Ext(entry.Name())]
+		if !exist || entry.IsDir() {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		labels := domain.Labels{"file": entry.Name()}
+		values, mtime, stale, err := c.readFile(entry.Name(), format)
+		if err == nil {
+			// ряд должен принадлежать одному файлу, иначе значения будут перезаписывать друг друга
+			for _, v := range values {
+				if file, exist := series[v.SeriesKey()]; exist {
+					err = fmt.Errorf("series %s already read from %s", v.SeriesKey(), file)
+					break
+				}
+			}
+		}
+
+		var failed float64
+		switch {
+		case err != nil:
+			logger.Errorf(ctx, "textfile %s: %v", entry.Name(), err)
+			failed = 1
+		case !stale:
+			for _, v := range values {
+				series[v.SeriesKey()] = entry.Name()
+			}
+			res = append(res, values...)
+		}
+
+		var staleValue float64
+		if stale {
+			staleValue = 1
+		}
+		if !mtime.IsZero() {
+			res = append(res, gaugeValue("TextfileMtimeSeconds", float64(mtime.UnixNano())/1e9, labels))
+		}
+		res = append(res,
+			gaugeValue("TextfileStale", staleValue, labels),
+			gaugeValue("TextfileError", failed, labels),
+		)
+	}
+
+	return res, nil
+}
+
+// readFile читает метрики файла name и возвращает время его изменения и признак устаревания.
+func (c *Textfile) readFile(name, format string) ([]domain.MetricValue, time.Time, bool, error) {
+	path := filepath.Join(c.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	mtime := info.ModTime()
+	stale := c.maxAge > 0 && time.Since(mtime) > c.maxAge
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, mtime, stale, err
+	}
+
+	values, err := parseMetrics(data, format)
+	if err != nil {
+		return nil, mtime, stale, err
+	}
+
+	return values, mtime, stale, nil
+}
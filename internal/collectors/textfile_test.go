@@ -0,0 +1,124 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestTextfile_Collect(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.prom":      "# TYPE queue_size gauge\nqueue_size{queue=\"default\"} 12\n",
+		"batch.json":    `[{"id":"jobs_done","type":"counter","delta":5}]`,
+		"broken.prom":   "queue_size{queue=\"default\" 1\n",
+		"old.prom":      "old_metric 1\n",
+		"conflict.prom": "queue_size{queue=\"default\"} 13\n",
+		"notes.txt":     "ignored\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldTime := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "old.prom"), oldTime, oldTime); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := NewTextfile(dir, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("NewTextfile() error = %v", err)
+	}
+	got, err := c.Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+
+	byKey := metricsByKey(got)
+	if _, exist := byKey[`old_metric`]; exist {
+		t.Error("Collect() returned metric from stale file")
+	}
+
+	file := func(name string) domain.Labels {
+		return domain.Labels{"file": name}
+	}
+	assertGauges(t, got, map[string]float64{
+		// файлы читаются в порядке имен, поэтому ряд из conflict.prom отклоняется
+		domain.SeriesKey("queue_size", domain.Labels{"queue": "default"}): 12,
+		"jobs_done": 5,
+		domain.SeriesKey("TextfileError", file("app.prom")):        0,
+		domain.SeriesKey("TextfileError", file("batch.json")):      0,
+		domain.SeriesKey("TextfileError", file("broken.prom")):     1,
+		domain.SeriesKey("TextfileError", file("conflict.prom")):   1,
+		domain.SeriesKey("TextfileError", file("old.prom")):        0,
+		domain.SeriesKey("TextfileStale", file("app.prom")):        0,
+		domain.SeriesKey("TextfileStale", file("old.prom")):        1,
+		domain.SeriesKey("TextfileMtimeSeconds", file("old.prom")): float64(oldTime.UnixNano()) / 1e9,
+	})
+
+	// 2 метрики приложений и по 3 служебные метрики на каждый из 5 файлов
+	if want := 2 + 5*3; len(got) != want {
+		t.Errorf("Collect() returned %d metrics, want %d: %v", len(got), want, got)
+	}
+}
+
+func TestTextfile_Collect_counters(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.prom":   "# TYPE jobs_total counter\njobs_total 10\n",
+		"batch.json": `[{"id":"batch_total","type":"counter","delta":5}]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := NewTextfile(dir, 0)
+	if err != nil {
+		t.Fatalf("NewTextfile() error = %v", err)
+	}
+
+	// неизменный файл при каждом сборе дает тот же накопленный итог, а не новое приращение
+	for range 2 {
+		got, err := c.Collect(t.Context())
+		if err != nil {
+			t.Fatalf("Collect() error = %v", err)
+		}
+		assertGauges(t, got, map[string]float64{
+			"jobs_total":  10,
+			"batch_total": 5,
+		})
+	}
+}
+
+func TestTextfile_Collect_noDirectory(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewTextfile(filepath.Join(t.TempDir(), "missing"), 0)
+	if err != nil {
+		t.Fatalf("NewTextfile() error = %v", err)
+	}
+	if _, err = c.Collect(t.Context()); err == nil {
+		t.Error("Collect() of missing directory error = nil")
+	}
+}
+
+func TestNewTextfile(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewTextfile("", 0); err == nil {
+		t.Error("NewTextfile() without directory error = nil")
+	}
+	if _, err := NewTextfile(t.TempDir(), -time.Second); err == nil {
+		t.Error("NewTextfile() with negative max age error = nil")
+	}
+}
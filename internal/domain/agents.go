@@ -0,0 +1,13 @@
+package domain
+
+import "time"
+
+// UnknownAgentName имя агента, не представившегося при отправке метрик.
+const UnknownAgentName = "unknown"
+
+// Agent агент, присылающий метрики.
+type Agent struct {
+	Name string
+	// LastSeen время последнего обновления метрик агентом.
+	LastSeen time.Time
+}
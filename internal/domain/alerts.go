@@ -0,0 +1,43 @@
+package domain
+
+import "time"
+
+// AlertState состояние алерта.
+type AlertState string
+
+const (
+	// AlertStatePending условие правила выполняется, но еще не дольше заданного интервала.
+	AlertStatePending AlertState = "pending"
+	// AlertStateFiring условие правила выполняется дольше заданного интервала.
+	AlertStateFiring AlertState = "firing"
+	// AlertStateResolved условие сработавшего правила перестало выполняться.
+	AlertStateResolved AlertState = "resolved"
+)
+
+// String возвращает строковое значение состояния алерта.
+func (as AlertState) String() string {
+	return string(as)
+}
+
+// Alert алерт, порожденный правилом.
+type Alert struct {
+	RuleName    string
+	Description string
+	State       AlertState
+	MetricType  MetricType
+	MetricName  string
+	Labels      Labels
+	// Value последнее вычисленное значение метрики.
+	Value      float64
+	ActiveAt   time.Time
+	FiredAt    time.Time
+	ResolvedAt time.Time
+}
+
+// Notification уведомление о группе алертов.
+type Notification struct {
+	GroupKey string
+	// Status firing, если в группе есть хотя бы один сработавший алерт, иначе resolved.
+	Status AlertState
+	Alerts []Alert
+}
@@ -0,0 +1,238 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Labels набор меток метрики. Метрика однозначно определяется именем и набором меток.
+type Labels map[string]string
+
+// labelNameRegexp допустимое имя метки.
+var labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Validate проверяет имена меток.
+func (l Labels) Validate() error {
+	for name := range l {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// String возвращает метки в каноническом виде {a="1",b="2"}, отсортированными по имени.
+// Для пустого набора возвращается пустая строка.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(l[name]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+// Equal сравнивает наборы меток, пустой набор равен nil.
+func (l Labels) Equal(o Labels) bool {
+	if len(l) != len(o) {
+		return false
+	}
+
+	for name, value := range l {
+		if v, exist := o[name]; !exist || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Copy возвращает копию набора меток.
+func (l Labels) Copy() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+
+	res := make(Labels, len(l))
+	for name, value := range l {
+		res[name] = value
+	}
+
+	return res
+}
+
+// ParseLabels разбирает метки вида env=prod,dc=eu.
+func ParseLabels(s string) (Labels, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	labels := make(Labels)
+	for _, pair := range strings.Split(s, ",") {
+		name, v, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid label %q", pair)
+		}
+
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(v)
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// SeriesKey возвращает ключ ряда метрики: имя и отсортированные метки.
+func SeriesKey(name string, labels Labels) string {
+	return name + labels.String()
+}
+
+// MatchType тип сравнения метки.
+type MatchType string
+
+const (
+	// MatchEqual значение метки равно заданному.
+	MatchEqual MatchType = "="
+	// MatchNotEqual значение метки не равно заданному.
+	MatchNotEqual MatchType = "!="
+	// MatchRegexp значение метки полностью соответствует регулярному выражению.
+	MatchRegexp MatchType = "=~"
+	// MatchNotRegexp значение метки не соответствует регулярному выражению.
+	MatchNotRegexp MatchType = "!~"
+)
+
+// LabelMatcher условие отбора метрик по метке.
+// Отсутствующая метка считается меткой с пустым значением.
+type LabelMatcher struct {
+	Type  MatchType
+	Name  string
+	Value string
+
+	re *regexp.Regexp
+}
+
+// NewLabelMatcher конструктор условия отбора по метке.
+func NewLabelMatcher(t MatchType, name, value string) (LabelMatcher, error) {
+	m := LabelMatcher{
+		Type:  t,
+		Name:  name,
+		Value: value,
+	}
+
+	if !labelNameRegexp.MatchString(name) {
+		return LabelMatcher{}, fmt.Errorf("invalid label name %q", name)
+	}
+
+	switch t {
+	case MatchEqual, MatchNotEqual:
+	case MatchRegexp, MatchNotRegexp:
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return LabelMatcher{}, fmt.Errorf("invalid label regexp %q: %w", value, err)
+		}
+		m.re = re
+	default:
+		return LabelMatcher{}, fmt.Errorf("unknown match type %q", t)
+	}
+
+	return m, nil
+}
+
+// Matches проверяет соответствие набора меток условию.
+func (m LabelMatcher) Matches(l Labels) bool {
+	v := l[m.Name]
+	switch m.Type {
+	case MatchEqual:
+		return v == m.Value
+	case MatchNotEqual:
+		return v != m.Value
+	case MatchRegexp:
+		return m.re.MatchString(v)
+	case MatchNotRegexp:
+		return !m.re.MatchString(v)
+	}
+
+	return false
+}
+
+// String возвращает строковое представление условия.
+func (m LabelMatcher) String() string {
+	return m.Name + string(m.Type) + m.Value
+}
+
+// MatchLabels проверяет соответствие набора меток всем условиям.
+func MatchLabels(l Labels, matchers ...LabelMatcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(l) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseLabelMatchers разбирает условия отбора вида host=a,env!=dev,dc=~eu-.*.
+// Значения условий не могут содержать запятую.
+func ParseLabelMatchers(s string) ([]LabelMatcher, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(s, ",")
+	res := make([]LabelMatcher, 0, len(parts))
+	for _, part := range parts {
+		i := strings.IndexAny(part, "=!")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid label matcher %q", part)
+		}
+
+		name := strings.TrimSpace(part[:i])
+		rest := part[i:]
+
+		var t MatchType
+		switch {
+		case strings.HasPrefix(rest, string(MatchRegexp)):
+			t = MatchRegexp
+		case strings.HasPrefix(rest, string(MatchNotRegexp)):
+			t = MatchNotRegexp
+		case strings.HasPrefix(rest, string(MatchNotEqual)):
+			t = MatchNotEqual
+		case strings.HasPrefix(rest, string(MatchEqual)):
+			t = MatchEqual
+		default:
+			return nil, fmt.Errorf("invalid label matcher %q", part)
+		}
+
+		m, err := NewLabelMatcher(t, name, rest[len(t):])
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, m)
+	}
+
+	return res, nil
+}
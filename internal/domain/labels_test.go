@@ -0,0 +1,153 @@
+package domain
+
+import "testing"
+
+func TestLabels_String(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name   string
+		labels Labels
+		want   string
+	}{
+		{
+			name:   "empty",
+			labels: nil,
+			want:   "",
+		},
+		{
+			name:   "sorted",
+			labels: Labels{"host": "b", "env": "prod"},
+			want:   `{env="prod",host="b"}`,
+		},
+		{
+			name:   "escaped",
+			labels: Labels{"path": `a"b`},
+			want:   `{path="a\"b"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.labels.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLabelMatchers(t *testing.T) {
+	t.Parallel()
+	labels := Labels{"host": "a", "env": "prod", "dc": "eu-west"}
+	tests := []struct {
+		name      string
+		s         string
+		wantMatch bool
+		wantErr   bool
+	}{
+		{
+			name:      "empty",
+			s:         "",
+			wantMatch: true,
+		},
+		{
+			name:      "equal",
+			s:         "host=a",
+			wantMatch: true,
+		},
+		{
+			name:      "not equal",
+			s:         "host=a,env!=prod",
+			wantMatch: false,
+		},
+		{
+			name:      "regexp",
+			s:         "dc=~eu-.*",
+			wantMatch: true,
+		},
+		{
+			name:      "not regexp",
+			s:         "dc!~eu-.*",
+			wantMatch: false,
+		},
+		{
+			name:      "missing label equals empty",
+			s:         "zone=",
+			wantMatch: true,
+		},
+		{
+			name:    "invalid name",
+			s:       "1host=a",
+			wantErr: true,
+		},
+		{
+			name:    "invalid regexp",
+			s:       "host=~(",
+			wantErr: true,
+		},
+		{
+			name:    "no operator",
+			s:       "host",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			matchers, err := ParseLabelMatchers(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLabelMatchers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := MatchLabels(labels, matchers...); got != tt.wantMatch {
+				t.Errorf("MatchLabels() = %v, want %v", got, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		s       string
+		want    Labels
+		wantErr bool
+	}{
+		{
+			name: "empty",
+			s:    "",
+			want: nil,
+		},
+		{
+			name: "trimmed",
+			s:    "env = prod, dc=eu",
+			want: Labels{"env": "prod", "dc": "eu"},
+		},
+		{
+			name:    "no value",
+			s:       "env",
+			wantErr: true,
+		},
+		{
+			name:    "invalid name",
+			s:       "1env=prod",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseLabels(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseLabels() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseLabels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
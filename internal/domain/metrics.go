@@ -30,10 +30,16 @@ func (mt MetricType) String() string {
 	return string(mt)
 }
 
-// MetricValue значение метрики, в зависимости от типа будет заполнено только одно из полей.
+// MetricValue значение метрики, в зависимости от типа будет заполнено только одно из полей значения.
 type MetricValue struct {
 	Type         MetricType
 	Name         string
+	Labels       Labels
 	CounterValue int64
 	GaugeValue   float64
 }
+
+// SeriesKey возвращает ключ ряда метрики.
+func (mv MetricValue) SeriesKey() string {
+	return SeriesKey(mv.Name, mv.Labels)
+}
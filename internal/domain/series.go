@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Aggregation функция агрегации значений метрики в пределах шага запроса.
+type Aggregation string
+
+const (
+	// AggregationAvg среднее значение градусника.
+	AggregationAvg Aggregation = "avg"
+	// AggregationMin минимальное значение градусника.
+	AggregationMin Aggregation = "min"
+	// AggregationMax максимальное значение градусника.
+	AggregationMax Aggregation = "max"
+	// AggregationLast последнее значение градусника.
+	AggregationLast Aggregation = "last"
+	// AggregationIncrease прирост счетчика.
+	AggregationIncrease Aggregation = "increase"
+	// AggregationRate прирост счетчика в секунду.
+	AggregationRate Aggregation = "rate"
+)
+
+// NewAggregationFromString конструктор функции агрегации.
+func NewAggregationFromString(s string) (Aggregation, error) {
+	switch a := Aggregation(s); a {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast,
+		AggregationIncrease, AggregationRate:
+		return a, nil
+	}
+
+	return "", fmt.Errorf("unknown aggregation %q", s)
+}
+
+// String возвращает строковое значение функции агрегации.
+func (a Aggregation) String() string {
+	return string(a)
+}
+
+// SupportedBy проверяет, применима ли функция агрегации к типу метрики.
+func (a Aggregation) SupportedBy(t MetricType) bool {
+	switch a {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationLast:
+		return t == GaugeMetricType
+	case AggregationIncrease, AggregationRate:
+		return t == CounterMetricType
+	}
+
+	return false
+}
+
+// DefaultAggregation возвращает функцию агрегации по умолчанию для типа метрики.
+func DefaultAggregation(t MetricType) Aggregation {
+	if t == CounterMetricType {
+		return AggregationIncrease
+	}
+
+	return AggregationAvg
+}
+
+// Sample агрегат значений ряда метрики, начиная с Timestamp.
+// Одиночное значение представляется агрегатом с Count = 1.
+// Для счетчика значения являются приращениями.
+type Sample struct {
+	Timestamp time.Time
+	Count     int64
+	Sum       float64
+	Min       float64
+	Max       float64
+	// Last значение, полученное последним.
+	Last float64
+}
+
+// NewSample создает агрегат из одиночного значения.
+func NewSample(ts time.Time, value float64) Sample {
+	return Sample{
+		Timestamp: ts,
+		Count:     1,
+		Sum:       value,
+		Min:       value,
+		Max:       value,
+		Last:      value,
+	}
+}
+
+// Merge добавляет к агрегату более поздний агрегат.
+func (s Sample) Merge(o Sample) Sample {
+	if s.Count == 0 {
+		o.Timestamp = s.Timestamp
+		return o
+	}
+
+	s.Count += o.Count
+	s.Sum += o.Sum
+	s.Min = min(s.Min, o.Min)
+	s.Max = max(s.Max, o.Max)
+	s.Last = o.Last
+
+	return s
+}
+
+// RangeQuery запрос значений ряда метрики за период с агрегацией по шагу.
+type RangeQuery struct {
+	Type   MetricType
+	Name   string
+	Labels Labels
+	// Start начало периода, включительно.
+	Start time.Time
+	// End конец периода, не включительно.
+	End         time.Time
+	Step        time.Duration
+	Aggregation Aggregation
+}
+
+// MaxRangePoints максимальное количество шагов в запросе значений за период.
+const MaxRangePoints = 11000
+
+// Validate проверяет корректность запроса.
+func (q RangeQuery) Validate() error {
+	if q.Name == "" {
+		return errors.New("metric name is empty")
+	}
+
+	if !q.Aggregation.SupportedBy(q.Type) {
+		return fmt.Errorf("aggregation %q is not supported by %s", q.Aggregation, q.Type)
+	}
+
+	if q.Step <= 0 {
+		return errors.New("step must be positive")
+	}
+
+	if !q.End.After(q.Start) {
+		return errors.New("end must be after start")
+	}
+
+	if q.End.Sub(q.Start)/q.Step > MaxRangePoints {
+		return fmt.Errorf("too many points, max %d", MaxRangePoints)
+	}
+
+	return q.Labels.Validate()
+}
+
+// Point значение ряда метрики на шаге, начинающемся с Timestamp.
+type Point struct {
+	Timestamp time.Time
+	Value     float64
+}
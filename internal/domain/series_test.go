@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSample_Merge(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		s    Sample
+		o    Sample
+		want Sample
+	}{
+		{
+			name: "empty",
+			s:    Sample{Timestamp: ts},
+			o:    NewSample(ts.Add(time.Second), 5),
+			want: Sample{Timestamp: ts, Count: 1, Sum: 5, Min: 5, Max: 5, Last: 5},
+		},
+		{
+			name: "merged",
+			s:    NewSample(ts, 5),
+			o:    Sample{Timestamp: ts.Add(time.Second), Count: 2, Sum: 4, Min: 1, Max: 3, Last: 1},
+			want: Sample{Timestamp: ts, Count: 3, Sum: 9, Min: 1, Max: 5, Last: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := tt.s.Merge(tt.o); got != tt.want {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
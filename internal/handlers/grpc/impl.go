@@ -0,0 +1,228 @@
+// Package grpc предоставляет grpc обработчики для сбора метрик и их последующего предоставления клиенту.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+// AgentNameMetadataKey ключ метаданных с именем агента, приславшего метрики.
+const AgentNameMetadataKey = "x-agent-name"
+
+type useCases interface {
+	GetMetric(ctx context.Context, value domain.MetricType,
+		name string, labels domain.Labels) (domain.MetricValue, error)
+	GetAllMetrics(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error)
+	UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error
+}
+
+// Handlers grpc обработчики для сбора метрик и их последующего предоставления клиенту.
+type Handlers struct {
+	metricspb.UnimplementedMetricsServer
+
+	metricUseCases useCases
+}
+
+// NewHandlers создает объект grpc обработчиков для сбора метрик и их последующего предоставления клиенту.
+func NewHandlers(useCases useCases) *Handlers {
+	return &Handlers{
+		metricUseCases: useCases,
+	}
+}
+
+// UpdateMetrics обновляет набор метрик.
+func (h *Handlers) UpdateMetrics(ctx context.Context,
+	req *metricspb.UpdateMetricsRequest) (*metricspb.UpdateMetricsResponse, error) {
+	if err := h.updateMetrics(ctx, req.GetMetrics()); err != nil {
+		return nil, err
+	}
+
+	return &metricspb.UpdateMetricsResponse{}, nil
+}
+
+// UpdateMetricsStream обновляет метрики, присылаемые частями в потоке.
+// Каждая часть применяется сразу после получения.
+func (h *Handlers) UpdateMetricsStream(
+	stream metricspb.Metrics_UpdateMetricsStreamServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(&metricspb.UpdateMetricsResponse{})
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = h.updateMetrics(stream.Context(), req.GetMetrics()); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *Handlers) updateMetrics(ctx context.Context, metrics []*metricspb.Metric) error {
+	values := make([]domain.MetricValue, 0, len(metrics))
+	for _, m := range metrics {
+		v, err := metricToDomain(m)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		values = append(values, v)
+	}
+
+	if err := h.metricUseCases.UpdateMetrics(ctx, agentFromContext(ctx), values); err != nil {
+		return toStatus(err)
+	}
+
+	return nil
+}
+
+// GetMetric возвращает значение одной метрики.
+func (h *Handlers) GetMetric(ctx context.Context,
+	req *metricspb.GetMetricRequest) (*metricspb.GetMetricResponse, error) {
+	t, err := metricTypeToDomain(req.GetType())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	labels := domain.Labels(req.GetLabels())
+	if err = labels.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	val, err := h.metricUseCases.GetMetric(ctx, t, req.GetName(), labels)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &metricspb.GetMetricResponse{
+		Metric: metricFromDomain(val),
+	}, nil
+}
+
+// ListMetrics возвращает значения всех метрик, удовлетворяющих условиям.
+func (h *Handlers) ListMetrics(ctx context.Context,
+	req *metricspb.ListMetricsRequest) (*metricspb.ListMetricsResponse, error) {
+	matchers := make([]domain.LabelMatcher, 0, len(req.GetMatch()))
+	for _, value := range req.GetMatch() {
+		m, err := domain.ParseLabelMatchers(value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		matchers = append(matchers, m...)
+	}
+
+	values, err := h.metricUseCases.GetAllMetrics(ctx, matchers...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	res := make([]*metricspb.Metric, 0, len(values))
+	for _, v := range values {
+		res = append(res, metricFromDomain(v))
+	}
+
+	return &metricspb.ListMetricsResponse{
+		Metrics: res,
+	}, nil
+}
+
+// agentFromContext возвращает имя агента из метаданных запроса.
+func agentFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(AgentNameMetadataKey)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+// toStatus преобразует ошибку бизнес-логики в статус ответа.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrResourceIsLocked):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+func metricTypeToDomain(t metricspb.MetricType) (domain.MetricType, error) {
+	switch t {
+	case metricspb.MetricType_METRIC_TYPE_GAUGE:
+		return domain.GaugeMetricType, nil
+	case metricspb.MetricType_METRIC_TYPE_COUNTER:
+		return domain.CounterMetricType, nil
+	}
+
+	return "", fmt.Errorf("unknown metric type: %v", t)
+}
+
+func metricToDomain(m *metricspb.Metric) (domain.MetricValue, error) {
+	t, err := metricTypeToDomain(m.GetType())
+	if err != nil {
+		return domain.MetricValue{}, err
+	}
+
+	labels := domain.Labels(m.GetLabels())
+	if err = labels.Validate(); err != nil {
+		return domain.MetricValue{}, err
+	}
+
+	v := domain.MetricValue{
+		Type:   t,
+		Name:   m.GetName(),
+		Labels: labels,
+	}
+	switch value := m.GetValue().(type) {
+	case *metricspb.Metric_Gauge:
+		if t != domain.GaugeMetricType {
+			return domain.MetricValue{}, fmt.Errorf("metric %s: gauge value for %s", v.Name, t)
+		}
+		v.GaugeValue = value.Gauge
+	case *metricspb.Metric_Delta:
+		if t != domain.CounterMetricType {
+			return domain.MetricValue{}, fmt.Errorf("metric %s: counter value for %s", v.Name, t)
+		}
+		v.CounterValue = value.Delta
+	default:
+		return domain.MetricValue{}, fmt.Errorf("metric %s: value is empty", v.Name)
+	}
+
+	return v, nil
+}
+
+func metricFromDomain(v domain.MetricValue) *metricspb.Metric {
+	m := &metricspb.Metric{
+		Name:   v.Name,
+		Labels: v.Labels,
+	}
+
+	switch v.Type {
+	case domain.GaugeMetricType:
+		m.Type = metricspb.MetricType_METRIC_TYPE_GAUGE
+		m.Value = &metricspb.Metric_Gauge{Gauge: v.GaugeValue}
+	case domain.CounterMetricType:
+		m.Type = metricspb.MetricType_METRIC_TYPE_COUNTER
+		m.Value = &metricspb.Metric_Delta{Delta: v.CounterValue}
+	}
+
+	return m
+}
@@ -0,0 +1,324 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+func newTestClient(t *testing.T, uc useCases, opts ...grpc.ServerOption) metricspb.MetricsClient {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	metricspb.RegisterMetricsServer(srv, NewHandlers(uc))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	return metricspb.NewMetricsClient(conn)
+}
+
+func gauge(name string, value float64, labels map[string]string) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name:   name,
+		Type:   metricspb.MetricType_METRIC_TYPE_GAUGE,
+		Labels: labels,
+		Value:  &metricspb.Metric_Gauge{Gauge: value},
+	}
+}
+
+func counter(name string, delta int64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name:  name,
+		Type:  metricspb.MetricType_METRIC_TYPE_COUNTER,
+		Value: &metricspb.Metric_Delta{Delta: delta},
+	}
+}
+
+func TestHandlers_UpdateMetrics(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		metrics  []*metricspb.Metric
+		err      error
+		wantCode codes.Code
+		want     []domain.MetricValue
+	}{
+		{
+			name:     "success",
+			metrics:  []*metricspb.Metric{gauge("Alloc", 1.5, map[string]string{"host": "a"}), counter("PollCount", 2)},
+			wantCode: codes.OK,
+			want: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "Alloc", Labels: domain.Labels{"host": "a"}, GaugeValue: 1.5},
+				{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 2},
+			},
+		},
+		{
+			name:     "unknown type",
+			metrics:  []*metricspb.Metric{{Name: "Alloc", Value: &metricspb.Metric_Gauge{Gauge: 1}}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "counter with gauge value",
+			metrics: []*metricspb.Metric{{
+				Name:  "PollCount",
+				Type:  metricspb.MetricType_METRIC_TYPE_COUNTER,
+				Value: &metricspb.Metric_Gauge{Gauge: 1},
+			}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "empty value",
+			metrics:  []*metricspb.Metric{{Name: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE}},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "invalid labels",
+			metrics:  []*metricspb.Metric{gauge("Alloc", 1, map[string]string{"1host": "a"})},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "locked",
+			metrics:  []*metricspb.Metric{counter("PollCount", 1)},
+			err:      domain.ErrResourceIsLocked,
+			wantCode: codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			uc := &metricUseCaseMock{err: tt.err}
+			client := newTestClient(t, uc)
+
+			ctx := metadata.AppendToOutgoingContext(context.Background(), AgentNameMetadataKey, "agent-1")
+			_, err := client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{Metrics: tt.metrics})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("UpdateMetrics() code = %v, want %v: %v", code, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if uc.agent != "agent-1" {
+				t.Errorf("agent = %q, want agent-1", uc.agent)
+			}
+			assertMetrics(t, uc.updated, tt.want)
+		})
+	}
+}
+
+func TestHandlers_UpdateMetricsStream(t *testing.T) {
+	t.Parallel()
+
+	uc := &metricUseCaseMock{}
+	client := newTestClient(t, uc)
+
+	stream, err := client.UpdateMetricsStream(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if err = stream.Send(&metricspb.UpdateMetricsRequest{
+			Metrics: []*metricspb.Metric{counter("PollCount", int64(i+1))},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = stream.CloseAndRecv(); err != nil {
+		t.Fatal(err)
+	}
+
+	assertMetrics(t, uc.updated, []domain.MetricValue{
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 1},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 2},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 3},
+	})
+
+	t.Run("invalid part", func(t *testing.T) {
+		t.Parallel()
+
+		client := newTestClient(t, &metricUseCaseMock{})
+		stream, err := client.UpdateMetricsStream(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = stream.Send(&metricspb.UpdateMetricsRequest{
+			Metrics: []*metricspb.Metric{{Name: "PollCount"}},
+		})
+		if _, err = stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("CloseAndRecv() code = %v, want %v", status.Code(err), codes.InvalidArgument)
+		}
+	})
+}
+
+func TestHandlers_GetMetric(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		req      *metricspb.GetMetricRequest
+		uc       *metricUseCaseMock
+		wantCode codes.Code
+		want     *metricspb.Metric
+	}{
+		{
+			name: "gauge",
+			req:  &metricspb.GetMetricRequest{Name: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE},
+			uc: &metricUseCaseMock{
+				value: domain.MetricValue{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 2.5},
+			},
+			wantCode: codes.OK,
+			want:     gauge("Alloc", 2.5, nil),
+		},
+		{
+			name: "counter",
+			req:  &metricspb.GetMetricRequest{Name: "PollCount", Type: metricspb.MetricType_METRIC_TYPE_COUNTER},
+			uc: &metricUseCaseMock{
+				value: domain.MetricValue{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 7},
+			},
+			wantCode: codes.OK,
+			want:     counter("PollCount", 7),
+		},
+		{
+			name:     "not found",
+			req:      &metricspb.GetMetricRequest{Name: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE},
+			uc:       &metricUseCaseMock{err: domain.ErrNotFound},
+			wantCode: codes.NotFound,
+		},
+		{
+			name:     "unknown type",
+			req:      &metricspb.GetMetricRequest{Name: "Alloc"},
+			uc:       &metricUseCaseMock{},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "internal",
+			req:      &metricspb.GetMetricRequest{Name: "Alloc", Type: metricspb.MetricType_METRIC_TYPE_GAUGE},
+			uc:       &metricUseCaseMock{err: errors.New("some error")},
+			wantCode: codes.Internal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := newTestClient(t, tt.uc).GetMetric(context.Background(), tt.req)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("GetMetric() code = %v, want %v: %v", code, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			got, err := metricToDomain(resp.GetMetric())
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, _ := metricToDomain(tt.want)
+			assertMetrics(t, []domain.MetricValue{got}, []domain.MetricValue{want})
+		})
+	}
+}
+
+func TestHandlers_ListMetrics(t *testing.T) {
+	t.Parallel()
+
+	values := []domain.MetricValue{
+		{Type: domain.GaugeMetricType, Name: "Alloc", Labels: domain.Labels{"host": "a"}, GaugeValue: 1},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 3},
+	}
+
+	tests := []struct {
+		name         string
+		match        []string
+		wantCode     codes.Code
+		wantMatchers int
+	}{
+		{
+			name:     "all",
+			wantCode: codes.OK,
+		},
+		{
+			name:         "matchers",
+			match:        []string{"host=a,env!=dev", "dc=~eu.*"},
+			wantCode:     codes.OK,
+			wantMatchers: 3,
+		},
+		{
+			name:     "invalid matcher",
+			match:    []string{"host"},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			uc := &metricUseCaseMock{values: values}
+			resp, err := newTestClient(t, uc).ListMetrics(context.Background(),
+				&metricspb.ListMetricsRequest{Match: tt.match})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("ListMetrics() code = %v, want %v: %v", code, tt.wantCode, err)
+			}
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			if len(uc.matchers) != tt.wantMatchers {
+				t.Errorf("matchers = %d, want %d", len(uc.matchers), tt.wantMatchers)
+			}
+
+			got := make([]domain.MetricValue, 0, len(resp.GetMetrics()))
+			for _, m := range resp.GetMetrics() {
+				v, err := metricToDomain(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, v)
+			}
+			assertMetrics(t, got, values)
+		})
+	}
+}
+
+func assertMetrics(t *testing.T, got, want []domain.MetricValue) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("metrics = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Type != want[i].Type || got[i].Name != want[i].Name ||
+			got[i].GaugeValue != want[i].GaugeValue || got[i].CounterValue != want[i].CounterValue ||
+			!got[i].Labels.Equal(want[i].Labels) {
+			t.Errorf("metrics[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+	"github.com/kdv2001/onlyMetrics/pkg/tlsutil"
+)
+
+// RealIPMetadataKey ключ метаданных с адресом агента, приславшего метрики.
+const RealIPMetadataKey = "x-real-ip"
+
+// ingestionMethods методы сбора метрик, доступные только из доверенной подсети.
+var ingestionMethods = map[string]struct{}{
+	metricspb.Metrics_UpdateMetrics_FullMethodName:       {},
+	metricspb.Metrics_UpdateMetricsStream_FullMethodName: {},
+}
+
+// LoggerUnaryInterceptor помещает logger в context и логирует запросы.
+func LoggerUnaryInterceptor(sugarLogger *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		ctx = logger.ToContext(ctx, sugarLogger)
+		start := time.Now()
+
+		resp, err := handler(ctx, req)
+		logger.Infof(ctx, "request: method: %s; code: %s; processing time: %s",
+			info.FullMethod, status.Code(err), time.Since(start).String())
+
+		return resp, err
+	}
+}
+
+// LoggerStreamInterceptor помещает logger в context потока и логирует запросы.
+func LoggerStreamInterceptor(sugarLogger *zap.SugaredLogger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		ctx := logger.ToContext(ss.Context(), sugarLogger)
+		start := time.Now()
+
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logger.Infof(ctx, "request: method: %s; code: %s; processing time: %s",
+			info.FullMethod, status.Code(err), time.Since(start).String())
+
+		return err
+	}
+}
+
+// contextStream поток с подмененным контекстом.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context ...
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// TrustedSubnetUnaryInterceptor пропускает запросы сбора метрик только от агентов
+// с адресом из метаданных x-real-ip внутри доверенной подсети. При nil подсети пропускаются все запросы.
+func TrustedSubnetUnaryInterceptor(subnet *net.IPNet) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		if err := checkTrustedSubnet(ctx, subnet, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// TrustedSubnetStreamInterceptor потоковый вариант TrustedSubnetUnaryInterceptor.
+func TrustedSubnetStreamInterceptor(subnet *net.IPNet) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if err := checkTrustedSubnet(ss.Context(), subnet, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+func checkTrustedSubnet(ctx context.Context, subnet *net.IPNet, method string) error {
+	if subnet == nil {
+		return nil
+	}
+	if _, exist := ingestionMethods[method]; !exist {
+		return nil
+	}
+
+	var ip net.IP
+	if values := metadata.ValueFromIncomingContext(ctx, RealIPMetadataKey); len(values) > 0 {
+		ip = net.ParseIP(values[0])
+	}
+	if ip == nil || !subnet.Contains(ip) {
+		return status.Error(codes.PermissionDenied, "address is not in trusted subnet")
+	}
+
+	return nil
+}
+
+// TLSAgentUnaryInterceptor задает имя агента из проверенного сертификата клиента.
+// Имя из сертификата заменяет значение метаданных x-agent-name.
+func TLSAgentUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (any, error) {
+		return handler(withTLSAgent(ctx), req)
+	}
+}
+
+// TLSAgentStreamInterceptor потоковый вариант TLSAgentUnaryInterceptor.
+func TLSAgentStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withTLSAgent(ss.Context())})
+	}
+}
+
+func withTLSAgent(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return ctx
+	}
+	name := tlsutil.PeerName(&info.State)
+	if name == "" {
+		return ctx
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Set(AgentNameMetadataKey, name)
+
+	return metadata.NewIncomingContext(ctx, md)
+}
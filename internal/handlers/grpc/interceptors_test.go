@@ -0,0 +1,152 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/kdv2001/onlyMetrics/internal/proto/metricspb"
+)
+
+func TestTrustedSubnetInterceptor(t *testing.T) {
+	t.Parallel()
+
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		subnet   *net.IPNet
+		realIP   string
+		wantCode codes.Code
+	}{
+		{
+			name:     "inside subnet",
+			subnet:   subnet,
+			realIP:   "192.168.1.10",
+			wantCode: codes.OK,
+		},
+		{
+			name:     "outside subnet",
+			subnet:   subnet,
+			realIP:   "10.0.0.1",
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "without metadata",
+			subnet:   subnet,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "subnet not set",
+			wantCode: codes.OK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := newTestClient(t, &metricUseCaseMock{},
+				grpc.UnaryInterceptor(TrustedSubnetUnaryInterceptor(tt.subnet)),
+				grpc.StreamInterceptor(TrustedSubnetStreamInterceptor(tt.subnet)))
+
+			ctx := context.Background()
+			if tt.realIP != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, RealIPMetadataKey, tt.realIP)
+			}
+
+			_, err := client.UpdateMetrics(ctx, &metricspb.UpdateMetricsRequest{
+				Metrics: []*metricspb.Metric{counter("PollCount", 1)},
+			})
+			if code := status.Code(err); code != tt.wantCode {
+				t.Errorf("UpdateMetrics() code = %v, want %v", code, tt.wantCode)
+			}
+
+			stream, err := client.UpdateMetricsStream(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = stream.CloseAndRecv(); status.Code(err) != tt.wantCode {
+				t.Errorf("UpdateMetricsStream() code = %v, want %v", status.Code(err), tt.wantCode)
+			}
+
+			// запросы чтения доступны из любой подсети
+			if _, err = client.ListMetrics(context.Background(), &metricspb.ListMetricsRequest{}); err != nil {
+				t.Errorf("ListMetrics() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTLSAgentUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	verified := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "agent-1"}},
+	}}}}
+
+	tests := []struct {
+		name     string
+		authInfo credentials.AuthInfo
+		agent    string
+		want     string
+	}{
+		{
+			name:     "verified certificate",
+			authInfo: verified,
+			want:     "agent-1",
+		},
+		{
+			name:     "certificate overrides metadata",
+			authInfo: verified,
+			agent:    "spoofed",
+			want:     "agent-1",
+		},
+		{
+			name:     "not verified",
+			authInfo: credentials.TLSInfo{},
+			agent:    "agent-2",
+			want:     "agent-2",
+		},
+		{
+			name:  "without tls",
+			agent: "agent-2",
+			want:  "agent-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: tt.authInfo})
+			if tt.agent != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(AgentNameMetadataKey, tt.agent))
+			}
+
+			var got string
+			_, err := TLSAgentUnaryInterceptor()(ctx, nil, &grpc.UnaryServerInfo{},
+				func(ctx context.Context, _ any) (any, error) {
+					got = agentFromContext(ctx)
+					return nil, nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type metricUseCaseMock struct {
+	value  domain.MetricValue
+	values []domain.MetricValue
+	err    error
+
+	mu       sync.Mutex
+	agent    string
+	updated  []domain.MetricValue
+	matchers []domain.LabelMatcher
+}
+
+func (m *metricUseCaseMock) GetMetric(_ context.Context, _ domain.MetricType,
+	_ string, _ domain.Labels) (domain.MetricValue, error) {
+	return m.value, m.err
+}
+
+func (m *metricUseCaseMock) GetAllMetrics(_ context.Context,
+	matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.matchers = matchers
+
+	return m.values, m.err
+}
+
+func (m *metricUseCaseMock) UpdateMetrics(_ context.Context, agent string, metrics []domain.MetricValue) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent = agent
+	m.updated = append(m.updated, metrics...)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// SilentForQueryKey параметр запроса, отбирающий агентов, не присылавших метрики дольше заданного интервала.
+const SilentForQueryKey = "silent_for"
+
+type agent struct {
+	Name     string    `json:"name"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type agentsResponse struct {
+	Agents []agent `json:"agents"`
+}
+
+// GetAgents обработчик для получения списка агентов и времени последнего обновления метрик каждым из них.
+//
+//	@Summary		get agents
+//	@Description	get agents and their last seen time
+//	@Tags			agent
+//	@Produce		json
+//	@Param			silent_for	query		string	false	"only agents silent longer than duration, e.g. 5m"
+//	@Success		200			{object}	http.agentsResponse
+//	@Failure		400			{object}	string
+//	@Failure		500			{object}	string
+//	@Router			/api/v1/agents [get]
+func (h *Handlers) GetAgents(w http.ResponseWriter, r *http.Request) {
+	var silentFor time.Duration
+	if v := r.URL.Query().Get(SilentForQueryKey); v != "" {
+		var err error
+		silentFor, err = time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	values, err := h.metricUseCases.GetAgents(r.Context(), silentFor)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := agentsResponse{
+		Agents: make([]agent, 0, len(values)),
+	}
+	for _, v := range values {
+		res.Agents = append(res.Agents, agent{
+			Name:     v.Name,
+			LastSeen: v.LastSeen,
+		})
+	}
+
+	writeJSON(w, res)
+}
+
+// GetAgent обработчик для получения времени последнего обновления метрик агентом.
+//
+//	@Summary		get agent
+//	@Description	get agent last seen time
+//	@Tags			agent
+//	@Produce		json
+//	@Param			agentName	path		string	true	"agentName"
+//	@Success		200			{object}	http.agent
+//	@Failure		404			{object}	string
+//	@Failure		500			{object}	string
+//	@Router			/api/v1/agents/{agentName} [get]
+func (h *Handlers) GetAgent(w http.ResponseWriter, r *http.Request) {
+	v, err := h.metricUseCases.GetAgent(r.Context(), chi.URLParam(r, AgentNamePathKey))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, agent{
+		Name:     v.Name,
+		LastSeen: v.LastSeen,
+	})
+}
+
+// writeJSON записывает ответ в формате JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(ContentType, ApplicationJSON)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(b)
+}
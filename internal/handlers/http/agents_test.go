@@ -0,0 +1,156 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestHandlers_GetAgents(t *testing.T) {
+	t.Parallel()
+	lastSeen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type expected struct {
+		status int
+		agents int
+	}
+	tests := []struct {
+		name     string
+		query    string
+		useCases useCases
+		expected expected
+	}{
+		{
+			name:  "success",
+			query: "",
+			useCases: &metricUseCaseMock{
+				agents: []domain.Agent{
+					{Name: "host-1", LastSeen: lastSeen},
+					{Name: "host-2", LastSeen: lastSeen},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				agents: 2,
+			},
+		},
+		{
+			name:     "success silent for",
+			query:    "?silent_for=5m",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusOK,
+				agents: 0,
+			},
+		},
+		{
+			name:     "invalid silent for",
+			query:    "?silent_for=five",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name:  "error",
+			query: "",
+			useCases: &metricUseCaseMock{
+				err: errors.New("some error"),
+			},
+			expected: expected{
+				status: http.StatusInternalServerError,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := NewHandlers(tt.useCases)
+			w := httptest.NewRecorder()
+			h.GetAgents(w, httptest.NewRequest(http.MethodGet, "/api/v1/agents"+tt.query, nil))
+
+			if w.Code != tt.expected.status {
+				t.Errorf("got %d, want %d", w.Code, tt.expected.status)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var res agentsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error unmarshal response: %v", err)
+			}
+			if len(res.Agents) != tt.expected.agents {
+				t.Errorf("got %d agents, want %d", len(res.Agents), tt.expected.agents)
+			}
+		})
+	}
+}
+
+func TestHandlers_GetAgent(t *testing.T) {
+	t.Parallel()
+	lastSeen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		agent    string
+		useCases useCases
+		status   int
+	}{
+		{
+			name:  "success",
+			agent: "host-1",
+			useCases: &metricUseCaseMock{
+				agents: []domain.Agent{{Name: "host-1", LastSeen: lastSeen}},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:     "not found",
+			agent:    "host-2",
+			useCases: &metricUseCaseMock{},
+			status:   http.StatusNotFound,
+		},
+		{
+			name:  "error",
+			agent: "host-1",
+			useCases: &metricUseCaseMock{
+				err: errors.New("some error"),
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := NewHandlers(tt.useCases)
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/agents/"+tt.agent, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add(AgentNamePathKey, tt.agent)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+			h.GetAgent(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("got %d, want %d", w.Code, tt.status)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var res agent
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error unmarshal response: %v", err)
+			}
+			if res.Name != tt.agent || !res.LastSeen.Equal(lastSeen) {
+				t.Errorf("got %+v", res)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+package http
+
+import (
+	"context"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type alertUseCaseMock struct {
+	alerts []domain.Alert
+	err    error
+}
+
+func (m *alertUseCaseMock) GetAlerts(_ context.Context) ([]domain.Alert, error) {
+	return m.alerts, m.err
+}
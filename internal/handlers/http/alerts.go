@@ -0,0 +1,86 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type alertUseCases interface {
+	GetAlerts(ctx context.Context) ([]domain.Alert, error)
+}
+
+// AlertHandlers http обработчики для предоставления алертов клиенту.
+type AlertHandlers struct {
+	alertUseCases alertUseCases
+}
+
+// NewAlertHandlers создает объект http обработчиков для предоставления алертов клиенту.
+func NewAlertHandlers(useCases alertUseCases) *AlertHandlers {
+	return &AlertHandlers{
+		alertUseCases: useCases,
+	}
+}
+
+// alert JSON представление алерта в ответе API.
+type alert struct {
+	Rule        string            `json:"rule"`
+	Description string            `json:"description,omitempty"`
+	State       string            `json:"state"`
+	MType       string            `json:"type"`
+	ID          string            `json:"id"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Value       float64           `json:"value"`
+	ActiveAt    time.Time         `json:"activeAt"`
+	FiredAt     *time.Time        `json:"firedAt,omitempty"`
+	ResolvedAt  *time.Time        `json:"resolvedAt,omitempty"`
+}
+
+type alertsResponse struct {
+	Alerts []alert `json:"alerts"`
+}
+
+// GetAlerts обработчик для получения текущего списка алертов.
+//
+//	@Summary		get alerts
+//	@Description	get pending, firing and resolved alerts
+//	@Tags			alert
+//	@Produce		json
+//	@Success		200	{object}	http.alertsResponse
+//	@Failure		500	{object}	string
+//	@Router			/api/v1/alerts [get]
+func (h *AlertHandlers) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	values, err := h.alertUseCases.GetAlerts(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := alertsResponse{
+		Alerts: make([]alert, 0, len(values)),
+	}
+	for _, v := range values {
+		a := alert{
+			Rule:        v.RuleName,
+			Description: v.Description,
+			State:       v.State.String(),
+			MType:       v.MetricType.String(),
+			ID:          v.MetricName,
+			Labels:      v.Labels,
+			Value:       v.Value,
+			ActiveAt:    v.ActiveAt,
+		}
+		if !v.FiredAt.IsZero() {
+			a.FiredAt = &v.FiredAt
+		}
+		if !v.ResolvedAt.IsZero() {
+			a.ResolvedAt = &v.ResolvedAt
+		}
+
+		res.Alerts = append(res.Alerts, a)
+	}
+
+	writeJSON(w, res)
+}
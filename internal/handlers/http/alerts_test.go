@@ -0,0 +1,87 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestAlertHandlers_GetAlerts(t *testing.T) {
+	t.Parallel()
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type expected struct {
+		status int
+		alerts int
+	}
+	tests := []struct {
+		name     string
+		useCases alertUseCases
+		expected expected
+	}{
+		{
+			name: "success",
+			useCases: &alertUseCaseMock{
+				alerts: []domain.Alert{
+					{
+						RuleName:   "HighHeap",
+						State:      domain.AlertStateFiring,
+						MetricType: domain.GaugeMetricType,
+						MetricName: "HeapAlloc",
+						Value:      100,
+						ActiveAt:   activeAt,
+						FiredAt:    activeAt.Add(time.Minute),
+					},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				alerts: 1,
+			},
+		},
+		{
+			name:     "empty",
+			useCases: &alertUseCaseMock{},
+			expected: expected{
+				status: http.StatusOK,
+				alerts: 0,
+			},
+		},
+		{
+			name: "error",
+			useCases: &alertUseCaseMock{
+				err: errors.New("some error"),
+			},
+			expected: expected{
+				status: http.StatusInternalServerError,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := NewAlertHandlers(tt.useCases)
+			w := httptest.NewRecorder()
+			h.GetAlerts(w, httptest.NewRequest(http.MethodGet, "/api/v1/alerts", nil))
+
+			if w.Code != tt.expected.status {
+				t.Errorf("got %d, want %d", w.Code, tt.expected.status)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var res alertsResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error unmarshal response: %v", err)
+			}
+			if len(res.Alerts) != tt.expected.alerts {
+				t.Errorf("got %d alerts, want %d", len(res.Alerts), tt.expected.alerts)
+			}
+		})
+	}
+}
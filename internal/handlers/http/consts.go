@@ -7,9 +7,17 @@ const (
 	Accept          = "Accept"
 	AcceptEncoding  = "Accept-Encoding"
 
-	ApplicationJSON = "application/json"
-	TextHTML        = "text/html"
-	Gzip            = "gzip"
+	ApplicationJSON     = "application/json"
+	TextHTML            = "text/html"
+	TextPlainPrometheus = "text/plain; version=0.0.4; charset=utf-8"
+	Gzip                = "gzip"
 
 	HashSHA256 = "HashSHA256"
+
+	// AgentName заголовок с именем агента, приславшего метрики.
+	AgentName = "X-Agent-Name"
+	// Encryption заголовок со схемой шифрования тела запроса.
+	Encryption = "X-Encryption"
+	// RealIP заголовок с адресом агента, приславшего метрики.
+	RealIP = "X-Real-IP"
 )
@@ -6,10 +6,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
@@ -18,12 +20,15 @@ import (
 )
 
 type useCases interface {
-	UpdateMetric(ctx context.Context, value domain.MetricValue) error
+	UpdateMetric(ctx context.Context, agent string, value domain.MetricValue) error
 	GetMetric(ctx context.Context, value domain.MetricType,
-		name string) (domain.MetricValue, error)
-	GetAllMetrics(ctx context.Context) ([]domain.MetricValue, error)
+		name string, labels domain.Labels) (domain.MetricValue, error)
+	GetAllMetrics(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error)
 	Ping(ctx context.Context) error
-	UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error
+	UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error
+	GetAgents(ctx context.Context, silentFor time.Duration) ([]domain.Agent, error)
+	GetAgent(ctx context.Context, name string) (domain.Agent, error)
+	QueryRange(ctx context.Context, q domain.RangeQuery) ([]domain.Point, error)
 }
 
 //	@Title			onlyMetric API
@@ -51,9 +56,47 @@ const (
 	MetricTypePathKey = "metricType"
 	MetricNamePathKey = "metricName"
 	ValuePathKey      = "value"
+	AgentNamePathKey  = "agentName"
+
+	// MatchQueryKey параметр запроса с условиями отбора метрик по меткам.
+	MatchQueryKey = "match"
 )
 
-// CollectMetric обработчик сбора метрик из URL параметров.
+// labelsFromQuery возвращает метки метрики из параметров запроса.
+func labelsFromQuery(r *http.Request) (domain.Labels, error) {
+	query := r.URL.Query()
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	labels := make(domain.Labels, len(query))
+	for name := range query {
+		labels[name] = query.Get(name)
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// matchersFromQuery возвращает условия отбора метрик по меткам из параметров запроса.
+func matchersFromQuery(r *http.Request) ([]domain.LabelMatcher, error) {
+	res := make([]domain.LabelMatcher, 0)
+	for _, value := range r.URL.Query()[MatchQueryKey] {
+		matchers, err := domain.ParseLabelMatchers(value)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, matchers...)
+	}
+
+	return res, nil
+}
+
+// CollectMetric обработчик сбора метрик из URL параметров, метки метрики передаются в параметрах запроса.
 //
 //	@Summary		collect metric
 //	@Description	collect metric from query
@@ -75,6 +118,12 @@ func (h *Handlers) CollectMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	labels, err := labelsFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var v domain.MetricValue
 	switch t {
 	case domain.GaugeMetricType:
@@ -86,6 +135,7 @@ func (h *Handlers) CollectMetric(w http.ResponseWriter, r *http.Request) {
 		v = domain.MetricValue{
 			Type:       t,
 			Name:       chi.URLParam(r, MetricNamePathKey),
+			Labels:     labels,
 			GaugeValue: mValue,
 		}
 	case domain.CounterMetricType:
@@ -97,11 +147,12 @@ func (h *Handlers) CollectMetric(w http.ResponseWriter, r *http.Request) {
 		v = domain.MetricValue{
 			Type:         t,
 			Name:         chi.URLParam(r, MetricNamePathKey),
+			Labels:       labels,
 			CounterValue: mValue,
 		}
 	}
 
-	err = h.metricUseCases.UpdateMetric(r.Context(), v)
+	err = h.metricUseCases.UpdateMetric(r.Context(), r.Header.Get(AgentName), v)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -117,10 +168,11 @@ func (h *Handlers) CollectMetric(w http.ResponseWriter, r *http.Request) {
 }
 
 type metric struct {
-	ID    string   `json:"id"`              // Имя метрики
-	MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-	Delta *int64   `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-	Value *float64 `json:"value,omitempty"` // Значение метрики в случае передачи gauge
+	ID     string            `json:"id"`               // Имя метрики
+	MType  string            `json:"type"`             // параметр, принимающий значение gauge или counter
+	Labels map[string]string `json:"labels,omitempty"` // Метки метрики
+	Delta  *int64            `json:"delta,omitempty"`  // Значение метрики в случае передачи counter
+	Value  *float64          `json:"value,omitempty"`  // Значение метрики в случае передачи gauge
 }
 
 // CollectBodyMetric обработчик сбора метрик из тела запроса.
@@ -156,7 +208,7 @@ func (h *Handlers) CollectBodyMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.metricUseCases.UpdateMetric(r.Context(), v)
+	err = h.metricUseCases.UpdateMetric(r.Context(), r.Header.Get(AgentName), v)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -171,7 +223,7 @@ func (h *Handlers) CollectBodyMetric(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
-// GetMetric обработчик для получения метрики.
+// GetMetric обработчик для получения метрики, метки метрики передаются в параметрах запроса.
 //
 //	@Summary		get metric
 //	@Description	get metric
@@ -192,7 +244,13 @@ func (h *Handlers) GetMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	val, err := h.metricUseCases.GetMetric(r.Context(), t, chi.URLParam(r, MetricNamePathKey))
+	labels, err := labelsFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	val, err := h.metricUseCases.GetMetric(r.Context(), t, chi.URLParam(r, MetricNamePathKey), labels)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -223,13 +281,20 @@ func (h *Handlers) GetMetric(w http.ResponseWriter, r *http.Request) {
 //	@Tags			metric
 //	@Accept			plain
 //	@Produce		plain
-//	@Success		200	{string}	string
+//	@Param			match	query		string	false	"label matchers, e.g. host=a,env!=dev"
+//	@Success		200		{string}	string
 //	@Failure		400	{object}	string
 //	@Failure		423	{object}	string
 //	@Failure		500	{object}	string
 //	@Router			/  [get]
 func (h *Handlers) GetAllMetric(w http.ResponseWriter, r *http.Request) {
-	values, err := h.metricUseCases.GetAllMetrics(r.Context())
+	matchers, err := matchersFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	values, err := h.metricUseCases.GetAllMetrics(r.Context(), matchers...)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -249,11 +314,11 @@ func (h *Handlers) GetAllMetric(w http.ResponseWriter, r *http.Request) {
 		switch v.Type {
 		case domain.GaugeMetricType:
 			resStrs = append(resStrs,
-				fmt.Sprintf("<br>%s %f</br>", v.Name, v.GaugeValue),
+				fmt.Sprintf("<br>%s %f</br>", html.EscapeString(v.SeriesKey()), v.GaugeValue),
 			)
 		default:
 			resStrs = append(resStrs,
-				fmt.Sprintf("<br>%s %d</br>", v.Name, v.CounterValue),
+				fmt.Sprintf("<br>%s %d</br>", html.EscapeString(v.SeriesKey()), v.CounterValue),
 			)
 		}
 	}
@@ -304,7 +369,13 @@ func (h *Handlers) GetBodyMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	val, err := h.metricUseCases.GetMetric(r.Context(), mType, parsedMetric.ID)
+	labels := domain.Labels(parsedMetric.Labels)
+	if err = labels.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	val, err := h.metricUseCases.GetMetric(r.Context(), mType, parsedMetric.ID, labels)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -323,15 +394,17 @@ func (h *Handlers) GetBodyMetric(w http.ResponseWriter, r *http.Request) {
 	switch val.Type {
 	case domain.GaugeMetricType:
 		v = metric{
-			ID:    val.Name,
-			MType: val.Type.String(),
-			Value: &val.GaugeValue,
+			ID:     val.Name,
+			MType:  val.Type.String(),
+			Labels: val.Labels,
+			Value:  &val.GaugeValue,
 		}
 	case domain.CounterMetricType:
 		v = metric{
-			ID:    val.Name,
-			MType: val.Type.String(),
-			Delta: &val.CounterValue,
+			ID:     val.Name,
+			MType:  val.Type.String(),
+			Labels: val.Labels,
+			Delta:  &val.CounterValue,
 		}
 	}
 
@@ -403,7 +476,7 @@ func (h *Handlers) UpdateMetrics(w http.ResponseWriter, r *http.Request) {
 		res = append(res, v)
 	}
 
-	err = h.metricUseCases.UpdateMetrics(r.Context(), res)
+	err = h.metricUseCases.UpdateMetrics(r.Context(), r.Header.Get(AgentName), res)
 	if err != nil {
 		switch {
 		case errors.Is(err, domain.ErrResourceIsLocked):
@@ -424,6 +497,11 @@ func metricToDomain(parsedMetric metric) (domain.MetricValue, error) {
 		return domain.MetricValue{}, err
 	}
 
+	labels := domain.Labels(parsedMetric.Labels)
+	if err = labels.Validate(); err != nil {
+		return domain.MetricValue{}, err
+	}
+
 	var v domain.MetricValue
 	switch mType {
 	case domain.GaugeMetricType:
@@ -435,6 +513,7 @@ func metricToDomain(parsedMetric metric) (domain.MetricValue, error) {
 		v = domain.MetricValue{
 			Type:       mType,
 			Name:       parsedMetric.ID,
+			Labels:     labels,
 			GaugeValue: resValue,
 		}
 	case domain.CounterMetricType:
@@ -450,6 +529,7 @@ func metricToDomain(parsedMetric metric) (domain.MetricValue, error) {
 		v = domain.MetricValue{
 			Type:         mType,
 			Name:         parsedMetric.ID,
+			Labels:       labels,
 			CounterValue: resValue,
 		}
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -379,3 +380,61 @@ func TestHandlers_GetMetric(t *testing.T) {
 		})
 	}
 }
+
+func Test_metricToDomain(t *testing.T) {
+	t.Parallel()
+	gaugeValue := float64(100)
+	tests := []struct {
+		name    string
+		metric  metric
+		want    domain.MetricValue
+		wantErr bool
+	}{
+		{
+			name: "gauge with labels",
+			metric: metric{
+				ID:     "HeapAlloc",
+				MType:  "gauge",
+				Labels: map[string]string{"host": "a"},
+				Value:  &gaugeValue,
+			},
+			want: domain.MetricValue{
+				Type:       domain.GaugeMetricType,
+				Name:       "HeapAlloc",
+				Labels:     domain.Labels{"host": "a"},
+				GaugeValue: 100,
+			},
+		},
+		{
+			name: "invalid label name",
+			metric: metric{
+				ID:     "HeapAlloc",
+				MType:  "gauge",
+				Labels: map[string]string{"host-name": "a"},
+				Value:  &gaugeValue,
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty counter value",
+			metric: metric{
+				ID:    "PollCount",
+				MType: "counter",
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := metricToDomain(tt.metric)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("metricToDomain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metricToDomain() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,32 +2,58 @@ package http
 
 import (
 	"context"
+	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
 )
 
 type metricUseCaseMock struct {
-	value domain.MetricValue
-	err   error
+	value  domain.MetricValue
+	values []domain.MetricValue
+	agents []domain.Agent
+	points []domain.Point
+	err    error
 }
 
-func (m *metricUseCaseMock) UpdateMetric(ctx context.Context, value domain.MetricValue) error {
+func (m *metricUseCaseMock) UpdateMetric(ctx context.Context, _ string, value domain.MetricValue) error {
 	return m.err
 }
 
 func (m *metricUseCaseMock) GetMetric(_ context.Context, _ domain.MetricType,
-	_ string) (domain.MetricValue, error) {
+	_ string, _ domain.Labels) (domain.MetricValue, error) {
 	return m.value, m.err
 }
 
-func (m *metricUseCaseMock) GetAllMetrics(ctx context.Context) ([]domain.MetricValue, error) {
-	return nil, nil
+func (m *metricUseCaseMock) GetAllMetrics(ctx context.Context, _ ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	return m.values, m.err
 }
 
 func (m *metricUseCaseMock) Ping(_ context.Context) error {
 	return nil
 }
 
-func (m *metricUseCaseMock) UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error {
+func (m *metricUseCaseMock) UpdateMetrics(ctx context.Context, _ string, metrics []domain.MetricValue) error {
 	return nil
 }
+
+func (m *metricUseCaseMock) GetAgents(_ context.Context, _ time.Duration) ([]domain.Agent, error) {
+	return m.agents, m.err
+}
+
+func (m *metricUseCaseMock) GetAgent(_ context.Context, name string) (domain.Agent, error) {
+	if m.err != nil {
+		return domain.Agent{}, m.err
+	}
+
+	for _, a := range m.agents {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+
+	return domain.Agent{}, domain.ErrNotFound
+}
+
+func (m *metricUseCaseMock) QueryRange(_ context.Context, _ domain.RangeQuery) ([]domain.Point, error) {
+	return m.points, m.err
+}
@@ -4,15 +4,22 @@ import (
 	"bytes"
 	"compress/gzip"
 	"crypto/hmac"
+	"crypto/rsa"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
 	"github.com/kdv2001/onlyMetrics/pkg/logger"
+	"github.com/kdv2001/onlyMetrics/pkg/tlsutil"
 )
 
 // hashWriter реализация интерфейса writer для перехвата информации ответа, последующего вычисления хэша
@@ -104,6 +111,44 @@ func NewSha256Middleware(key string) func(handler http.Handler) http.Handler {
 	}
 }
 
+// TrustedSubnetMiddleware создаёт middleware, пропускающий только запросы агентов
+// с адресом из заголовка X-Real-IP внутри доверенной подсети. При nil подсети пропускаются все запросы.
+func TrustedSubnetMiddleware(subnet *net.IPNet) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if subnet == nil {
+			return next
+		}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			ip := net.ParseIP(r.Header.Get(RealIP))
+			if ip == nil || !subnet.Contains(ip) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// TLSAgentMiddleware создаёт middleware, задающий имя агента из проверенного
+// сертификата клиента. Имя из сертификата заменяет значение заголовка X-Agent-Name.
+func TLSAgentMiddleware() func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if name := tlsutil.PeerName(r.TLS); name != "" {
+				r.Header.Set(AgentName, name)
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
 // defaultAcceptedEncodingTypes поддерживаемы типы для компрессии
 var defaultAcceptedEncodingTypes = map[string]struct{}{
 	TextHTML:        {},
@@ -204,6 +249,67 @@ func CompressMiddleware(encodingTypes map[string]struct{}) func(handler http.Han
 	}
 }
 
+// maxEncryptedBodySize максимальный размер зашифрованного тела запроса.
+const maxEncryptedBodySize = 32 << 20
+
+// DecryptMiddleware создаёт middleware для расшифровки тела запроса закрытым ключом сервера.
+// Запросы обновления метрик /update и /updates без заголовка X-Encryption отклоняются,
+// остальные запросы без заголовка пропускаются без изменений.
+func DecryptMiddleware(key *rsa.PrivateKey) func(handler http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(Encryption)
+			if scheme == "" {
+				if isUpdateRequest(r) {
+					http.Error(w, "error: request body must be encrypted", http.StatusBadRequest)
+					return
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if scheme != hybrid.Scheme {
+				http.Error(w, "error: unsupported encryption scheme", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEncryptedBodySize))
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					http.Error(w, "error: request body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, fmt.Sprintf("Error reading body: %v", err), http.StatusBadRequest)
+				return
+			}
+			_ = r.Body.Close()
+
+			plaintext, err := hybrid.Decrypt(key, body)
+			if err != nil {
+				logger.Errorf(r.Context(), "error decrypt body: %v", err)
+				http.Error(w, "error decrypt body", http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Del(Encryption)
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// isUpdateRequest проверяет, является ли запрос обновлением метрик.
+func isUpdateRequest(r *http.Request) bool {
+	return r.URL.Path == "/update" || r.URL.Path == "/updates" ||
+		strings.HasPrefix(r.URL.Path, "/update/") || strings.HasPrefix(r.URL.Path, "/updates/")
+}
+
 // DecompressMiddleware создаёт middleware для декомпрессии.
 func DecompressMiddleware() func(handler http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -0,0 +1,239 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/pkg/hybrid"
+)
+
+func TestTrustedSubnetMiddleware(t *testing.T) {
+	t.Parallel()
+
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		subnet *net.IPNet
+		realIP string
+		want   int
+	}{
+		{
+			name:   "inside subnet",
+			subnet: subnet,
+			realIP: "192.168.1.10",
+			want:   http.StatusOK,
+		},
+		{
+			name:   "outside subnet",
+			subnet: subnet,
+			realIP: "10.0.0.1",
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "without header",
+			subnet: subnet,
+			want:   http.StatusForbidden,
+		},
+		{
+			name:   "invalid header",
+			subnet: subnet,
+			realIP: "192.168.1.10:8080",
+			want:   http.StatusForbidden,
+		},
+		{
+			name: "subnet not set",
+			want: http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := TrustedSubnetMiddleware(tt.subnet)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/updates", nil)
+			if tt.realIP != "" {
+				r.Header.Set(RealIP, tt.realIP)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecryptMiddleware(t *testing.T) {
+	t.Parallel()
+
+	key, err := rsa.GenerateKey(rand.Reader, hybrid.MinKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := []byte(`[{"id":"Alloc","type":"gauge","value":1}]`)
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, _ = zw.Write(payload)
+	_ = zw.Close()
+
+	encrypted, err := hybrid.Encrypt(&key.PublicKey, gzipped.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       []byte
+		encryption string
+		want       int
+	}{
+		{
+			name:       "encrypted",
+			path:       "/updates",
+			body:       encrypted,
+			encryption: hybrid.Scheme,
+			want:       http.StatusOK,
+		},
+		{
+			name: "not encrypted update",
+			path: "/updates",
+			body: gzipped.Bytes(),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "not encrypted single update",
+			path: "/update/gauge/Alloc/1",
+			body: gzipped.Bytes(),
+			want: http.StatusBadRequest,
+		},
+		{
+			name: "not encrypted value request",
+			path: "/value",
+			body: gzipped.Bytes(),
+			want: http.StatusOK,
+		},
+		{
+			name:       "unknown scheme",
+			path:       "/updates",
+			body:       encrypted,
+			encryption: "rot13",
+			want:       http.StatusBadRequest,
+		},
+		{
+			name:       "corrupted",
+			path:       "/updates",
+			body:       gzipped.Bytes(),
+			encryption: hybrid.Scheme,
+			want:       http.StatusBadRequest,
+		},
+		{
+			name:       "too large",
+			path:       "/updates",
+			body:       make([]byte, maxEncryptedBodySize+1),
+			encryption: hybrid.Scheme,
+			want:       http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []byte
+			// расшифровка выполняется до распаковки
+			handler := DecryptMiddleware(key)(DecompressMiddleware()(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					got, _ = io.ReadAll(r.Body)
+					w.WriteHeader(http.StatusOK)
+				})))
+
+			r := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+			r.Header.Set(ContentEncoding, Gzip)
+			if tt.encryption != "" {
+				r.Header.Set(Encryption, tt.encryption)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && !bytes.Equal(got, payload) {
+				t.Errorf("body = %q, want %q", got, payload)
+			}
+		})
+	}
+}
+
+func TestTLSAgentMiddleware(t *testing.T) {
+	t.Parallel()
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+		{Subject: pkix.Name{CommonName: "agent-1"}},
+	}}}
+
+	tests := []struct {
+		name   string
+		state  *tls.ConnectionState
+		header string
+		want   string
+	}{
+		{
+			name:  "verified certificate",
+			state: verified,
+			want:  "agent-1",
+		},
+		{
+			name:   "certificate overrides header",
+			state:  verified,
+			header: "spoofed",
+			want:   "agent-1",
+		},
+		{
+			name:   "without tls",
+			header: "agent-2",
+			want:   "agent-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got string
+			handler := TLSAgentMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(AgentName)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/updates", nil)
+			r.TLS = tt.state
+			if tt.header != "" {
+				r.Header.Set(AgentName, tt.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if got != tt.want {
+				t.Errorf("agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
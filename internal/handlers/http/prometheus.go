@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// GetPrometheusMetrics обработчик для получения всех метрик в текстовом формате Prometheus.
+//
+//	@Summary		get metrics in prometheus format
+//	@Description	get all metrics in prometheus text exposition format
+//	@Tags			metric
+//	@Produce		plain
+//	@Param			match	query		string	false	"label matchers, e.g. host=a,env!=dev"
+//	@Success		200		{string}	string
+//	@Failure		400		{object}	string
+//	@Failure		423		{object}	string
+//	@Failure		500		{object}	string
+//	@Router			/metrics  [get]
+func (h *Handlers) GetPrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	matchers, err := matchersFromQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	values, err := h.metricUseCases.GetAllMetrics(r.Context(), matchers...)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		switch {
+		case errors.Is(err, domain.ErrResourceIsLocked):
+			w.WriteHeader(http.StatusLocked)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// метрики сортируются по приведенному имени, при совпадении приведенных имен
+	// первой отправляется метрика с меньшим исходным именем
+	sanitized := make(map[string]string, len(values))
+	for _, v := range values {
+		sanitized[v.Name] = sanitizeMetricName(v.Name)
+	}
+	sorted := append([]domain.MetricValue(nil), values...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sanitized[sorted[i].Name] != sanitized[sorted[j].Name] {
+			return sanitized[sorted[i].Name] < sanitized[sorted[j].Name]
+		}
+		if sorted[i].Labels.String() != sorted[j].Labels.String() {
+			return sorted[i].Labels.String() < sorted[j].Labels.String()
+		}
+
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	buf := bytes.NewBuffer(nil)
+	types := make(map[string]domain.MetricType, len(sorted))
+	series := make(map[string]string, len(sorted))
+	for _, v := range sorted {
+		name := sanitized[v.Name]
+		t, exist := types[name]
+		switch {
+		case !exist:
+			types[name] = v.Type
+			fmt.Fprintf(buf, "# TYPE %s %s\n", name, v.Type)
+		case t != v.Type:
+			// в формате Prometheus у метрики может быть только один тип
+			logger.Errorf(r.Context(), "skip metric %s: type %s conflicts with %s", v.Name, v.Type, t)
+			continue
+		}
+
+		// разные имена могут совпасть после приведения, ряд отправляется один раз
+		key := domain.SeriesKey(name, v.Labels)
+		if first, exist := series[key]; exist {
+			logger.Errorf(r.Context(), "skip metric %s: series %s already exported from %s", v.Name, key, first)
+			continue
+		}
+		series[key] = v.Name
+
+		switch v.Type {
+		case domain.GaugeMetricType:
+			fmt.Fprintf(buf, "%s%s %s\n", name, formatPrometheusLabels(v.Labels),
+				formatPrometheusFloat(v.GaugeValue))
+		case domain.CounterMetricType:
+			fmt.Fprintf(buf, "%s%s %d\n", name, formatPrometheusLabels(v.Labels), v.CounterValue)
+		}
+	}
+
+	w.Header().Set(ContentType, TextPlainPrometheus)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// sanitizeMetricName приводит имя метрики к виду [a-zA-Z_:][a-zA-Z0-9_:]*.
+func sanitizeMetricName(name string) string {
+	var b strings.Builder
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+			b.WriteRune(c)
+		case c >= '0' && c <= '9':
+			// имя не может начинаться с цифры
+			if i == 0 {
+				b.WriteRune('_')
+			}
+			b.WriteRune(c)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	if b.Len() == 0 {
+		return "_"
+	}
+
+	return b.String()
+}
+
+// prometheusLabelValueReplacer экранирует значения меток.
+var prometheusLabelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatPrometheusLabels возвращает метки в виде {a="1",b="2"}, отсортированными по имени.
+func formatPrometheusLabels(labels domain.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, prometheusLabelValueReplacer.Replace(labels[name]))
+	}
+	b.WriteByte('}')
+
+	return b.String()
+}
+
+func formatPrometheusFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
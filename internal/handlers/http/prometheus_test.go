@@ -0,0 +1,123 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestHandlers_GetPrometheusMetrics(t *testing.T) {
+	t.Parallel()
+	type expected struct {
+		status int
+		body   string
+	}
+	tests := []struct {
+		name     string
+		useCases useCases
+		request  string
+		expected expected
+	}{
+		{
+			name: "success",
+			useCases: &metricUseCaseMock{
+				values: []domain.MetricValue{
+					{Type: domain.GaugeMetricType, Name: "HeapAlloc", GaugeValue: 1.5},
+					{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 10},
+					{Type: domain.GaugeMetricType, Name: "HeapAlloc", GaugeValue: 2,
+						Labels: domain.Labels{"host": "b", "path": "C:\\\"x\""}},
+					{Type: domain.GaugeMetricType, Name: "1st metric-name.x", GaugeValue: math.Inf(1)},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				body: "# TYPE HeapAlloc gauge\nHeapAlloc 1.5\n" +
+					"HeapAlloc{host=\"b\",path=\"C:\\\\\\\"x\\\"\"} 2\n" +
+					"# TYPE PollCount counter\nPollCount 10\n" +
+					"# TYPE _1st_metric_name_x gauge\n_1st_metric_name_x +Inf\n",
+			},
+		},
+		{
+			name: "conflicting types",
+			useCases: &metricUseCaseMock{
+				values: []domain.MetricValue{
+					{Type: domain.GaugeMetricType, Name: "some", GaugeValue: 1},
+					{Type: domain.CounterMetricType, Name: "some", CounterValue: 1},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				body:   "# TYPE some gauge\nsome 1\n",
+			},
+		},
+		{
+			name: "sanitized name collision",
+			useCases: &metricUseCaseMock{
+				values: []domain.MetricValue{
+					{Type: domain.CounterMetricType, Name: "disk_used", CounterValue: 5},
+					{Type: domain.GaugeMetricType, Name: "disk.used", GaugeValue: 1},
+					{Type: domain.GaugeMetricType, Name: "disk-used", GaugeValue: 2},
+					{Type: domain.GaugeMetricType, Name: "disk.used", GaugeValue: 4,
+						Labels: domain.Labels{"host": "a"}},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				body: "# TYPE disk_used gauge\ndisk_used 2\n" +
+					"disk_used{host=\"a\"} 4\n",
+			},
+		},
+		{
+			name:     "bad matcher",
+			useCases: &metricUseCaseMock{},
+			request:  "/metrics?match=host=~(",
+			expected: expected{
+				status: http.StatusBadRequest,
+				body:   "invalid label regexp \"(\": error parsing regexp: missing closing ): `^(?:()$`\n",
+			},
+		},
+		{
+			name: "empty storage",
+			useCases: &metricUseCaseMock{
+				err: domain.ErrNotFound,
+			},
+			expected: expected{
+				status: http.StatusOK,
+				body:   "",
+			},
+		},
+		{
+			name: "error",
+			useCases: &metricUseCaseMock{
+				err: errors.New("some error"),
+			},
+			expected: expected{
+				status: http.StatusInternalServerError,
+				body:   "some error\n",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := NewHandlers(tt.useCases)
+			w := httptest.NewRecorder()
+			request := tt.request
+			if request == "" {
+				request = "/metrics"
+			}
+			h.GetPrometheusMetrics(w, httptest.NewRequest(http.MethodGet, request, nil))
+
+			if w.Code != tt.expected.status {
+				t.Errorf("got %d, want %d", w.Code, tt.expected.status)
+			}
+			if w.Body.String() != tt.expected.body {
+				t.Errorf("got body %q, want %q", w.Body.String(), tt.expected.body)
+			}
+		})
+	}
+}
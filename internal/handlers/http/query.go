@@ -0,0 +1,183 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Параметры запроса значений ряда метрики за период.
+const (
+	QueryTypeKey        = "type"
+	QueryMetricKey      = "metric"
+	QueryLabelsKey      = "labels"
+	QueryStartKey       = "start"
+	QueryEndKey         = "end"
+	QueryStepKey        = "step"
+	QueryAggregationKey = "agg"
+)
+
+const (
+	defaultQueryRange = time.Hour
+	defaultQueryStep  = time.Minute
+)
+
+type point struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+type queryRangeResponse struct {
+	MType       string            `json:"type"`
+	ID          string            `json:"id"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Aggregation string            `json:"aggregation"`
+	Step        float64           `json:"step"`
+	Points      []point           `json:"points"`
+}
+
+// QueryRange обработчик для получения значений ряда метрики за период с агрегацией по шагу.
+//
+//	@Summary		query range
+//	@Description	get metric series for period aggregated by step: avg, min, max, last for gauges, increase, rate for counters
+//	@Tags			metric
+//	@Produce		json
+//	@Param			type	query		string	true	"metric type"
+//	@Param			metric	query		string	true	"metric name"
+//	@Param			labels	query		string	false	"series labels, e.g. env=prod,dc=eu"
+//	@Param			start	query		string	false	"RFC3339 or unix time, end minus 1h by default"
+//	@Param			end		query		string	false	"RFC3339 or unix time, now by default"
+//	@Param			step	query		string	false	"duration or seconds, 1m by default"
+//	@Param			agg		query		string	false	"aggregation, avg for gauges and increase for counters by default"
+//	@Success		200		{object}	http.queryRangeResponse
+//	@Failure		400		{object}	string
+//	@Failure		423		{object}	string
+//	@Failure		500		{object}	string
+//	@Failure		501		{object}	string
+//	@Router			/api/v1/query_range [get]
+func (h *Handlers) QueryRange(w http.ResponseWriter, r *http.Request) {
+	q, err := rangeQueryFromRequest(r, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	points, err := h.metricUseCases.QueryRange(r.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrResourceIsLocked):
+			w.WriteHeader(http.StatusLocked)
+			return
+		case errors.Is(err, domain.ErrNotSupported):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := queryRangeResponse{
+		MType:       q.Type.String(),
+		ID:          q.Name,
+		Labels:      q.Labels,
+		Aggregation: q.Aggregation.String(),
+		Step:        q.Step.Seconds(),
+		Points:      make([]point, 0, len(points)),
+	}
+	for _, p := range points {
+		res.Points = append(res.Points, point{
+			Timestamp: p.Timestamp,
+			Value:     p.Value,
+		})
+	}
+
+	writeJSON(w, res)
+}
+
+// rangeQueryFromRequest разбирает и проверяет параметры запроса значений за период.
+func rangeQueryFromRequest(r *http.Request, now time.Time) (domain.RangeQuery, error) {
+	query := r.URL.Query()
+
+	mType, err := domain.NewMetricTypeFromString(query.Get(QueryTypeKey))
+	if err != nil {
+		return domain.RangeQuery{}, err
+	}
+
+	labels, err := domain.ParseLabels(query.Get(QueryLabelsKey))
+	if err != nil {
+		return domain.RangeQuery{}, err
+	}
+
+	end := now
+	if v := query.Get(QueryEndKey); v != "" {
+		end, err = parseTime(v)
+		if err != nil {
+			return domain.RangeQuery{}, fmt.Errorf("invalid end: %w", err)
+		}
+	}
+
+	start := end.Add(-defaultQueryRange)
+	if v := query.Get(QueryStartKey); v != "" {
+		start, err = parseTime(v)
+		if err != nil {
+			return domain.RangeQuery{}, fmt.Errorf("invalid start: %w", err)
+		}
+	}
+
+	step := defaultQueryStep
+	if v := query.Get(QueryStepKey); v != "" {
+		step, err = parseStep(v)
+		if err != nil {
+			return domain.RangeQuery{}, fmt.Errorf("invalid step: %w", err)
+		}
+	}
+
+	aggregation := domain.DefaultAggregation(mType)
+	if v := query.Get(QueryAggregationKey); v != "" {
+		aggregation, err = domain.NewAggregationFromString(v)
+		if err != nil {
+			return domain.RangeQuery{}, err
+		}
+	}
+
+	q := domain.RangeQuery{
+		Type:        mType,
+		Name:        query.Get(QueryMetricKey),
+		Labels:      labels,
+		Start:       start.UTC(),
+		End:         end.UTC(),
+		Step:        step,
+		Aggregation: aggregation,
+	}
+
+	if err = q.Validate(); err != nil {
+		return domain.RangeQuery{}, err
+	}
+
+	return q, nil
+}
+
+// parseTime разбирает время в формате RFC3339 или unix time в секундах.
+func parseTime(v string) (time.Time, error) {
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+	}
+
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// parseStep разбирает шаг в формате длительности или в секундах.
+func parseStep(v string) (time.Duration, error) {
+	if sec, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(sec * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(v)
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestHandlers_QueryRange(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type expected struct {
+		status int
+		points int
+	}
+	tests := []struct {
+		name     string
+		query    string
+		useCases useCases
+		expected expected
+	}{
+		{
+			name:  "success",
+			query: "?type=gauge&metric=Alloc&start=2024-01-01T00:00:00Z&end=2024-01-01T01:00:00Z&step=1m&agg=max",
+			useCases: &metricUseCaseMock{
+				points: []domain.Point{
+					{Timestamp: start, Value: 1},
+					{Timestamp: start.Add(time.Minute), Value: 2},
+				},
+			},
+			expected: expected{
+				status: http.StatusOK,
+				points: 2,
+			},
+		},
+		{
+			name:     "success defaults",
+			query:    "?type=counter&metric=PollCount&labels=host=a",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusOK,
+				points: 0,
+			},
+		},
+		{
+			name:     "unknown type",
+			query:    "?type=some&metric=Alloc",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name:     "unsupported aggregation",
+			query:    "?type=gauge&metric=Alloc&agg=rate",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name:     "too many points",
+			query:    "?type=gauge&metric=Alloc&start=0&end=86400&step=1",
+			useCases: &metricUseCaseMock{},
+			expected: expected{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name:  "not supported",
+			query: "?type=gauge&metric=Alloc",
+			useCases: &metricUseCaseMock{
+				err: fmt.Errorf("error GetSamples: %w", domain.ErrNotSupported),
+			},
+			expected: expected{
+				status: http.StatusNotImplemented,
+			},
+		},
+		{
+			name:  "error",
+			query: "?type=gauge&metric=Alloc",
+			useCases: &metricUseCaseMock{
+				err: errors.New("some error"),
+			},
+			expected: expected{
+				status: http.StatusInternalServerError,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			h := NewHandlers(tt.useCases)
+			w := httptest.NewRecorder()
+			h.QueryRange(w, httptest.NewRequest(http.MethodGet, "/api/v1/query_range"+tt.query, nil))
+
+			if w.Code != tt.expected.status {
+				t.Errorf("got %d, want %d", w.Code, tt.expected.status)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var res queryRangeResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("error unmarshal response: %v", err)
+			}
+			if len(res.Points) != tt.expected.points {
+				t.Errorf("got %d points, want %d", len(res.Points), tt.expected.points)
+			}
+		})
+	}
+}
+
+func Test_rangeQueryFromRequest(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?type=counter&metric=PollCount&start=1704067200.5&step=30", nil)
+
+	got, err := rangeQueryFromRequest(r, now)
+	if err != nil {
+		t.Fatalf("rangeQueryFromRequest() error = %v", err)
+	}
+
+	want := domain.RangeQuery{
+		Type:        domain.CounterMetricType,
+		Name:        "PollCount",
+		Start:       time.Date(2024, 1, 1, 0, 0, 0, int(500*time.Millisecond), time.UTC),
+		End:         now,
+		Step:        30 * time.Second,
+		Aggregation: domain.AggregationIncrease,
+	}
+	if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.Step != want.Step ||
+		got.Aggregation != want.Aggregation || got.Name != want.Name || got.Type != want.Type {
+		t.Errorf("rangeQueryFromRequest() got = %+v, want %+v", got, want)
+	}
+}
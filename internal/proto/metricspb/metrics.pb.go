@@ -0,0 +1,577 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.29.3
+// source: metricspb/metrics.proto
+
+package metricspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MetricType тип метрики.
+type MetricType int32
+
+const (
+	MetricType_METRIC_TYPE_UNSPECIFIED MetricType = 0
+	// METRIC_TYPE_GAUGE тип "градусник".
+	MetricType_METRIC_TYPE_GAUGE MetricType = 1
+	// METRIC_TYPE_COUNTER тип "счетчик".
+	MetricType_METRIC_TYPE_COUNTER MetricType = 2
+)
+
+// Enum value maps for MetricType.
+var (
+	MetricType_name = map[int32]string{
+		0: "METRIC_TYPE_UNSPECIFIED",
+		1: "METRIC_TYPE_GAUGE",
+		2: "METRIC_TYPE_COUNTER",
+	}
+	MetricType_value = map[string]int32{
+		"METRIC_TYPE_UNSPECIFIED": 0,
+		"METRIC_TYPE_GAUGE":       1,
+		"METRIC_TYPE_COUNTER":     2,
+	}
+)
+
+func (x MetricType) Enum() *MetricType {
+	p := new(MetricType)
+	*p = x
+	return p
+}
+
+func (x MetricType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MetricType) Descriptor() protoreflect.EnumDescriptor {
+	return file_metricspb_metrics_proto_enumTypes[0].Descriptor()
+}
+
+func (MetricType) Type() protoreflect.EnumType {
+	return &file_metricspb_metrics_proto_enumTypes[0]
+}
+
+func (x MetricType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MetricType.Descriptor instead.
+func (MetricType) EnumDescriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{0}
+}
+
+// Metric значение метрики.
+type Metric struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Name   string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type   MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=onlymetrics.metrics.v1.MetricType" json:"type,omitempty"`
+	Labels map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Types that are valid to be assigned to Value:
+	//
+	//	*Metric_Gauge
+	//	*Metric_Delta
+	Value         isMetric_Value `protobuf_oneof:"value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metric) Reset() {
+	*x = Metric{}
+	mi := &file_metricspb_metrics_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metric) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metric) ProtoMessage() {}
+
+func (x *Metric) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metric.ProtoReflect.Descriptor instead.
+func (*Metric) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Metric) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Metric) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *Metric) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Metric) GetValue() isMetric_Value {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *Metric) GetGauge() float64 {
+	if x != nil {
+		if x, ok := x.Value.(*Metric_Gauge); ok {
+			return x.Gauge
+		}
+	}
+	return 0
+}
+
+func (x *Metric) GetDelta() int64 {
+	if x != nil {
+		if x, ok := x.Value.(*Metric_Delta); ok {
+			return x.Delta
+		}
+	}
+	return 0
+}
+
+type isMetric_Value interface {
+	isMetric_Value()
+}
+
+type Metric_Gauge struct {
+	// gauge значение градусника.
+	Gauge float64 `protobuf:"fixed64,4,opt,name=gauge,proto3,oneof"`
+}
+
+type Metric_Delta struct {
+	// delta приращение счетчика, в ответах - текущее значение счетчика.
+	Delta int64 `protobuf:"varint,5,opt,name=delta,proto3,oneof"`
+}
+
+func (*Metric_Gauge) isMetric_Value() {}
+
+func (*Metric_Delta) isMetric_Value() {}
+
+type UpdateMetricsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricsRequest) Reset() {
+	*x = UpdateMetricsRequest{}
+	mi := &file_metricspb_metrics_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricsRequest) ProtoMessage() {}
+
+func (x *UpdateMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricsRequest.ProtoReflect.Descriptor instead.
+func (*UpdateMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *UpdateMetricsRequest) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+type UpdateMetricsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateMetricsResponse) Reset() {
+	*x = UpdateMetricsResponse{}
+	mi := &file_metricspb_metrics_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateMetricsResponse) ProtoMessage() {}
+
+func (x *UpdateMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateMetricsResponse.ProtoReflect.Descriptor instead.
+func (*UpdateMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{2}
+}
+
+type GetMetricRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          MetricType             `protobuf:"varint,2,opt,name=type,proto3,enum=onlymetrics.metrics.v1.MetricType" json:"type,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricRequest) Reset() {
+	*x = GetMetricRequest{}
+	mi := &file_metricspb_metrics_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricRequest) ProtoMessage() {}
+
+func (x *GetMetricRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricRequest.ProtoReflect.Descriptor instead.
+func (*GetMetricRequest) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetMetricRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *GetMetricRequest) GetType() MetricType {
+	if x != nil {
+		return x.Type
+	}
+	return MetricType_METRIC_TYPE_UNSPECIFIED
+}
+
+func (x *GetMetricRequest) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type GetMetricResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metric        *Metric                `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetMetricResponse) Reset() {
+	*x = GetMetricResponse{}
+	mi := &file_metricspb_metrics_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetMetricResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMetricResponse) ProtoMessage() {}
+
+func (x *GetMetricResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMetricResponse.ProtoReflect.Descriptor instead.
+func (*GetMetricResponse) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetMetricResponse) GetMetric() *Metric {
+	if x != nil {
+		return x.Metric
+	}
+	return nil
+}
+
+type ListMetricsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// match условия отбора по меткам в том же формате, что и параметр match http API,
+	// например host=a,env!=dev.
+	Match         []string `protobuf:"bytes,1,rep,name=match,proto3" json:"match,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMetricsRequest) Reset() {
+	*x = ListMetricsRequest{}
+	mi := &file_metricspb_metrics_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMetricsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMetricsRequest) ProtoMessage() {}
+
+func (x *ListMetricsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMetricsRequest.ProtoReflect.Descriptor instead.
+func (*ListMetricsRequest) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListMetricsRequest) GetMatch() []string {
+	if x != nil {
+		return x.Match
+	}
+	return nil
+}
+
+type ListMetricsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metrics       []*Metric              `protobuf:"bytes,1,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMetricsResponse) Reset() {
+	*x = ListMetricsResponse{}
+	mi := &file_metricspb_metrics_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMetricsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMetricsResponse) ProtoMessage() {}
+
+func (x *ListMetricsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_metricspb_metrics_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMetricsResponse.ProtoReflect.Descriptor instead.
+func (*ListMetricsResponse) Descriptor() ([]byte, []int) {
+	return file_metricspb_metrics_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListMetricsResponse) GetMetrics() []*Metric {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+var File_metricspb_metrics_proto protoreflect.FileDescriptor
+
+const file_metricspb_metrics_proto_rawDesc = "" +
+	"\n" +
+	"\x17metricspb/metrics.proto\x12\x16onlymetrics.metrics.v1\"\x8c\x02\n" +
+	"\x06Metric\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x126\n" +
+	"\x04type\x18\x02 \x01(\x0e2\".onlymetrics.metrics.v1.MetricTypeR\x04type\x12B\n" +
+	"\x06labels\x18\x03 \x03(\v2*.onlymetrics.metrics.v1.Metric.LabelsEntryR\x06labels\x12\x16\n" +
+	"\x05gauge\x18\x04 \x01(\x01H\x00R\x05gauge\x12\x16\n" +
+	"\x05delta\x18\x05 \x01(\x03H\x00R\x05delta\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\a\n" +
+	"\x05value\"P\n" +
+	"\x14UpdateMetricsRequest\x128\n" +
+	"\ametrics\x18\x01 \x03(\v2\x1e.onlymetrics.metrics.v1.MetricR\ametrics\"\x17\n" +
+	"\x15UpdateMetricsResponse\"\xe7\x01\n" +
+	"\x10GetMetricRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x126\n" +
+	"\x04type\x18\x02 \x01(\x0e2\".onlymetrics.metrics.v1.MetricTypeR\x04type\x12L\n" +
+	"\x06labels\x18\x03 \x03(\v24.onlymetrics.metrics.v1.GetMetricRequest.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"K\n" +
+	"\x11GetMetricResponse\x126\n" +
+	"\x06metric\x18\x01 \x01(\v2\x1e.onlymetrics.metrics.v1.MetricR\x06metric\"*\n" +
+	"\x12ListMetricsRequest\x12\x14\n" +
+	"\x05match\x18\x01 \x03(\tR\x05match\"O\n" +
+	"\x13ListMetricsResponse\x128\n" +
+	"\ametrics\x18\x01 \x03(\v2\x1e.onlymetrics.metrics.v1.MetricR\ametrics*Y\n" +
+	"\n" +
+	"MetricType\x12\x1b\n" +
+	"\x17METRIC_TYPE_UNSPECIFIED\x10\x00\x12\x15\n" +
+	"\x11METRIC_TYPE_GAUGE\x10\x01\x12\x17\n" +
+	"\x13METRIC_TYPE_COUNTER\x10\x022\xb7\x03\n" +
+	"\aMetrics\x12l\n" +
+	"\rUpdateMetrics\x12,.onlymetrics.metrics.v1.UpdateMetricsRequest\x1a-.onlymetrics.metrics.v1.UpdateMetricsResponse\x12t\n" +
+	"\x13UpdateMetricsStream\x12,.onlymetrics.metrics.v1.UpdateMetricsRequest\x1a-.onlymetrics.metrics.v1.UpdateMetricsResponse(\x01\x12`\n" +
+	"\tGetMetric\x12(.onlymetrics.metrics.v1.GetMetricRequest\x1a).onlymetrics.metrics.v1.GetMetricResponse\x12f\n" +
+	"\vListMetrics\x12*.onlymetrics.metrics.v1.ListMetricsRequest\x1a+.onlymetrics.metrics.v1.ListMetricsResponseB9Z7github.com/kdv2001/onlyMetrics/internal/proto/metricspbb\x06proto3"
+
+var (
+	file_metricspb_metrics_proto_rawDescOnce sync.Once
+	file_metricspb_metrics_proto_rawDescData []byte
+)
+
+func file_metricspb_metrics_proto_rawDescGZIP() []byte {
+	file_metricspb_metrics_proto_rawDescOnce.Do(func() {
+		file_metricspb_metrics_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_metricspb_metrics_proto_rawDesc), len(file_metricspb_metrics_proto_rawDesc)))
+	})
+	return file_metricspb_metrics_proto_rawDescData
+}
+
+var file_metricspb_metrics_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_metricspb_metrics_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_metricspb_metrics_proto_goTypes = []any{
+	(MetricType)(0),               // 0: onlymetrics.metrics.v1.MetricType
+	(*Metric)(nil),                // 1: onlymetrics.metrics.v1.Metric
+	(*UpdateMetricsRequest)(nil),  // 2: onlymetrics.metrics.v1.UpdateMetricsRequest
+	(*UpdateMetricsResponse)(nil), // 3: onlymetrics.metrics.v1.UpdateMetricsResponse
+	(*GetMetricRequest)(nil),      // 4: onlymetrics.metrics.v1.GetMetricRequest
+	(*GetMetricResponse)(nil),     // 5: onlymetrics.metrics.v1.GetMetricResponse
+	(*ListMetricsRequest)(nil),    // 6: onlymetrics.metrics.v1.ListMetricsRequest
+	(*ListMetricsResponse)(nil),   // 7: onlymetrics.metrics.v1.ListMetricsResponse
+	nil,                           // 8: onlymetrics.metrics.v1.Metric.LabelsEntry
+	nil,                           // 9: onlymetrics.metrics.v1.GetMetricRequest.LabelsEntry
+}
+var file_metricspb_metrics_proto_depIdxs = []int32{
+	0,  // 0: onlymetrics.metrics.v1.Metric.type:type_name -> onlymetrics.metrics.v1.MetricType
+	8,  // 1: onlymetrics.metrics.v1.Metric.labels:type_name -> onlymetrics.metrics.v1.Metric.LabelsEntry
+	1,  // 2: onlymetrics.metrics.v1.UpdateMetricsRequest.metrics:type_name -> onlymetrics.metrics.v1.Metric
+	0,  // 3: onlymetrics.metrics.v1.GetMetricRequest.type:type_name -> onlymetrics.metrics.v1.MetricType
+	9,  // 4: onlymetrics.metrics.v1.GetMetricRequest.labels:type_name -> onlymetrics.metrics.v1.GetMetricRequest.LabelsEntry
+	1,  // 5: onlymetrics.metrics.v1.GetMetricResponse.metric:type_name -> onlymetrics.metrics.v1.Metric
+	1,  // 6: onlymetrics.metrics.v1.ListMetricsResponse.metrics:type_name -> onlymetrics.metrics.v1.Metric
+	2,  // 7: onlymetrics.metrics.v1.Metrics.UpdateMetrics:input_type -> onlymetrics.metrics.v1.UpdateMetricsRequest
+	2,  // 8: onlymetrics.metrics.v1.Metrics.UpdateMetricsStream:input_type -> onlymetrics.metrics.v1.UpdateMetricsRequest
+	4,  // 9: onlymetrics.metrics.v1.Metrics.GetMetric:input_type -> onlymetrics.metrics.v1.GetMetricRequest
+	6,  // 10: onlymetrics.metrics.v1.Metrics.ListMetrics:input_type -> onlymetrics.metrics.v1.ListMetricsRequest
+	3,  // 11: onlymetrics.metrics.v1.Metrics.UpdateMetrics:output_type -> onlymetrics.metrics.v1.UpdateMetricsResponse
+	3,  // 12: onlymetrics.metrics.v1.Metrics.UpdateMetricsStream:output_type -> onlymetrics.metrics.v1.UpdateMetricsResponse
+	5,  // 13: onlymetrics.metrics.v1.Metrics.GetMetric:output_type -> onlymetrics.metrics.v1.GetMetricResponse
+	7,  // 14: onlymetrics.metrics.v1.Metrics.ListMetrics:output_type -> onlymetrics.metrics.v1.ListMetricsResponse
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_metricspb_metrics_proto_init() }
+func file_metricspb_metrics_proto_init() {
+	if File_metricspb_metrics_proto != nil {
+		return
+	}
+	file_metricspb_metrics_proto_msgTypes[0].OneofWrappers = []any{
+		(*Metric_Gauge)(nil),
+		(*Metric_Delta)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_metricspb_metrics_proto_rawDesc), len(file_metricspb_metrics_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_metricspb_metrics_proto_goTypes,
+		DependencyIndexes: file_metricspb_metrics_proto_depIdxs,
+		EnumInfos:         file_metricspb_metrics_proto_enumTypes,
+		MessageInfos:      file_metricspb_metrics_proto_msgTypes,
+	}.Build()
+	File_metricspb_metrics_proto = out.File
+	file_metricspb_metrics_proto_goTypes = nil
+	file_metricspb_metrics_proto_depIdxs = nil
+}
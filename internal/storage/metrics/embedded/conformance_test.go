@@ -0,0 +1,16 @@
+package embedded
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/storagetest"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+)
+
+func TestStorage_conformance(t *testing.T) {
+	t.Parallel()
+	storagetest.Run(t, func(t *testing.T) metrics.MetricStorage {
+		return newTestStorage(t, t.TempDir(), WithHistoryOpt(time.Hour, time.Second))
+	})
+}
@@ -0,0 +1,122 @@
+package embedded
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// seriesValueSize размер значения ряда в начале записи.
+const seriesValueSize = 8
+
+// sampleSize размер записи агрегата: количество и четыре значения.
+const sampleSize = 8 * 5
+
+var errInvalidRecord = errors.New("invalid record")
+
+// seriesMeta имя и метки ряда, хранящиеся вместе со значением.
+type seriesMeta struct {
+	Name   string        `json:"name"`
+	Labels domain.Labels `json:"labels,omitempty"`
+}
+
+// encodeSeries кодирует запись ряда: значение и метаданные ряда.
+func encodeSeries(value uint64, name string, labels domain.Labels) ([]byte, error) {
+	meta, err := json.Marshal(seriesMeta{
+		Name:   name,
+		Labels: labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, seriesValueSize, seriesValueSize+len(meta))
+	binary.BigEndian.PutUint64(res, value)
+
+	return append(res, meta...), nil
+}
+
+// decodeSeries декодирует запись ряда.
+func decodeSeries(data []byte) (uint64, seriesMeta, error) {
+	if len(data) < seriesValueSize {
+		return 0, seriesMeta{}, errInvalidRecord
+	}
+
+	var meta seriesMeta
+	if err := json.Unmarshal(data[seriesValueSize:], &meta); err != nil {
+		return 0, seriesMeta{}, err
+	}
+
+	return binary.BigEndian.Uint64(data), meta, nil
+}
+
+// seriesValue возвращает значение ряда без разбора метаданных.
+func seriesValue(data []byte) (uint64, error) {
+	if len(data) < seriesValueSize {
+		return 0, errInvalidRecord
+	}
+
+	return binary.BigEndian.Uint64(data), nil
+}
+
+// encodeSample кодирует агрегат без времени, время хранится в ключе.
+func encodeSample(s domain.Sample) []byte {
+	res := make([]byte, sampleSize)
+	binary.BigEndian.PutUint64(res[0:], uint64(s.Count))
+	binary.BigEndian.PutUint64(res[8:], math.Float64bits(s.Sum))
+	binary.BigEndian.PutUint64(res[16:], math.Float64bits(s.Min))
+	binary.BigEndian.PutUint64(res[24:], math.Float64bits(s.Max))
+	binary.BigEndian.PutUint64(res[32:], math.Float64bits(s.Last))
+
+	return res
+}
+
+// decodeSample декодирует агрегат с началом интервала ts.
+func decodeSample(ts time.Time, data []byte) (domain.Sample, error) {
+	if len(data) != sampleSize {
+		return domain.Sample{}, errInvalidRecord
+	}
+
+	return domain.Sample{
+		Timestamp: ts,
+		Count:     int64(binary.BigEndian.Uint64(data[0:])),
+		Sum:       math.Float64frombits(binary.BigEndian.Uint64(data[8:])),
+		Min:       math.Float64frombits(binary.BigEndian.Uint64(data[16:])),
+		Max:       math.Float64frombits(binary.BigEndian.Uint64(data[24:])),
+		Last:      math.Float64frombits(binary.BigEndian.Uint64(data[32:])),
+	}, nil
+}
+
+// encodeInterval кодирует номер интервала так, чтобы ключи сортировались по времени.
+func encodeInterval(t int64) []byte {
+	res := make([]byte, 8)
+	binary.BigEndian.PutUint64(res, uint64(t)^(1<<63))
+
+	return res
+}
+
+// decodeInterval декодирует номер интервала.
+func decodeInterval(data []byte) int64 {
+	return int64(binary.BigEndian.Uint64(data) ^ (1 << 63))
+}
+
+// encodeTime кодирует время последнего обновления агента.
+func encodeTime(t time.Time) []byte {
+	res := make([]byte, 8)
+	binary.BigEndian.PutUint64(res, uint64(t.UnixNano()))
+
+	return res
+}
+
+// decodeTime декодирует время последнего обновления агента.
+func decodeTime(data []byte) (time.Time, error) {
+	if len(data) != 8 {
+		return time.Time{}, errInvalidRecord
+	}
+
+	return time.Unix(0, int64(binary.BigEndian.Uint64(data))).UTC(), nil
+}
@@ -0,0 +1,120 @@
+package embedded
+
+import (
+	"bytes"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// historyKey ключ истории ряда, ряды разных типов хранятся раздельно.
+func historyKey(t domain.MetricType, name string, labels domain.Labels) string {
+	return t.String() + ":" + domain.SeriesKey(name, labels)
+}
+
+// addSample объединяет значение ряда с агрегатом его интервала разрешения.
+func (s *Storage) addSample(tx *bolt.Tx, t domain.MetricType, name string,
+	labels domain.Labels, ts time.Time, value float64) error {
+	if s.retention == 0 {
+		return nil
+	}
+
+	bucket, err := tx.Bucket(samplesBucket).CreateBucketIfNotExists([]byte(historyKey(t, name, labels)))
+	if err != nil {
+		return err
+	}
+
+	interval := s.intervalOf(ts)
+	key := encodeInterval(interval)
+	sample := domain.Sample{Timestamp: s.timeOf(interval)}
+	if data := bucket.Get(key); data != nil {
+		sample, err = decodeSample(sample.Timestamp, data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return bucket.Put(key, encodeSample(sample.Merge(domain.NewSample(ts, value))))
+}
+
+// samples возвращает упорядоченные агрегаты ряда с началом интервала в [start, end).
+func (s *Storage) samples(key string, start, end time.Time) ([]domain.Sample, error) {
+	res := make([]domain.Sample, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(samplesBucket).Bucket([]byte(key))
+		if bucket == nil {
+			return nil
+		}
+
+		c := bucket.Cursor()
+		for k, v := c.Seek(encodeInterval(s.intervalOf(start))); k != nil; k, v = c.Next() {
+			ts := s.timeOf(decodeInterval(k))
+			if !ts.Before(end) {
+				break
+			}
+			if ts.Before(start) {
+				continue
+			}
+
+			sample, err := decodeSample(ts, v)
+			if err != nil {
+				return err
+			}
+			res = append(res, sample)
+		}
+
+		return nil
+	})
+
+	return res, err
+}
+
+// truncateHistory удаляет агрегаты старше срока хранения и опустевшие ряды.
+func (s *Storage) truncateHistory(now time.Time) error {
+	minKey := encodeInterval(s.intervalOf(now.Add(-s.retention)))
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(samplesBucket)
+
+		var empty [][]byte
+		err := root.ForEachBucket(func(name []byte) error {
+			bucket := root.Bucket(name)
+			c := bucket.Cursor()
+			// удаление сдвигает курсор, поэтому каждый раз читаем первый ключ
+			for k, _ := c.First(); k != nil && bytes.Compare(k, minKey) < 0; k, _ = c.First() {
+				if err := c.Delete(); err != nil {
+					return err
+				}
+			}
+
+			if k, _ := c.First(); k == nil {
+				empty = append(empty, bytes.Clone(name))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, name := range empty {
+			if err = root.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// intervalOf возвращает номер интервала разрешения, содержащего ts.
+func (s *Storage) intervalOf(ts time.Time) int64 {
+	return ts.UnixNano() / int64(s.resolution)
+}
+
+// timeOf возвращает время начала интервала разрешения.
+func (s *Storage) timeOf(t int64) time.Time {
+	return time.Unix(0, t*int64(s.resolution)).UTC()
+}
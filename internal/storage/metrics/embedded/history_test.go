@@ -0,0 +1,153 @@
+package embedded
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestStorage_samples(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestStorage(t, t.TempDir(), WithHistoryOpt(time.Hour, 10*time.Second))
+
+	add := func(ts time.Time, value float64) {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			return s.addSample(tx, domain.GaugeMetricType, "Alloc", nil, ts, value)
+		})
+		if err != nil {
+			t.Fatalf("addSample() error = %v", err)
+		}
+	}
+	add(start, 1)
+	add(start.Add(5*time.Second), 3)
+	add(start.Add(25*time.Second), 7)
+	add(start.Add(31*time.Second), 5)
+
+	key := historyKey(domain.GaugeMetricType, "Alloc", nil)
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  []domain.Sample
+	}{
+		{
+			name:  "all",
+			start: start,
+			end:   start.Add(time.Minute),
+			want: []domain.Sample{
+				{Timestamp: start, Count: 2, Sum: 4, Min: 1, Max: 3, Last: 3},
+				{Timestamp: start.Add(20 * time.Second), Count: 1, Sum: 7, Min: 7, Max: 7, Last: 7},
+				{Timestamp: start.Add(30 * time.Second), Count: 1, Sum: 5, Min: 5, Max: 5, Last: 5},
+			},
+		},
+		{
+			name:  "range",
+			start: start.Add(10 * time.Second),
+			end:   start.Add(30 * time.Second),
+			want: []domain.Sample{
+				{Timestamp: start.Add(20 * time.Second), Count: 1, Sum: 7, Min: 7, Max: 7, Last: 7},
+			},
+		},
+		{
+			name:  "empty",
+			start: start.Add(time.Hour),
+			end:   start.Add(2 * time.Hour),
+			want:  []domain.Sample{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.samples(key, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("samples() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("samples() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := s.truncateHistory(start.Add(time.Hour + 25*time.Second)); err != nil {
+		t.Fatalf("truncateHistory() error = %v", err)
+	}
+	got, err := s.samples(key, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("samples() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("samples() after truncate = %v, want 2 samples", got)
+	}
+
+	if err = s.truncateHistory(start.Add(2 * time.Hour)); err != nil {
+		t.Fatalf("truncateHistory() error = %v", err)
+	}
+	err = s.db.View(func(tx *bolt.Tx) error {
+		if tx.Bucket(samplesBucket).Bucket([]byte(key)) != nil {
+			return errors.New("empty series bucket is not deleted")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestStorage_GetSamples(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		opts    []storageOption
+		want    int
+		wantErr error
+	}{
+		{
+			name: "with history",
+			opts: []storageOption{WithHistoryOpt(time.Hour, time.Minute)},
+			want: 1,
+		},
+		{
+			name:    "without history",
+			wantErr: domain.ErrNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := newTestStorage(t, t.TempDir(), tt.opts...)
+			value := domain.MetricValue{
+				Type:         domain.CounterMetricType,
+				Name:         "PollCount",
+				CounterValue: 5,
+			}
+			for range 2 {
+				if err := s.UpdateCounter(t.Context(), "agent", value); err != nil {
+					t.Fatalf("UpdateCounter() error = %v", err)
+				}
+			}
+
+			now := time.Now()
+			got, err := s.GetSamples(t.Context(), domain.RangeQuery{
+				Type:  domain.CounterMetricType,
+				Name:  "PollCount",
+				Start: now.Add(-time.Minute),
+				End:   now.Add(time.Minute),
+				Step:  time.Minute,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetSamples() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Fatalf("GetSamples() = %v, want %d samples", got, tt.want)
+			}
+			if tt.want > 0 && got[0].Sum != 10 {
+				t.Errorf("GetSamples() sum = %v, want 10 (counter increments)", got[0].Sum)
+			}
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Package embedded предоставляет методы для работы со встроенным хранилищем метрик на диске.
+package embedded
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// dbFileName имя файла базы в каталоге хранилища.
+const dbFileName = "metrics.db"
+
+// openTimeout время ожидания блокировки файла базы другим процессом.
+const openTimeout = time.Second
+
+var (
+	gaugeBucket   = []byte("gauge")
+	counterBucket = []byte("counter")
+	agentsBucket  = []byte("agents")
+	// samplesBucket содержит по вложенному бакету агрегатов на каждый ряд.
+	samplesBucket = []byte("samples")
+)
+
+// Storage хранилище метрик во встроенной базе bbolt. Ряды метрик хранятся по ключу
+// из имени и отсортированных меток, для счетчиков хранится накопленное значение.
+type Storage struct {
+	db *bolt.DB
+
+	// retention срок хранения истории, 0 если история не хранится.
+	retention  time.Duration
+	resolution time.Duration
+}
+
+// storageOption опция хранилища.
+type storageOption func(s *Storage)
+
+// WithHistoryOpt включает хранение истории значений в течение retention,
+// значения объединяются по интервалам resolution.
+func WithHistoryOpt(retention, resolution time.Duration) storageOption {
+	return func(s *Storage) {
+		if retention <= 0 {
+			s.retention = 0
+			return
+		}
+
+		if resolution <= 0 {
+			resolution = time.Second
+		}
+
+		s.retention = retention
+		s.resolution = resolution
+	}
+}
+
+// NewStorage открывает или создает хранилище в каталоге dir.
+func NewStorage(ctx context.Context, dir string, opts ...storageOption) (*Storage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage dir: %w", err)
+	}
+
+	db, err := bolt.Open(filepath.Join(dir, dbFileName), 0644, &bolt.Options{
+		Timeout: openTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{gaugeBucket, counterBucket, agentsBucket, samplesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to init storage: %w", err)
+	}
+
+	s := &Storage{
+		db: db,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.asyncTruncateHistory(ctx)
+
+	return s, nil
+}
+
+// Close закрывает хранилище.
+func (s *Storage) Close(ctx context.Context) {
+	if err := s.db.Close(); err != nil {
+		logger.Errorf(ctx, "error close storage: %v", err)
+	}
+}
+
+// historyTruncateInterval период удаления устаревшей истории.
+const historyTruncateInterval = time.Minute
+
+func (s *Storage) asyncTruncateHistory(ctx context.Context) {
+	if s.retention == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(historyTruncateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := s.truncateHistory(now); err != nil {
+					logger.Errorf(ctx, "error truncate history: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// UpdateGauge обновить или добавить, если не существует, метрику типа "градусник".
+func (s *Storage) UpdateGauge(ctx context.Context, agent string, value domain.MetricValue) error {
+	value.Type = domain.GaugeMetricType
+	return s.UpdateMetrics(ctx, agent, []domain.MetricValue{value})
+}
+
+// UpdateCounter обновить или добавить, если не существует, метрику типа "счетчик".
+func (s *Storage) UpdateCounter(ctx context.Context, agent string, value domain.MetricValue) error {
+	value.Type = domain.CounterMetricType
+	return s.UpdateMetrics(ctx, agent, []domain.MetricValue{value})
+}
+
+// UpdateMetrics обновляет значения метрик, присланных агентом, в одной транзакции.
+// Транзакции параллельных запросов объединяются в одну запись на диск.
+func (s *Storage) UpdateMetrics(_ context.Context, agent string, metrics []domain.MetricValue) error {
+	now := time.Now().UTC()
+	err := s.db.Batch(func(tx *bolt.Tx) error {
+		for _, m := range metrics {
+			if err := s.updateMetric(tx, m, now); err != nil {
+				return err
+			}
+		}
+
+		return tx.Bucket(agentsBucket).Put([]byte(agent), encodeTime(now))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update metrics: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Storage) updateMetric(tx *bolt.Tx, m domain.MetricValue, now time.Time) error {
+	key := []byte(m.SeriesKey())
+
+	var (
+		bucket       *bolt.Bucket
+		value        uint64
+		historyValue float64
+	)
+	switch m.Type {
+	case domain.GaugeMetricType:
+		bucket = tx.Bucket(gaugeBucket)
+		value = math.Float64bits(m.GaugeValue)
+		historyValue = m.GaugeValue
+	case domain.CounterMetricType:
+		bucket = tx.Bucket(counterBucket)
+		total := m.CounterValue
+		if data := bucket.Get(key); data != nil {
+			prev, err := seriesValue(data)
+			if err != nil {
+				return err
+			}
+			total += int64(prev)
+		}
+		value = uint64(total)
+		// в историю счетчика записывается приращение
+		historyValue = float64(m.CounterValue)
+	default:
+		return nil
+	}
+
+	data, err := encodeSeries(value, m.Name, m.Labels)
+	if err != nil {
+		return err
+	}
+
+	if err = bucket.Put(key, data); err != nil {
+		return err
+	}
+
+	return s.addSample(tx, m.Type, m.Name, m.Labels, now, historyValue)
+}
+
+// GetGaugeValue получить метрику типа "градусник".
+func (s *Storage) GetGaugeValue(_ context.Context, name string, labels domain.Labels) (float64, error) {
+	value, err := s.getValue(gaugeBucket, domain.SeriesKey(name, labels))
+	if err != nil {
+		return 0, fmt.Errorf("err get gauge: %w", err)
+	}
+
+	return math.Float64frombits(value), nil
+}
+
+// GetCounterValue получить метрику типа "счетчик".
+func (s *Storage) GetCounterValue(_ context.Context, name string, labels domain.Labels) (int64, error) {
+	value, err := s.getValue(counterBucket, domain.SeriesKey(name, labels))
+	if err != nil {
+		return 0, fmt.Errorf("err get counter: %w", err)
+	}
+
+	return int64(value), nil
+}
+
+func (s *Storage) getValue(bucket []byte, key string) (uint64, error) {
+	var value uint64
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		var err error
+		value, err = seriesValue(data)
+		return err
+	})
+
+	return value, err
+}
+
+// GetAllValues вернуть значения всех метрик, метки которых удовлетворяют условиям.
+func (s *Storage) GetAllValues(_ context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	values := make([]domain.MetricValue, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		for _, t := range []domain.MetricType{domain.GaugeMetricType, domain.CounterMetricType} {
+			bucket := gaugeBucket
+			if t == domain.CounterMetricType {
+				bucket = counterBucket
+			}
+
+			err := tx.Bucket(bucket).ForEach(func(_, data []byte) error {
+				value, meta, err := decodeSeries(data)
+				if err != nil {
+					return err
+				}
+
+				if !domain.MatchLabels(meta.Labels, matchers...) {
+					return nil
+				}
+
+				v := domain.MetricValue{
+					Type:   t,
+					Name:   meta.Name,
+					Labels: meta.Labels,
+				}
+				if t == domain.GaugeMetricType {
+					v.GaugeValue = math.Float64frombits(value)
+				} else {
+					v.CounterValue = int64(value)
+				}
+				values = append(values, v)
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("err get all values: %w", err)
+	}
+
+	return values, nil
+}
+
+// GetAgents вернуть агентов, присылавших метрики.
+func (s *Storage) GetAgents(_ context.Context) ([]domain.Agent, error) {
+	res := make([]domain.Agent, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(agentsBucket).ForEach(func(name, data []byte) error {
+			lastSeen, err := decodeTime(data)
+			if err != nil {
+				return err
+			}
+
+			res = append(res, domain.Agent{
+				Name:     string(name),
+				LastSeen: lastSeen,
+			})
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("err get agents: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetAgent вернуть агента по имени.
+func (s *Storage) GetAgent(_ context.Context, name string) (domain.Agent, error) {
+	var res domain.Agent
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(agentsBucket).Get([]byte(name))
+		if data == nil {
+			return domain.ErrNotFound
+		}
+
+		lastSeen, err := decodeTime(data)
+		if err != nil {
+			return err
+		}
+
+		res = domain.Agent{
+			Name:     name,
+			LastSeen: lastSeen,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return domain.Agent{}, fmt.Errorf("err get agent: %w", err)
+	}
+
+	return res, nil
+}
+
+// GetSamples вернуть значения ряда метрики за период запроса, объединенные по интервалам разрешения.
+func (s *Storage) GetSamples(_ context.Context, q domain.RangeQuery) ([]domain.Sample, error) {
+	if s.retention == 0 {
+		return nil, domain.ErrNotSupported
+	}
+
+	res, err := s.samples(historyKey(q.Type, q.Name, q.Labels), q.Start, q.End)
+	if err != nil {
+		return nil, fmt.Errorf("err get samples: %w", err)
+	}
+
+	return res, nil
+}
+
+// Ping проверяет, что хранилище открыто.
+func (s *Storage) Ping(_ context.Context) error {
+	return s.db.View(func(_ *bolt.Tx) error {
+		return nil
+	})
+}
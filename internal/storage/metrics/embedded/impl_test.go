@@ -0,0 +1,166 @@
+package embedded
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func newTestStorage(t *testing.T, dir string, opts ...storageOption) *Storage {
+	t.Helper()
+	s, err := NewStorage(t.Context(), dir, opts...)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close(context.Background())
+	})
+
+	return s
+}
+
+func TestStorage_values(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	dir := t.TempDir()
+	s, err := NewStorage(ctx, dir)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+
+	updates := []domain.MetricValue{
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 1.5},
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: math.Inf(1),
+			Labels: domain.Labels{"host": "b"}},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 2,
+			Labels: domain.Labels{"host": "a"}},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: -5,
+			Labels: domain.Labels{"host": "a"}},
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 3.5},
+	}
+	if err = s.UpdateMetrics(ctx, "agent", updates); err != nil {
+		t.Fatalf("UpdateMetrics() error = %v", err)
+	}
+	s.Close(ctx)
+
+	// значения сохраняются после переоткрытия хранилища
+	s = newTestStorage(t, dir)
+
+	gauge, err := s.GetGaugeValue(ctx, "Alloc", nil)
+	if err != nil || gauge != 3.5 {
+		t.Errorf("GetGaugeValue() = %v, %v, want 3.5", gauge, err)
+	}
+
+	gauge, err = s.GetGaugeValue(ctx, "Alloc", domain.Labels{"host": "b"})
+	if err != nil || !math.IsInf(gauge, 1) {
+		t.Errorf("GetGaugeValue() = %v, %v, want +Inf", gauge, err)
+	}
+
+	counter, err := s.GetCounterValue(ctx, "PollCount", domain.Labels{"host": "a"})
+	if err != nil || counter != -3 {
+		t.Errorf("GetCounterValue() = %v, %v, want -3", counter, err)
+	}
+
+	if _, err = s.GetCounterValue(ctx, "PollCount", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetCounterValue() error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	if _, err = s.GetGaugeValue(ctx, "PollCount", domain.Labels{"host": "a"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetGaugeValue() error = %v, want %v", err, domain.ErrNotFound)
+	}
+
+	matcher, err := domain.NewLabelMatcher(domain.MatchEqual, "host", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		matchers []domain.LabelMatcher
+		want     []string
+	}{
+		{
+			name: "all",
+			want: []string{"Alloc", "Alloc{host=\"b\"}", "PollCount{host=\"a\"}"},
+		},
+		{
+			name:     "by label",
+			matchers: []domain.LabelMatcher{matcher},
+			want:     []string{"PollCount{host=\"a\"}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := s.GetAllValues(ctx, tt.matchers...)
+			if err != nil {
+				t.Fatalf("GetAllValues() error = %v", err)
+			}
+
+			got := make([]string, 0, len(values))
+			for _, v := range values {
+				got = append(got, v.SeriesKey())
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("GetAllValues() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("GetAllValues() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_GetAllValues_empty(t *testing.T) {
+	t.Parallel()
+	s := newTestStorage(t, t.TempDir())
+
+	values, err := s.GetAllValues(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllValues() error = %v", err)
+	}
+	if len(values) != 0 {
+		t.Errorf("GetAllValues() = %v, want empty", values)
+	}
+}
+
+func TestStorage_agents(t *testing.T) {
+	t.Parallel()
+	ctx := t.Context()
+	s := newTestStorage(t, t.TempDir())
+
+	before := time.Now().Add(-time.Second)
+	value := domain.MetricValue{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 1}
+	for _, agent := range []string{"a", "b"} {
+		if err := s.UpdateGauge(ctx, agent, value); err != nil {
+			t.Fatalf("UpdateGauge() error = %v", err)
+		}
+	}
+
+	agents, err := s.GetAgents(ctx)
+	if err != nil {
+		t.Fatalf("GetAgents() error = %v", err)
+	}
+	if len(agents) != 2 {
+		t.Fatalf("GetAgents() = %v, want 2 agents", agents)
+	}
+
+	agent, err := s.GetAgent(ctx, "b")
+	if err != nil {
+		t.Fatalf("GetAgent() error = %v", err)
+	}
+	if agent.LastSeen.Before(before) {
+		t.Errorf("GetAgent() last seen = %v, want after %v", agent.LastSeen, before)
+	}
+
+	if _, err = s.GetAgent(ctx, "c"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetAgent() error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
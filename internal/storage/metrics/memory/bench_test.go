@@ -0,0 +1,233 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// lockedSeries ряд метрики в прежней схеме хранения.
+type lockedSeries struct {
+	name   string
+	labels domain.Labels
+	gauge  float64
+	count  int64
+}
+
+// lockedStorage прежняя схема хранения: все ряды типа в одной карте под одним RWMutex.
+// Используется только как точка отсчета в бенчмарках.
+type lockedStorage struct {
+	gaugeMu sync.RWMutex
+	gauge   map[string]lockedSeries
+
+	counterMu sync.RWMutex
+	counter   map[string]lockedSeries
+}
+
+func newLockedStorage() *lockedStorage {
+	return &lockedStorage{
+		gauge:   make(map[string]lockedSeries),
+		counter: make(map[string]lockedSeries),
+	}
+}
+
+func (s *lockedStorage) UpdateMetrics(_ context.Context, _ string, metrics []domain.MetricValue) error {
+	for _, m := range metrics {
+		key := m.SeriesKey()
+		switch m.Type {
+		case domain.GaugeMetricType:
+			s.gaugeMu.Lock()
+			s.gauge[key] = lockedSeries{name: m.Name, labels: m.Labels.Copy(), gauge: m.GaugeValue}
+			s.gaugeMu.Unlock()
+		case domain.CounterMetricType:
+			s.counterMu.Lock()
+			v := s.counter[key]
+			s.counter[key] = lockedSeries{name: m.Name, labels: m.Labels.Copy(), count: v.count + m.CounterValue}
+			s.counterMu.Unlock()
+		}
+	}
+
+	return nil
+}
+
+func (s *lockedStorage) GetAllValues(_ context.Context, _ ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	s.gaugeMu.RLock()
+	defer s.gaugeMu.RUnlock()
+	s.counterMu.RLock()
+	defer s.counterMu.RUnlock()
+
+	res := make([]domain.MetricValue, 0, len(s.gauge)+len(s.counter))
+	for _, v := range s.gauge {
+		res = append(res, domain.MetricValue{
+			Type: domain.GaugeMetricType, Name: v.name, Labels: v.labels.Copy(), GaugeValue: v.gauge,
+		})
+	}
+	for _, v := range s.counter {
+		res = append(res, domain.MetricValue{
+			Type: domain.CounterMetricType, Name: v.name, Labels: v.labels.Copy(), CounterValue: v.count,
+		})
+	}
+
+	return res, nil
+}
+
+type benchStorage interface {
+	UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error
+	GetAllValues(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error)
+}
+
+// benchAgents количество агентов, каждый присылает свой набор рядов.
+const benchAgents = 256
+
+// benchBatches пакеты метрик агентов, как их присылает агент: 28 градусников и счетчик.
+func benchBatches() [][]domain.MetricValue {
+	res := make([][]domain.MetricValue, benchAgents)
+	for i := range res {
+		labels := domain.Labels{"host": fmt.Sprintf("host-%d", i)}
+		batch := make([]domain.MetricValue, 0, 29)
+		for j := range 28 {
+			batch = append(batch, domain.MetricValue{
+				Type:       domain.GaugeMetricType,
+				Name:       fmt.Sprintf("Gauge%d", j),
+				Labels:     labels,
+				GaugeValue: float64(j),
+			})
+		}
+		batch = append(batch, domain.MetricValue{
+			Type:         domain.CounterMetricType,
+			Name:         "PollCount",
+			Labels:       labels,
+			CounterValue: 1,
+		})
+		res[i] = batch
+	}
+
+	return res
+}
+
+func benchmarkUpdate(b *testing.B, s benchStorage, readEvery int) {
+	batches := benchBatches()
+	// ряды создаются заранее, измеряется обновление существующих рядов
+	for _, batch := range batches {
+		if err := s.UpdateMetrics(b.Context(), "agent", batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	var next atomic.Int64
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			i := next.Add(1)
+			if readEvery > 0 && i%int64(readEvery) == 0 {
+				if _, err := s.GetAllValues(ctx); err != nil {
+					b.Error(err)
+				}
+				continue
+			}
+
+			if err := s.UpdateMetrics(ctx, "agent", batches[i%benchAgents]); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
+
+func BenchmarkStorage_UpdateMetrics(b *testing.B) {
+	newSharded := func(b *testing.B) benchStorage {
+		s, err := NewStorage(b.Context(), "", 0, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		return s
+	}
+
+	tests := []struct {
+		name      string
+		storage   func(b *testing.B) benchStorage
+		readEvery int
+	}{
+		{
+			name:    "locked",
+			storage: func(*testing.B) benchStorage { return newLockedStorage() },
+		},
+		{
+			name:    "sharded",
+			storage: newSharded,
+		},
+		{
+			name:      "locked with reads",
+			storage:   func(*testing.B) benchStorage { return newLockedStorage() },
+			readEvery: 16,
+		},
+		{
+			name:      "sharded with reads",
+			storage:   newSharded,
+			readEvery: 16,
+		},
+		{
+			name: "sharded with history",
+			storage: func(b *testing.B) benchStorage {
+				s, err := NewStorage(b.Context(), "", 0, false, WithHistoryOpt(time.Hour, 10*time.Second))
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				return s
+			},
+		},
+		{
+			name: "sharded with wal",
+			storage: func(b *testing.B) benchStorage {
+				s, err := NewStorage(b.Context(), filepath.Join(b.TempDir(), "metrics.json"), time.Hour, false)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.Cleanup(func() {
+					s.Close(context.Background())
+				})
+
+				return s
+			},
+		},
+	}
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			benchmarkUpdate(b, tt.storage(b), tt.readEvery)
+		})
+	}
+}
+
+func BenchmarkStorage_GetGaugeValue(b *testing.B) {
+	s, err := NewStorage(b.Context(), "", 0, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, batch := range benchBatches() {
+		if err = s.UpdateMetrics(b.Context(), "agent", batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	labels := domain.Labels{"host": "host-1"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		ctx := context.Background()
+		for pb.Next() {
+			if _, err := s.GetGaugeValue(ctx, "Gauge1", labels); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,361 @@
+package memory
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+)
+
+// chunkSize максимальное количество значений в чанке.
+const chunkSize = 120
+
+// sampleColumns количество сжимаемых колонок агрегата: count, sum, min, max, last.
+const sampleColumns = 5
+
+// errChunkEnd ошибка чтения за пределами чанка.
+var errChunkEnd = errors.New("unexpected end of chunk")
+
+// bstream поток битов.
+type bstream struct {
+	stream []byte
+	// count количество свободных бит в последнем байте.
+	count uint8
+}
+
+func (b *bstream) writeBit(bit bool) {
+	if b.count == 0 {
+		b.stream = append(b.stream, 0)
+		b.count = 8
+	}
+
+	if bit {
+		b.stream[len(b.stream)-1] |= 1 << (b.count - 1)
+	}
+	b.count--
+}
+
+func (b *bstream) writeBits(u uint64, nbits int) {
+	for nbits > 0 {
+		nbits--
+		b.writeBit((u>>nbits)&1 == 1)
+	}
+}
+
+// bstreamReader читатель потока битов.
+type bstreamReader struct {
+	stream []byte
+	pos    int
+}
+
+func (r *bstreamReader) readBit() (bool, error) {
+	if r.pos >= len(r.stream)*8 {
+		return false, errChunkEnd
+	}
+
+	bit := r.stream[r.pos/8]&(1<<(7-r.pos%8)) != 0
+	r.pos++
+
+	return bit, nil
+}
+
+func (r *bstreamReader) readBits(nbits int) (uint64, error) {
+	var u uint64
+	for i := 0; i < nbits; i++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		u <<= 1
+		if bit {
+			u |= 1
+		}
+	}
+
+	return u, nil
+}
+
+// xorState состояние XOR кодирования колонки значений.
+type xorState struct {
+	prev     uint64
+	leading  uint8
+	trailing uint8
+}
+
+// chunk сжатая последовательность агрегатов ряда метрики.
+// Время хранится в номерах интервалов разрешения и кодируется разностью разностей,
+// значения каждой колонки кодируются XOR с предыдущим значением, как в Gorilla.
+type chunk struct {
+	b   bstream
+	num int
+
+	firstT int64
+	lastT  int64
+	tDelta int64
+
+	cols [sampleColumns]xorState
+}
+
+// full возвращает признак заполненности чанка.
+func (c *chunk) full() bool {
+	return c.num >= chunkSize
+}
+
+// append добавляет значения колонок для интервала t, t должен возрастать.
+func (c *chunk) append(t int64, values [sampleColumns]float64) {
+	switch c.num {
+	case 0:
+		c.b.writeBits(uint64(t), 64)
+		c.firstT = t
+		for i, v := range values {
+			c.cols[i] = xorState{prev: math.Float64bits(v), leading: 0xff}
+			c.b.writeBits(c.cols[i].prev, 64)
+		}
+	case 1:
+		c.tDelta = t - c.lastT
+		writeVarbits(&c.b, c.tDelta)
+		for i, v := range values {
+			writeXOR(&c.b, &c.cols[i], v)
+		}
+	default:
+		delta := t - c.lastT
+		writeDoD(&c.b, delta-c.tDelta)
+		c.tDelta = delta
+		for i, v := range values {
+			writeXOR(&c.b, &c.cols[i], v)
+		}
+	}
+
+	c.lastT = t
+	c.num++
+}
+
+// iterate декодирует значения чанка по порядку.
+func (c *chunk) iterate(fn func(t int64, values [sampleColumns]float64)) error {
+	r := bstreamReader{stream: c.b.stream}
+
+	var (
+		t      int64
+		tDelta int64
+		cols   [sampleColumns]xorState
+		values [sampleColumns]float64
+	)
+	for n := 0; n < c.num; n++ {
+		switch n {
+		case 0:
+			u, err := r.readBits(64)
+			if err != nil {
+				return err
+			}
+			t = int64(u)
+			for i := range cols {
+				u, err = r.readBits(64)
+				if err != nil {
+					return err
+				}
+				cols[i] = xorState{prev: u}
+				values[i] = math.Float64frombits(u)
+			}
+		default:
+			if n == 1 {
+				d, err := readVarbits(&r)
+				if err != nil {
+					return err
+				}
+				tDelta = d
+			} else {
+				dod, err := readDoD(&r)
+				if err != nil {
+					return err
+				}
+				tDelta += dod
+			}
+			t += tDelta
+
+			for i := range cols {
+				if err := readXOR(&r, &cols[i]); err != nil {
+					return err
+				}
+				values[i] = math.Float64frombits(cols[i].prev)
+			}
+		}
+
+		fn(t, values)
+	}
+
+	return nil
+}
+
+// writeVarbits записывает первую разность времени.
+func writeVarbits(b *bstream, v int64) {
+	if v >= 0 && v < 1<<14 {
+		b.writeBit(false)
+		b.writeBits(uint64(v), 14)
+		return
+	}
+
+	b.writeBit(true)
+	b.writeBits(uint64(v), 64)
+}
+
+func readVarbits(r *bstreamReader) (int64, error) {
+	bit, err := r.readBit()
+	if err != nil {
+		return 0, err
+	}
+
+	nbits := 14
+	if bit {
+		nbits = 64
+	}
+
+	u, err := r.readBits(nbits)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(u), nil
+}
+
+// dodBuckets диапазоны разности разностей времени: префикс и количество бит значения.
+var dodBuckets = []struct {
+	prefix     uint64
+	prefixBits int
+	nbits      int
+}{
+	{prefix: 0b10, prefixBits: 2, nbits: 7},
+	{prefix: 0b110, prefixBits: 3, nbits: 9},
+	{prefix: 0b1110, prefixBits: 4, nbits: 12},
+}
+
+// writeDoD записывает разность разностей времени.
+func writeDoD(b *bstream, dod int64) {
+	if dod == 0 {
+		b.writeBit(false)
+		return
+	}
+
+	for _, bucket := range dodBuckets {
+		if dod >= -(1<<(bucket.nbits-1))+1 && dod <= 1<<(bucket.nbits-1) {
+			b.writeBits(bucket.prefix, bucket.prefixBits)
+			b.writeBits(uint64(dod), bucket.nbits)
+			return
+		}
+	}
+
+	b.writeBits(0b1111, 4)
+	b.writeBits(uint64(dod), 64)
+}
+
+func readDoD(r *bstreamReader) (int64, error) {
+	prefixBits := 0
+	for ; prefixBits < 4; prefixBits++ {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		}
+		if !bit {
+			break
+		}
+	}
+
+	nbits := 64
+	switch prefixBits {
+	case 0:
+		return 0, nil
+	case 1, 2, 3:
+		nbits = dodBuckets[prefixBits-1].nbits
+	}
+
+	u, err := r.readBits(nbits)
+	if err != nil {
+		return 0, err
+	}
+
+	if nbits == 64 {
+		return int64(u), nil
+	}
+
+	// восстанавливаем знак
+	if u > 1<<(nbits-1) {
+		return int64(u) - 1<<nbits, nil
+	}
+
+	return int64(u), nil
+}
+
+// writeXOR записывает значение колонки как XOR с предыдущим.
+func writeXOR(b *bstream, s *xorState, v float64) {
+	u := math.Float64bits(v)
+	delta := u ^ s.prev
+	s.prev = u
+
+	if delta == 0 {
+		b.writeBit(false)
+		return
+	}
+	b.writeBit(true)
+
+	leading := uint8(bits.LeadingZeros64(delta))
+	trailing := uint8(bits.TrailingZeros64(delta))
+	// количество ведущих нулей кодируется 5 битами
+	if leading >= 32 {
+		leading = 31
+	}
+
+	if s.leading != 0xff && leading >= s.leading && trailing >= s.trailing {
+		b.writeBit(false)
+		b.writeBits(delta>>s.trailing, 64-int(s.leading)-int(s.trailing))
+		return
+	}
+
+	s.leading, s.trailing = leading, trailing
+	b.writeBit(true)
+	b.writeBits(uint64(leading), 5)
+	// длина значимой части 64 не помещается в 6 бит и записывается как 0
+	sigbits := 64 - leading - trailing
+	b.writeBits(uint64(sigbits), 6)
+	b.writeBits(delta>>trailing, int(sigbits))
+}
+
+func readXOR(r *bstreamReader, s *xorState) error {
+	bit, err := r.readBit()
+	if err != nil {
+		return err
+	}
+	if !bit {
+		return nil
+	}
+
+	bit, err = r.readBit()
+	if err != nil {
+		return err
+	}
+
+	if bit {
+		leading, err := r.readBits(5)
+		if err != nil {
+			return err
+		}
+
+		sigbits, err := r.readBits(6)
+		if err != nil {
+			return err
+		}
+		if sigbits == 0 {
+			sigbits = 64
+		}
+
+		s.leading = uint8(leading)
+		s.trailing = uint8(64 - leading - sigbits)
+	}
+
+	sigbits := 64 - int(s.leading) - int(s.trailing)
+	u, err := r.readBits(sigbits)
+	if err != nil {
+		return err
+	}
+
+	s.prev ^= u << s.trailing
+	return nil
+}
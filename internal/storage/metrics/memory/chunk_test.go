@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestChunk_iterate(t *testing.T) {
+	t.Parallel()
+	type point struct {
+		t      int64
+		values [sampleColumns]float64
+	}
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name   string
+		points func() []point
+	}{
+		{
+			name: "single",
+			points: func() []point {
+				return []point{{t: 100, values: [sampleColumns]float64{1, 2, 2, 2, 2}}}
+			},
+		},
+		{
+			name: "regular constant",
+			points: func() []point {
+				res := make([]point, 0, chunkSize)
+				for i := 0; i < chunkSize; i++ {
+					res = append(res, point{t: int64(1000 + i), values: [sampleColumns]float64{1, 5, 5, 5, 5}})
+				}
+				return res
+			},
+		},
+		{
+			name: "irregular random",
+			points: func() []point {
+				res := make([]point, 0, chunkSize)
+				t := int64(170000000)
+				for i := 0; i < chunkSize; i++ {
+					t += 1 + random.Int63n(int64(1)<<uint(random.Intn(40)))
+					v := random.NormFloat64() * 1e6
+					res = append(res, point{
+						t:      t,
+						values: [sampleColumns]float64{float64(i + 1), v * 2, v - 1, v + 1, v},
+					})
+				}
+				return res
+			},
+		},
+		{
+			name: "special values",
+			points: func() []point {
+				return []point{
+					{t: 1, values: [sampleColumns]float64{1, 0, 0, 0, 0}},
+					{t: 2, values: [sampleColumns]float64{1, math.Inf(1), math.Inf(-1), math.MaxFloat64, -0.5}},
+					{t: 300, values: [sampleColumns]float64{1, math.SmallestNonzeroFloat64, 1, 1, 1}},
+					{t: 301, values: [sampleColumns]float64{1, 0, 0, 0, 0}},
+					{t: 1 << 40, values: [sampleColumns]float64{1, 1, 1, 1, 1}},
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			points := tt.points()
+			c := &chunk{}
+			for _, p := range points {
+				c.append(p.t, p.values)
+			}
+
+			got := make([]point, 0, len(points))
+			err := c.iterate(func(t int64, values [sampleColumns]float64) {
+				got = append(got, point{t: t, values: values})
+			})
+			if err != nil {
+				t.Fatalf("iterate() error = %v", err)
+			}
+
+			if len(got) != len(points) {
+				t.Fatalf("iterate() got %d points, want %d", len(got), len(points))
+			}
+			for i := range points {
+				if got[i] != points[i] {
+					t.Errorf("iterate() point %d got = %v, want %v", i, got[i], points[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChunk_compression(t *testing.T) {
+	t.Parallel()
+	c := &chunk{}
+	for i := 0; i < chunkSize; i++ {
+		c.append(int64(i), [sampleColumns]float64{1, 42, 42, 42, 42})
+	}
+
+	// несжатое представление занимает 8 байт на время и каждую колонку
+	raw := chunkSize * 8 * (sampleColumns + 1)
+	if len(c.b.stream)*10 > raw {
+		t.Errorf("chunk size %d bytes, raw size %d bytes", len(c.b.stream), raw)
+	}
+}
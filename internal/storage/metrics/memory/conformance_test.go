@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/storagetest"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+)
+
+func TestStorage_conformance(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		filePath func(t *testing.T) string
+		opts     []storageOption
+	}{
+		{
+			name:     "without file",
+			filePath: func(*testing.T) string { return "" },
+			opts:     []storageOption{WithHistoryOpt(time.Hour, time.Second)},
+		},
+		{
+			name: "with file",
+			filePath: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "metrics.json")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			storagetest.Run(t, func(t *testing.T) metrics.MetricStorage {
+				s, err := NewStorage(t.Context(), tt.filePath(t), 0, false, tt.opts...)
+				if err != nil {
+					t.Fatalf("NewStorage() error = %v", err)
+				}
+				t.Cleanup(func() {
+					s.Close(context.Background())
+				})
+
+				return s
+			})
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// seriesHistory история ряда метрики: сжатые чанки и открытый интервал разрешения.
+type seriesHistory struct {
+	chunks []*chunk
+	// head агрегат текущего интервала, еще не записанный в чанк.
+	head  domain.Sample
+	headT int64
+}
+
+// history ограниченная по времени история значений рядов метрик.
+// Значения объединяются в агрегаты по интервалам разрешения.
+type history struct {
+	mu     sync.RWMutex
+	series map[string]*seriesHistory
+
+	retention  time.Duration
+	resolution time.Duration
+}
+
+func newHistory(retention, resolution time.Duration) *history {
+	if resolution <= 0 {
+		resolution = time.Second
+	}
+
+	return &history{
+		series:     make(map[string]*seriesHistory),
+		retention:  retention,
+		resolution: resolution,
+	}
+}
+
+// historyKey ключ истории ряда, ряды разных типов хранятся раздельно.
+func historyKey(t domain.MetricType, name string, labels domain.Labels) string {
+	return t.String() + ":" + domain.SeriesKey(name, labels)
+}
+
+// add добавляет значение ряда, полученное в момент ts.
+func (h *history) add(key string, ts time.Time, value float64) {
+	t := ts.UnixNano() / int64(h.resolution)
+	sample := domain.NewSample(ts, value)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, exist := h.series[key]
+	if !exist {
+		s = &seriesHistory{}
+		h.series[key] = s
+	}
+
+	switch {
+	case s.head.Count == 0:
+		s.headT = t
+		s.head = domain.Sample{Timestamp: h.timeOf(t)}.Merge(sample)
+	case t > s.headT:
+		s.flushHead()
+		s.headT = t
+		s.head = domain.Sample{Timestamp: h.timeOf(t)}.Merge(sample)
+	default:
+		// значение из прошлого интервала учитывается в текущем
+		s.head = s.head.Merge(sample)
+	}
+}
+
+// flushHead записывает агрегат открытого интервала в чанк.
+func (s *seriesHistory) flushHead() {
+	if len(s.chunks) == 0 || s.chunks[len(s.chunks)-1].full() {
+		s.chunks = append(s.chunks, &chunk{})
+	}
+
+	s.chunks[len(s.chunks)-1].append(s.headT, [sampleColumns]float64{
+		float64(s.head.Count), s.head.Sum, s.head.Min, s.head.Max, s.head.Last,
+	})
+	s.head = domain.Sample{}
+}
+
+// samples возвращает упорядоченные агрегаты ряда с началом интервала в [start, end).
+func (h *history) samples(key string, start, end time.Time) ([]domain.Sample, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	res := make([]domain.Sample, 0)
+	s, exist := h.series[key]
+	if !exist {
+		return res, nil
+	}
+
+	startT := start.UnixNano() / int64(h.resolution)
+	endT := end.UnixNano() / int64(h.resolution)
+	for _, c := range s.chunks {
+		if c.lastT < startT || c.firstT > endT {
+			continue
+		}
+
+		err := c.iterate(func(t int64, values [sampleColumns]float64) {
+			ts := h.timeOf(t)
+			if ts.Before(start) || !ts.Before(end) {
+				return
+			}
+
+			res = append(res, domain.Sample{
+				Timestamp: ts,
+				Count:     int64(values[0]),
+				Sum:       values[1],
+				Min:       values[2],
+				Max:       values[3],
+				Last:      values[4],
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if s.head.Count > 0 && !s.head.Timestamp.Before(start) && s.head.Timestamp.Before(end) {
+		res = append(res, s.head)
+	}
+
+	return res, nil
+}
+
+// truncate удаляет чанки, все значения которых старше срока хранения.
+func (h *history) truncate(now time.Time) {
+	minT := now.Add(-h.retention).UnixNano() / int64(h.resolution)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for key, s := range h.series {
+		i := 0
+		for i < len(s.chunks) && s.chunks[i].lastT < minT {
+			i++
+		}
+		s.chunks = s.chunks[i:]
+
+		if s.head.Count > 0 && s.headT < minT {
+			s.head = domain.Sample{}
+		}
+
+		if len(s.chunks) == 0 && s.head.Count == 0 {
+			delete(h.series, key)
+		}
+	}
+}
+
+// timeOf возвращает время начала интервала разрешения.
+func (h *history) timeOf(t int64) time.Time {
+	return time.Unix(0, t*int64(h.resolution)).UTC()
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestHistory_samples(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(time.Hour, 10*time.Second)
+	key := historyKey(domain.GaugeMetricType, "Alloc", nil)
+
+	h.add(key, start, 1)
+	h.add(key, start.Add(5*time.Second), 3)
+	h.add(key, start.Add(25*time.Second), 7)
+	h.add(key, start.Add(31*time.Second), 5)
+	h.add(historyKey(domain.CounterMetricType, "Alloc", nil), start, 100)
+
+	tests := []struct {
+		name  string
+		start time.Time
+		end   time.Time
+		want  []domain.Sample
+	}{
+		{
+			name:  "all",
+			start: start,
+			end:   start.Add(time.Minute),
+			want: []domain.Sample{
+				{Timestamp: start, Count: 2, Sum: 4, Min: 1, Max: 3, Last: 3},
+				{Timestamp: start.Add(20 * time.Second), Count: 1, Sum: 7, Min: 7, Max: 7, Last: 7},
+				{Timestamp: start.Add(30 * time.Second), Count: 1, Sum: 5, Min: 5, Max: 5, Last: 5},
+			},
+		},
+		{
+			name:  "range",
+			start: start.Add(10 * time.Second),
+			end:   start.Add(30 * time.Second),
+			want: []domain.Sample{
+				{Timestamp: start.Add(20 * time.Second), Count: 1, Sum: 7, Min: 7, Max: 7, Last: 7},
+			},
+		},
+		{
+			name:  "empty",
+			start: start.Add(time.Hour),
+			end:   start.Add(2 * time.Hour),
+			want:  []domain.Sample{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := h.samples(key, tt.start, tt.end)
+			if err != nil {
+				t.Fatalf("samples() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("samples() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHistory_truncate(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := newHistory(time.Hour, time.Second)
+	key := historyKey(domain.GaugeMetricType, "Alloc", nil)
+
+	// три заполненных чанка и открытый интервал
+	for i := 0; i < 3*chunkSize+1; i++ {
+		h.add(key, start.Add(time.Duration(i)*time.Second), float64(i))
+	}
+	h.add(historyKey(domain.GaugeMetricType, "Old", nil), start, 1)
+
+	h.truncate(start.Add(time.Hour + 2*chunkSize*time.Second))
+
+	if len(h.series) != 1 {
+		t.Fatalf("got %d series, want 1", len(h.series))
+	}
+
+	got, err := h.samples(key, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("samples() error = %v", err)
+	}
+	if len(got) != chunkSize+1 {
+		t.Fatalf("got %d samples, want %d", len(got), chunkSize+1)
+	}
+	if want := start.Add(2 * chunkSize * time.Second); !got[0].Timestamp.Equal(want) {
+		t.Errorf("got first sample at %v, want %v", got[0].Timestamp, want)
+	}
+}
+
+func TestStorage_GetSamples(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		opts    []storageOption
+		wantErr error
+	}{
+		{
+			name:    "with history",
+			opts:    []storageOption{WithHistoryOpt(time.Hour, time.Second)},
+			wantErr: nil,
+		},
+		{
+			name:    "without history",
+			opts:    nil,
+			wantErr: domain.ErrNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s, err := NewStorage(t.Context(), "", 0, false, tt.opts...)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			value := domain.MetricValue{
+				Type:         domain.CounterMetricType,
+				Name:         "PollCount",
+				CounterValue: 5,
+			}
+			if err := s.UpdateCounter(t.Context(), "agent", value); err != nil {
+				t.Fatalf("UpdateCounter() error = %v", err)
+			}
+
+			now := time.Now()
+			got, err := s.GetSamples(t.Context(), domain.RangeQuery{
+				Type:  domain.CounterMetricType,
+				Name:  "PollCount",
+				Start: now.Add(-time.Minute),
+				End:   now.Add(time.Minute),
+				Step:  time.Minute,
+			})
+			if err != tt.wantErr {
+				t.Fatalf("GetSamples() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if len(got) != 1 || got[0].Sum != 5 {
+				t.Errorf("GetSamples() got = %v", got)
+			}
+		})
+	}
+}
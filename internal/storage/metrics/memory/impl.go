@@ -6,253 +6,584 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"math"
 	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
 	"github.com/kdv2001/onlyMetrics/pkg/logger"
 )
 
-// Storage хранилище метрик.
+// Storage хранилище метрик. Ряды метрик хранятся по ключу из имени и отсортированных меток
+// в шардах, чтение значений не требует блокировок, а значения обновляются атомарно.
+//
+// Если задан файл, хранилище периодически сохраняет в него снимок метрик,
+// а обновления между снимками дописывает в журнал рядом с файлом снимка.
 type Storage struct {
-	gaugeMu sync.RWMutex
-	gauge   map[string]float64
+	gauges   shards
+	counters shards
 
-	counterMu sync.RWMutex
-	counter   map[string]int64
+	// agents время последнего обновления метрик агентом в наносекундах, *atomic.Int64 по имени агента.
+	agents sync.Map
+
+	// histories история значений по шардам, nil если история не хранится.
+	histories []*history
 
 	filePath string
 	period   time.Duration
+
+	// persistMu обновления удерживают на чтение, сохранение снимка - на запись,
+	// чтобы снимок и журнал разделялись ровно по номеру записи.
+	persistMu sync.RWMutex
+	// seq номер последней записи журнала.
+	seq    atomic.Uint64
+	wal    *walWriter
+	closed bool
+
+	// snapshotMu не дает сохранять снимки одновременно.
+	snapshotMu sync.Mutex
+	snapshotCh chan struct{}
+}
+
+// storageOption опция хранилища.
+type storageOption func(s *Storage)
+
+// WithHistoryOpt включает хранение истории значений в течение retention,
+// значения объединяются по интервалам resolution.
+func WithHistoryOpt(retention, resolution time.Duration) storageOption {
+	return func(s *Storage) {
+		if retention <= 0 {
+			s.histories = nil
+			return
+		}
+
+		s.histories = make([]*history, shardCount)
+		for i := range s.histories {
+			s.histories[i] = newHistory(retention, resolution)
+		}
+	}
 }
 
 // NewStorage создает объект хранилища.
+// Нулевой period означает, что каждое обновление сбрасывается в журнал на диске до ответа.
 func NewStorage(ctx context.Context, filePath string,
-	period time.Duration, restoreData bool) *Storage {
+	period time.Duration, restoreData bool, opts ...storageOption) (*Storage, error) {
 	s := &Storage{
-		gauge:    make(map[string]float64),
-		counter:  make(map[string]int64),
-		filePath: filePath,
-		period:   period,
+		filePath:   filePath,
+		period:     period,
+		snapshotCh: make(chan struct{}, 1),
 	}
 
-	s.asyncFlushData(ctx)
-	// неудачная загрузка метрик не крит, чтоб не запускать приложение
-	if restoreData {
-		if err := s.restoreMetrics(ctx); err != nil {
-			if !errors.Is(err, os.ErrNotExist) {
-				logger.Errorf(ctx, "error restore metric: %v", err)
-			}
-		}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err := s.openPersistence(ctx, restoreData); err != nil {
+		return nil, err
 	}
 
-	return s
+	s.asyncSnapshot(ctx)
+	s.asyncTruncateHistory(ctx)
+
+	return s, nil
 }
 
 // Close закрывает хранилище с сохранением метрик.
 func (s *Storage) Close(ctx context.Context) {
-	err := s.flushMetrics(ctx)
+	if s.filePath == "" {
+		return
+	}
+
+	if err := s.snapshot(ctx); err != nil {
+		logger.Errorf(ctx, "error store metrics: %v", err)
+	}
+
+	s.closeWAL()
+}
+
+// closeWAL останавливает запись журнала после обработки принятых обновлений.
+func (s *Storage) closeWAL() {
+	s.persistMu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.wal.reqs)
+	}
+	s.persistMu.Unlock()
+
+	<-s.wal.stopped
+}
+
+func (s *Storage) walPath() string {
+	return s.filePath + walSuffix
+}
+
+// openPersistence восстанавливает метрики из снимка и журнала и открывает журнал на запись.
+func (s *Storage) openPersistence(ctx context.Context, restoreData bool) error {
+	if s.filePath == "" {
+		return nil
+	}
+
+	// при неудачном восстановлении запуск прерывается, иначе снимок ниже
+	// заменил бы непрочитанные метрики и удалил записи журнала
+	if restoreData {
+		if err := s.restoreMetrics(ctx); err != nil {
+			return fmt.Errorf("failed to restore metrics: %w", err)
+		}
+	}
+
+	// без восстановления прежние записи журнала удаляются
+	wal, err := openWAL(s.walPath(), !restoreData, s.period <= 0, s.requestSnapshot)
 	if err != nil {
-		logger.Errorf(ctx, "error flush metrics")
+		return fmt.Errorf("failed to open wal: %w", err)
+	}
+	s.wal = wal
+	go wal.run(ctx)
+
+	// снимок переносит восстановленные записи из журнала
+	if err = s.snapshot(ctx); err != nil {
+		s.closeWAL()
+		return fmt.Errorf("failed to store metrics: %w", err)
+	}
+
+	return nil
+}
+
+// requestSnapshot запрашивает сохранение снимка вне расписания.
+func (s *Storage) requestSnapshot() {
+	select {
+	case s.snapshotCh <- struct{}{}:
+	default:
 	}
 }
 
-func (s *Storage) asyncFlushData(ctx context.Context) {
+func (s *Storage) asyncSnapshot(ctx context.Context) {
+	if s.filePath == "" {
+		return
+	}
+
 	go func() {
-		// для нулевого периода запись в файл должна быть синхронной
-		if s.period <= 0 {
-			return
+		// при нулевом периоде снимок сохраняется только при разрастании журнала
+		var tick <-chan time.Time
+		if s.period > 0 {
+			ticker := time.NewTicker(s.period)
+			defer ticker.Stop()
+			tick = ticker.C
 		}
 
-		// запускаем периодический процесс для обновления метрик в файле
-		ticker := time.NewTicker(s.period)
 		for {
 			select {
 			case <-ctx.Done():
-				err := s.flushMetrics(ctx)
-				if err != nil {
-					logger.Errorf(ctx, "error flush metric")
-				}
 				return
-			case <-ticker.C:
-				err := s.flushMetrics(ctx)
-				if err != nil {
-					logger.Errorf(ctx, "error flush metric: %v", err)
+			case <-tick:
+				if err := s.snapshot(ctx); err != nil {
+					logger.Errorf(ctx, "error store metrics: %v", err)
+					continue
 				}
 				logger.Infof(ctx, "store metrics")
+			case <-s.snapshotCh:
+				if err := s.snapshot(ctx); err != nil {
+					logger.Errorf(ctx, "error store metrics: %v", err)
+				}
 			}
 		}
 	}()
 }
 
-func (s *Storage) flushMetrics(ctx context.Context) error {
-	if s.filePath == "" {
-		return nil
+// historyTruncateInterval период удаления устаревшей истории.
+const historyTruncateInterval = time.Minute
+
+func (s *Storage) asyncTruncateHistory(ctx context.Context) {
+	if s.histories == nil {
+		return
 	}
 
+	go func() {
+		ticker := time.NewTicker(historyTruncateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, h := range s.histories {
+					h.truncate(now)
+				}
+			}
+		}
+	}()
+}
+
+// snapshot атомарно сохраняет снимок метрик и удаляет из журнала вошедшие в него записи.
+// Обновления приостанавливаются только на время копирования значений.
+func (s *Storage) snapshot(ctx context.Context) error {
+	s.snapshotMu.Lock()
+	defer s.snapshotMu.Unlock()
+
+	s.persistMu.Lock()
+	if s.closed {
+		s.persistMu.Unlock()
+		return errStorageClosed
+	}
+
+	seq := s.seq.Load()
 	values, err := s.GetAllValues(ctx)
 	if err != nil {
+		s.persistMu.Unlock()
 		return err
 	}
 
-	bytes, err := json.Marshal(values)
-	if err != nil {
+	// все записи до seq уже в очереди журнала и попадут в сегмент
+	rotated := make(chan error, 1)
+	s.wal.reqs <- walRequest{rotate: true, done: rotated}
+	s.persistMu.Unlock()
+
+	if err = <-rotated; err != nil {
 		return err
 	}
 
-	file, err := os.OpenFile(s.filePath, os.O_WRONLY|os.O_CREATE, 0666)
+	metrics := make([]snapshotMetric, 0, len(values))
+	for _, v := range values {
+		metrics = append(metrics, snapshotMetric{
+			Type:         v.Type,
+			Name:         v.Name,
+			Labels:       v.Labels,
+			CounterValue: v.CounterValue,
+			GaugeValue:   jsonFloat(v.GaugeValue),
+		})
+	}
+
+	data, err := json.Marshal(snapshot{
+		Seq:     seq,
+		Metrics: metrics,
+	})
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	_, err = file.Write(bytes)
-	if err != nil {
+	if err = writeFileAtomic(s.filePath, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	return s.wal.removeSegment()
+}
+
+// restoreMetrics восстанавливает метрики из снимка и записанных после него записей журнала.
+func (s *Storage) restoreMetrics(ctx context.Context) error {
+	snap, err := readSnapshot(s.filePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
 
+	for _, v := range snap.Metrics {
+		key := domain.SeriesKey(v.Name, v.Labels)
+		switch v.Type {
+		case domain.CounterMetricType:
+			s.counters.of(key).getOrCreate(key, v.Name, v.Labels, 0).bits.Store(uint64(v.CounterValue))
+		case domain.GaugeMetricType:
+			bits := math.Float64bits(float64(v.GaugeValue))
+			s.gauges.of(key).getOrCreate(key, v.Name, v.Labels, bits).bits.Store(bits)
+		}
+	}
+
+	// записи до номера снимка уже вошли в него, если снимок сохранился, а журнал не успел очиститься
+	records := make([]walRecord, 0)
+	for _, path := range []string{s.walPath() + segmentSuffix, s.walPath()} {
+		err = readWAL(path, func(r walRecord) {
+			if r.Seq > snap.Seq {
+				records = append(records, r)
+			}
+		})
+		switch {
+		case errors.Is(err, errWALCorrupted):
+			// недописанные при аварийном завершении записи ожидаемы
+			logger.Errorf(ctx, "error read %s: %v", path, err)
+		case err != nil:
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Seq < records[j].Seq
+	})
+
+	seq := snap.Seq
+	if len(records) > 0 {
+		seq = records[len(records)-1].Seq
+	}
+	for _, r := range records {
+		s.apply(&r)
+	}
+	// apply нумерует записи заново, номера продолжаются после восстановленных
+	s.seq.Store(seq)
+
+	logger.Infof(ctx, "restore metrics: %d from snapshot, %d from wal", len(snap.Metrics), len(records))
 	return nil
 }
 
-func (s *Storage) restoreMetrics(ctx context.Context) error {
+// apply применяет запись журнала к метрикам и присваивает ей номер, если журнал ведется.
+func (s *Storage) apply(r *walRecord) {
+	if r.key == "" {
+		r.key = domain.SeriesKey(r.Name, r.Labels)
+	}
+
+	switch r.Type {
+	case domain.GaugeMetricType:
+		bits := math.Float64bits(float64(r.Gauge))
+		sr := s.gauges.of(r.key).getOrCreate(r.key, r.Name, r.Labels, bits)
+		if s.filePath == "" {
+			sr.bits.Store(bits)
+			return
+		}
+
+		// номер присваивается под блокировкой ряда, чтобы порядок записей
+		// в журнале совпадал с порядком обновлений градусника
+		sr.mu.Lock()
+		r.Seq = s.seq.Add(1)
+		sr.bits.Store(bits)
+		sr.mu.Unlock()
+	case domain.CounterMetricType:
+		sr := s.counters.of(r.key).getOrCreate(r.key, r.Name, r.Labels, 0)
+		if s.filePath != "" && r.Seq == 0 {
+			// приращения счетчика перестановочны, порядок не важен
+			r.Seq = s.seq.Add(1)
+		}
+		sr.bits.Add(uint64(r.Delta))
+	}
+}
+
+// update применяет обновления метрик и передает их в журнал.
+// При нулевом периоде ответ возвращается после сброса журнала на диск.
+func (s *Storage) update(agent string, metrics []domain.MetricValue) error {
+	s.touchAgent(agent)
+
+	records := make([]walRecord, 0, len(metrics))
+	for _, m := range metrics {
+		r := walRecord{
+			Type:   m.Type,
+			Name:   m.Name,
+			Labels: m.Labels,
+		}
+		switch m.Type {
+		case domain.GaugeMetricType:
+			r.Gauge = jsonFloat(m.GaugeValue)
+		case domain.CounterMetricType:
+			r.Delta = m.CounterValue
+		default:
+			continue
+		}
+
+		if s.filePath != "" {
+			// запись кодируется в журнал после ответа, метки не должны меняться
+			r.Labels = r.Labels.Copy()
+		}
+		records = append(records, r)
+	}
+
 	if s.filePath == "" {
+		s.applyUpdates(records)
 		return nil
 	}
 
-	file, err := os.OpenFile(s.filePath, os.O_RDONLY, 0666)
-	if err != nil {
-		return err
+	s.persistMu.RLock()
+	if s.closed {
+		s.persistMu.RUnlock()
+		return errStorageClosed
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return err
+	if s.period > 0 {
+		s.applyUpdates(records)
+		s.wal.reqs <- walRequest{records: records}
+		s.persistMu.RUnlock()
+		return nil
+	}
+	defer s.persistMu.RUnlock()
+
+	// счетчики применяются только после записи журнала, иначе повтор запроса после ошибки
+	// увеличил бы счетчик дважды. Градусники применяются сразу, чтобы порядок записей
+	// в журнале совпадал с порядком обновлений, повторная запись не меняет их значение.
+	// Снимок не может сохраниться до применения счетчиков, так как persistMu удерживается
+	gauges := make([]walRecord, 0, len(records))
+	counters := make([]walRecord, 0, len(records))
+	for _, r := range records {
+		if r.Type == domain.CounterMetricType {
+			r.Seq = s.seq.Add(1)
+			counters = append(counters, r)
+			continue
+		}
+		gauges = append(gauges, r)
 	}
+	s.applyUpdates(gauges)
 
-	var values []domain.MetricValue
-	if err = json.Unmarshal(data, &values); err != nil {
+	done := make(chan error, 1)
+	s.wal.reqs <- walRequest{
+		records: append(gauges, counters...),
+		done:    done,
+	}
+	if err := <-done; err != nil {
 		return err
 	}
+	s.applyUpdates(counters)
 
-	s.gaugeMu.Lock()
-	defer s.gaugeMu.Unlock()
-	s.counterMu.Lock()
-	defer s.counterMu.Unlock()
+	return nil
+}
 
-	for _, v := range values {
-		switch v.Type {
-		case domain.CounterMetricType:
-			s.counter[v.Name] = v.CounterValue
-		case domain.GaugeMetricType:
-			s.gauge[v.Name] = v.GaugeValue
+// applyUpdates применяет записи к метрикам и истории, присваивая им номера.
+func (s *Storage) applyUpdates(records []walRecord) {
+	now := time.Now()
+	for i := range records {
+		r := &records[i]
+		s.apply(r)
+
+		if s.histories == nil {
+			continue
 		}
-	}
 
-	logger.Infof(ctx, "resotre metrics")
-	return nil
+		key := r.Type.String() + ":" + r.key
+		value := float64(r.Gauge)
+		if r.Type == domain.CounterMetricType {
+			// в историю счетчика записывается приращение
+			value = float64(r.Delta)
+		}
+		s.histories[shardIndex(key)].add(key, now, value)
+	}
 }
 
 // UpdateGauge обновить или добавить, если не существует, метрику типа "градусник".
-func (s *Storage) UpdateGauge(ctx context.Context, value domain.MetricValue) error {
-	s.gaugeMu.Lock()
-	s.gauge[value.Name] = value.GaugeValue
-	s.gaugeMu.Unlock()
+func (s *Storage) UpdateGauge(_ context.Context, agent string, value domain.MetricValue) error {
+	value.Type = domain.GaugeMetricType
+	return s.update(agent, []domain.MetricValue{value})
+}
 
-	if err := s.flushMetrics(ctx); err != nil {
-		return err
+// UpdateCounter обновить или добавить, если не существует, метрику типа "счетчик".
+func (s *Storage) UpdateCounter(_ context.Context, agent string, value domain.MetricValue) error {
+	value.Type = domain.CounterMetricType
+	return s.update(agent, []domain.MetricValue{value})
+}
+
+// UpdateMetrics обновляет значения метрик, присланных агентом.
+// Все обновления пакета передаются в журнал одним запросом.
+func (s *Storage) UpdateMetrics(_ context.Context, agent string, metrics []domain.MetricValue) error {
+	return s.update(agent, metrics)
+}
+
+// touchAgent обновляет время последнего обновления метрик агентом.
+func (s *Storage) touchAgent(agent string) {
+	now := time.Now().UnixNano()
+	v, exist := s.agents.Load(agent)
+	if !exist {
+		v, _ = s.agents.LoadOrStore(agent, new(atomic.Int64))
 	}
 
-	return nil
+	v.(*atomic.Int64).Store(now)
 }
 
-// UpdateCounter обновить или добавить, если не существует, метрику типа "счетчик".
-func (s *Storage) UpdateCounter(ctx context.Context, value domain.MetricValue) error {
-	s.counterMu.Lock()
-	v := s.counter[value.Name]
-	s.counter[value.Name] = v + value.CounterValue
-	s.counterMu.Unlock()
+// GetAgents вернуть агентов, присылавших метрики.
+func (s *Storage) GetAgents(_ context.Context) ([]domain.Agent, error) {
+	res := make([]domain.Agent, 0)
+	s.agents.Range(func(name, lastSeen any) bool {
+		res = append(res, domain.Agent{
+			Name:     name.(string),
+			LastSeen: time.Unix(0, lastSeen.(*atomic.Int64).Load()).UTC(),
+		})
 
-	if err := s.flushMetrics(ctx); err != nil {
-		return err
+		return true
+	})
+
+	return res, nil
+}
+
+// GetAgent вернуть агента по имени.
+func (s *Storage) GetAgent(_ context.Context, name string) (domain.Agent, error) {
+	lastSeen, exist := s.agents.Load(name)
+	if !exist {
+		return domain.Agent{}, fmt.Errorf("err get agent: %w", domain.ErrNotFound)
 	}
 
-	return nil
+	return domain.Agent{
+		Name:     name,
+		LastSeen: time.Unix(0, lastSeen.(*atomic.Int64).Load()).UTC(),
+	}, nil
 }
 
 // GetGaugeValue получить метрику типа "градусник".
-func (s *Storage) GetGaugeValue(_ context.Context, name string) (float64, error) {
-	s.gaugeMu.RLock()
-	defer s.gaugeMu.RUnlock()
-	val, exist := s.gauge[name]
-	if !exist {
+func (s *Storage) GetGaugeValue(_ context.Context, name string, labels domain.Labels) (float64, error) {
+	key := domain.SeriesKey(name, labels)
+	sr := s.gauges.of(key).get(key)
+	if sr == nil {
 		return 0, fmt.Errorf("err get gauge: %w", domain.ErrNotFound)
 	}
 
-	return val, nil
+	return sr.gauge(), nil
 }
 
 // GetCounterValue получить метрику типа "счетчик".
-func (s *Storage) GetCounterValue(_ context.Context, name string) (int64, error) {
-	s.counterMu.RLock()
-	defer s.counterMu.RUnlock()
-	val, exist := s.counter[name]
-	if !exist {
+func (s *Storage) GetCounterValue(_ context.Context, name string, labels domain.Labels) (int64, error) {
+	key := domain.SeriesKey(name, labels)
+	sr := s.counters.of(key).get(key)
+	if sr == nil {
 		return 0, fmt.Errorf("err get counter: %w", domain.ErrNotFound)
 	}
 
-	return val, nil
+	return sr.counter(), nil
 }
 
-// GetAllValues вернуть все значения метрик.
-func (s *Storage) GetAllValues(_ context.Context) ([]domain.MetricValue, error) {
-	values := make([]domain.MetricValue, 0, len(s.gauge)+len(s.counter))
-	s.gaugeMu.RLock()
-	defer s.gaugeMu.RUnlock()
-	for n, v := range s.gauge {
-		values = append(values, domain.MetricValue{
-			Type:       domain.GaugeMetricType,
-			Name:       n,
-			GaugeValue: v,
-		})
+// GetAllValues вернуть значения всех метрик, метки которых удовлетворяют условиям.
+// Наборы рядов шардов не изменяются после публикации, поэтому обход не блокирует обновления.
+func (s *Storage) GetAllValues(_ context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	gauges, counters := s.gauges.load(), s.counters.load()
+	total := 0
+	for i := range gauges {
+		total += len(gauges[i]) + len(counters[i])
 	}
 
-	s.counterMu.RLock()
-	defer s.counterMu.RUnlock()
-	for n, v := range s.counter {
-		values = append(values, domain.MetricValue{
-			Type:         domain.CounterMetricType,
-			Name:         n,
-			CounterValue: v,
-		})
+	values := make([]domain.MetricValue, 0, total)
+	for _, m := range gauges {
+		for _, sr := range m {
+			if !domain.MatchLabels(sr.labels, matchers...) {
+				continue
+			}
+
+			values = append(values, domain.MetricValue{
+				Type:       domain.GaugeMetricType,
+				Name:       sr.name,
+				Labels:     sr.labels.Copy(),
+				GaugeValue: sr.gauge(),
+			})
+		}
+	}
+
+	for _, m := range counters {
+		for _, sr := range m {
+			if !domain.MatchLabels(sr.labels, matchers...) {
+				continue
+			}
+
+			values = append(values, domain.MetricValue{
+				Type:         domain.CounterMetricType,
+				Name:         sr.name,
+				Labels:       sr.labels.Copy(),
+				CounterValue: sr.counter(),
+			})
+		}
 	}
 
 	return values, nil
 }
 
+// GetSamples вернуть значения ряда метрики за период запроса, объединенные по интервалам разрешения.
+func (s *Storage) GetSamples(_ context.Context, q domain.RangeQuery) ([]domain.Sample, error) {
+	if s.histories == nil {
+		return nil, domain.ErrNotSupported
+	}
+
+	key := historyKey(q.Type, q.Name, q.Labels)
+	return s.histories[shardIndex(key)].samples(key, q.Start, q.End)
+}
+
 // Ping необходим только для удовлетворения общему интерфейсу.
 func (s *Storage) Ping(_ context.Context) error {
 	return nil
 }
-
-// UpdateMetrics обновляет значения метрик.
-func (s *Storage) UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error {
-	errs := make([]error, 0)
-	for _, m := range metrics {
-		switch m.Type {
-		case domain.GaugeMetricType:
-			err := s.UpdateGauge(ctx, m)
-			if err != nil {
-				errs = append(errs, err)
-			}
-		case domain.CounterMetricType:
-			err := s.UpdateCounter(ctx, m)
-			if err != nil {
-				errs = append(errs, err)
-			}
-		}
-	}
-
-	return errors.Join(errs...)
-}
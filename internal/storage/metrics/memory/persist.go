@@ -0,0 +1,445 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// walSuffix суффикс файла журнала обновлений рядом с файлом снимка.
+const walSuffix = ".wal"
+
+// segmentSuffix суффикс сегмента журнала, перенесенного перед сохранением снимка.
+const segmentSuffix = ".old"
+
+// walCompactSize размер журнала, после которого снимок сохраняется вне расписания.
+const walCompactSize = 4 << 20
+
+// maxWALRecordSize максимальный размер записи журнала.
+const maxWALRecordSize = 1 << 20
+
+// walMaxBatch максимальное количество запросов, записываемых в журнал за раз.
+const walMaxBatch = 256
+
+// walQueueSize размер очереди запросов к журналу.
+const walQueueSize = 1024
+
+var (
+	errStorageClosed = errors.New("storage is closed")
+	errWALCorrupted  = errors.New("wal is corrupted")
+)
+
+// jsonFloat число, которое в JSON может принимать значения NaN и ±Inf в виде строк.
+type jsonFloat float64
+
+// MarshalJSON кодирует число, NaN и ±Inf кодируются строками.
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return []byte(strconv.Quote(strconv.FormatFloat(v, 'g', -1, 64))), nil
+	}
+
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON декодирует число или строку с NaN и ±Inf.
+func (f *jsonFloat) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return err
+		}
+
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*f = jsonFloat(v)
+
+		return nil
+	}
+
+	var v float64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*f = jsonFloat(v)
+
+	return nil
+}
+
+// walRecord запись журнала обновлений. Для счетчика хранится приращение.
+type walRecord struct {
+	Seq    uint64            `json:"seq"`
+	Type   domain.MetricType `json:"type"`
+	Name   string            `json:"name"`
+	Labels domain.Labels     `json:"labels,omitempty"`
+	Gauge  jsonFloat         `json:"gauge,omitempty"`
+	Delta  int64             `json:"delta,omitempty"`
+
+	// key ключ ряда, вычисляется при применении записи.
+	key string
+}
+
+// snapshotMetric метрика в снимке, поля совпадают с прежним форматом файла.
+type snapshotMetric struct {
+	Type         domain.MetricType
+	Name         string
+	Labels       domain.Labels
+	CounterValue int64
+	GaugeValue   jsonFloat
+}
+
+// snapshot снимок метрик. Seq номер последней записи журнала, вошедшей в снимок.
+type snapshot struct {
+	Seq     uint64           `json:"seq"`
+	Metrics []snapshotMetric `json:"metrics"`
+}
+
+// readSnapshot читает снимок метрик. Поддерживается и прежний формат файла - массив метрик.
+func readSnapshot(path string) (snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return snapshot{}, nil
+	}
+
+	var snap snapshot
+	if data[0] == '[' {
+		// прежняя версия перезаписывала файл без усечения, поэтому после массива
+		// метрик может остаться хвост предыдущего снимка
+		err = json.NewDecoder(bytes.NewReader(data)).Decode(&snap.Metrics)
+	} else {
+		err = json.Unmarshal(data, &snap)
+	}
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to parse snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// readWAL читает записи журнала по порядку. Поврежденные записи, например
+// недописанные при аварийном завершении, пропускаются, и в конце возвращается ошибка.
+func readWAL(path string, fn func(r walRecord)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxWALRecordSize)
+	line, corrupted := 0, 0
+	for scanner.Scan() {
+		line++
+		var r walRecord
+		if err = json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			corrupted++
+			continue
+		}
+
+		fn(r)
+	}
+
+	// записи после непрочитанной строки были бы потеряны, поэтому это не повреждение записи
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("line %d: %w", line+1, err)
+	}
+
+	if corrupted > 0 {
+		return fmt.Errorf("%w: %d of %d records skipped", errWALCorrupted, corrupted, line)
+	}
+
+	return nil
+}
+
+// walRequest запрос к писателю журнала.
+type walRequest struct {
+	records []walRecord
+	// rotate переносит журнал в сегмент, который удаляется после сохранения снимка.
+	rotate bool
+	// done получает результат, nil если результат не ожидается.
+	done chan error
+}
+
+// walWriter писатель журнала. Записи параллельных запросов записываются
+// и сбрасываются на диск вместе, не задерживая обработку запросов.
+type walWriter struct {
+	path string
+	file *os.File
+	size int64
+	// sync сбрасывать ли каждую запись на диск.
+	sync bool
+	// full вызывается, когда размер журнала превысил walCompactSize.
+	full func()
+
+	reqs    chan walRequest
+	stopped chan struct{}
+}
+
+// openWAL открывает журнал на запись. При truncate прежние записи удаляются.
+func openWAL(path string, truncate, sync bool, full func()) (*walWriter, error) {
+	flags := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if truncate {
+		flags |= os.O_TRUNC
+		if err := os.Remove(path + segmentSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &walWriter{
+		path:    path,
+		file:    file,
+		size:    info.Size(),
+		sync:    sync,
+		full:    full,
+		reqs:    make(chan walRequest, walQueueSize),
+		stopped: make(chan struct{}),
+	}, nil
+}
+
+// run обрабатывает запросы до закрытия очереди.
+func (w *walWriter) run(ctx context.Context) {
+	defer close(w.stopped)
+
+	batch := make([]walRequest, 0, walMaxBatch)
+	for req := range w.reqs {
+		batch = append(batch[:0], req)
+	drain:
+		for len(batch) < walMaxBatch {
+			select {
+			case r, ok := <-w.reqs:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, r)
+			default:
+				break drain
+			}
+		}
+
+		w.process(ctx, batch)
+	}
+
+	if err := w.file.Close(); err != nil {
+		logger.Errorf(ctx, "error close wal: %v", err)
+	}
+}
+
+func (w *walWriter) process(ctx context.Context, batch []walRequest) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	pending := make([]chan error, 0, len(batch))
+	flush := func() {
+		err := w.write(&buf)
+		if err != nil && len(pending) == 0 {
+			logger.Errorf(ctx, "error write wal: %v", err)
+		}
+		for _, done := range pending {
+			done <- err
+		}
+		pending = pending[:0]
+	}
+
+	for _, req := range batch {
+		if req.rotate {
+			flush()
+			req.done <- w.rotate()
+			continue
+		}
+
+		var err error
+		for i := range req.records {
+			if err = enc.Encode(&req.records[i]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if req.done != nil {
+				req.done <- err
+			} else {
+				logger.Errorf(ctx, "error encode wal record: %v", err)
+			}
+			continue
+		}
+
+		if req.done != nil {
+			pending = append(pending, req.done)
+		}
+	}
+
+	flush()
+}
+
+// write дописывает буфер в журнал.
+func (w *walWriter) write(buf *bytes.Buffer) error {
+	if buf.Len() == 0 {
+		return nil
+	}
+	defer buf.Reset()
+
+	if _, err := w.file.Write(buf.Bytes()); err != nil {
+		// недописанная запись испортила бы следующую
+		_ = w.file.Truncate(w.size)
+		return fmt.Errorf("failed to write wal: %w", err)
+	}
+
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			_ = w.file.Truncate(w.size)
+			return fmt.Errorf("failed to sync wal: %w", err)
+		}
+	}
+
+	w.size += int64(buf.Len())
+	if w.size >= walCompactSize {
+		w.full()
+	}
+
+	return nil
+}
+
+// rotate переносит записи журнала в сегмент и начинает новый журнал.
+// Если сегмент остался от неудачного сохранения снимка, записи дописываются в него.
+func (w *walWriter) rotate() error {
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync wal: %w", err)
+	}
+
+	segment := w.path + segmentSuffix
+	if _, err := os.Stat(segment); err == nil {
+		if err = appendFile(segment, w.path); err != nil {
+			return fmt.Errorf("failed to append wal segment: %w", err)
+		}
+	} else if err = os.Rename(w.path, segment); err != nil {
+		return fmt.Errorf("failed to rename wal: %w", err)
+	}
+
+	file, err := os.OpenFile(w.path, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open wal: %w", err)
+	}
+
+	_ = w.file.Close()
+	w.file = file
+	w.size = 0
+
+	return syncDir(filepath.Dir(w.path))
+}
+
+// removeSegment удаляет сегмент журнала, вошедший в сохраненный снимок.
+func (w *walWriter) removeSegment() error {
+	err := os.Remove(w.path + segmentSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// appendFile дописывает содержимое файла src в файл dst.
+func appendFile(dst, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	if err = out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// writeFileAtomic записывает файл через временный файл в том же каталоге,
+// который сбрасывается на диск и переименовывается поверх исходного.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if err = writeAndSync(tmp, data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func writeAndSync(file *os.File, data []byte) error {
+	if err := file.Chmod(0644); err != nil {
+		return err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// syncDir сбрасывает на диск каталог, чтобы переименование файла пережило сбой.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,319 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func sortedValues(t *testing.T, s *Storage) []domain.MetricValue {
+	t.Helper()
+	values, err := s.GetAllValues(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllValues() error = %v", err)
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return historyKey(values[i].Type, values[i].Name, values[i].Labels) <
+			historyKey(values[j].Type, values[j].Name, values[j].Labels)
+	})
+
+	return values
+}
+
+func TestStorage_restore(t *testing.T) {
+	t.Parallel()
+	updates := []domain.MetricValue{
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 1.5},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 2},
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 3.5},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 3,
+			Labels: domain.Labels{"host": "a"}},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 5},
+		{Type: domain.GaugeMetricType, Name: "Inf", GaugeValue: math.Inf(-1)},
+	}
+	want := []domain.MetricValue{
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 7},
+		{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 3,
+			Labels: domain.Labels{"host": "a"}},
+		{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 3.5},
+		{Type: domain.GaugeMetricType, Name: "Inf", GaugeValue: math.Inf(-1)},
+	}
+
+	tests := []struct {
+		name string
+		// close закрывать ли хранилище перед восстановлением, иначе имитируется аварийное завершение
+		close bool
+		// snapshotAfter после скольких обновлений сохранить снимок
+		snapshotAfter int
+	}{
+		{
+			name: "wal only",
+		},
+		{
+			name:          "snapshot and wal",
+			snapshotAfter: 3,
+		},
+		{
+			name:  "closed",
+			close: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "metrics.json")
+			ctx, cancel := context.WithCancel(t.Context())
+			s, err := NewStorage(ctx, path, 0, false)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+
+			for i, u := range updates {
+				if i == tt.snapshotAfter && i > 0 {
+					if err = s.snapshot(ctx); err != nil {
+						t.Fatalf("snapshot() error = %v", err)
+					}
+				}
+				if err = s.UpdateMetrics(ctx, "agent", []domain.MetricValue{u}); err != nil {
+					t.Fatalf("UpdateMetrics() error = %v", err)
+				}
+			}
+
+			if tt.close {
+				s.Close(ctx)
+				info, err := os.Stat(path + walSuffix)
+				if err != nil {
+					t.Fatalf("stat wal error = %v", err)
+				}
+				if info.Size() != 0 {
+					t.Errorf("wal size = %d after close, want 0", info.Size())
+				}
+			}
+			cancel()
+
+			restored, err := NewStorage(t.Context(), path, 0, true)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			defer restored.Close(t.Context())
+
+			got := sortedValues(t, restored)
+			if len(got) != len(want) {
+				t.Fatalf("restored %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i].Type != want[i].Type || got[i].SeriesKey() != want[i].SeriesKey() ||
+					got[i].GaugeValue != want[i].GaugeValue || got[i].CounterValue != want[i].CounterValue {
+					t.Errorf("restored[%d] = %v, want %v", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStorage_restoreFiles(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		snapshot string
+		segment  string
+		wal      string
+		restore  bool
+		want     []domain.MetricValue
+	}{
+		{
+			name:     "legacy snapshot",
+			snapshot: `[{"Type":"counter","Name":"PollCount","CounterValue":10}]`,
+			restore:  true,
+			want: []domain.MetricValue{
+				{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 10},
+			},
+		},
+		{
+			name:     "legacy snapshot with trailing garbage",
+			snapshot: `[{"Type":"counter","Name":"PollCount","CounterValue":10}]alue":5}]`,
+			restore:  true,
+			want: []domain.MetricValue{
+				{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 10},
+			},
+		},
+		{
+			name:     "skip wal records from snapshot",
+			snapshot: `{"seq":2,"metrics":[{"Type":"counter","Name":"PollCount","CounterValue":10}]}`,
+			wal: `{"seq":1,"type":"counter","name":"PollCount","delta":4}
+{"seq":2,"type":"counter","name":"PollCount","delta":6}
+{"seq":3,"type":"counter","name":"PollCount","delta":1}
+`,
+			restore: true,
+			want: []domain.MetricValue{
+				{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 11},
+			},
+		},
+		{
+			name:     "segment left by failed snapshot",
+			snapshot: `{"seq":1,"metrics":[{"Type":"gauge","Name":"Alloc","GaugeValue":"NaN"}]}`,
+			segment: `{"seq":1,"type":"gauge","name":"Alloc","gauge":"NaN"}
+{"seq":2,"type":"gauge","name":"Alloc","gauge":5}
+`,
+			wal:     `{"seq":3,"type":"counter","name":"PollCount","delta":1}`,
+			restore: true,
+			want: []domain.MetricValue{
+				{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 1},
+				{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 5},
+			},
+		},
+		{
+			name: "torn wal record",
+			wal: `{"seq":1,"type":"gauge","name":"Alloc","gauge":2}
+{"seq":2,"type":"counter","name":"Poll`,
+			restore: true,
+			want: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "Alloc", GaugeValue: 2},
+			},
+		},
+		{
+			name:     "without restore",
+			snapshot: `{"seq":1,"metrics":[{"Type":"counter","Name":"PollCount","CounterValue":10}]}`,
+			wal:      `{"seq":2,"type":"counter","name":"PollCount","delta":1}`,
+			restore:  false,
+			want:     []domain.MetricValue{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "metrics.json")
+			if tt.snapshot != "" {
+				if err := os.WriteFile(path, []byte(tt.snapshot), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.segment != "" {
+				if err := os.WriteFile(path+walSuffix+segmentSuffix, []byte(tt.segment), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.wal != "" {
+				if err := os.WriteFile(path+walSuffix, []byte(tt.wal), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			s, err := NewStorage(t.Context(), path, 0, tt.restore)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			defer s.Close(t.Context())
+
+			got := sortedValues(t, s)
+			if len(got) != len(tt.want) {
+				t.Fatalf("restored %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i].Type != tt.want[i].Type || got[i].SeriesKey() != tt.want[i].SeriesKey() ||
+					got[i].GaugeValue != tt.want[i].GaugeValue || got[i].CounterValue != tt.want[i].CounterValue {
+					t.Errorf("restored[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+
+			// после открытия журнал очищен, а снимок содержит восстановленные метрики
+			snap, err := readSnapshot(path)
+			if err != nil {
+				t.Fatalf("readSnapshot() error = %v", err)
+			}
+			if len(snap.Metrics) != len(tt.want) {
+				t.Errorf("snapshot metrics = %v, want %v", snap.Metrics, tt.want)
+			}
+			if info, err := os.Stat(path + walSuffix); err != nil || info.Size() != 0 {
+				t.Errorf("wal is not truncated: %v", err)
+			}
+			if _, err := os.Stat(path + walSuffix + segmentSuffix); !os.IsNotExist(err) {
+				t.Errorf("wal segment is not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestStorage_restoreCorruptSnapshot(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	const wal = `{"seq":3,"type":"counter","name":"PollCount","delta":4}
+{"seq":4,"type":"gauge","name":"Alloc","gauge":2}
+`
+	if err := os.WriteFile(path, []byte(`{"seq":2,"metrics":[{"Type":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+walSuffix, []byte(wal), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewStorage(t.Context(), path, 0, true); err == nil {
+		t.Fatal("NewStorage() with corrupt snapshot error = nil")
+	}
+
+	// журнал не должен удаляться, пока снимок не прочитан
+	data, err := os.ReadFile(path + walSuffix)
+	if err != nil {
+		t.Fatalf("read wal error = %v", err)
+	}
+	if string(data) != wal {
+		t.Errorf("wal = %q after failed restore, want %q", data, wal)
+	}
+
+	// после удаления поврежденного снимка метрики восстанавливаются из журнала
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewStorage(t.Context(), path, 0, true)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	defer s.Close(t.Context())
+
+	counter, err := s.GetCounterValue(t.Context(), "PollCount", nil)
+	if err != nil || counter != 4 {
+		t.Errorf("GetCounterValue() = %d, %v, want 4", counter, err)
+	}
+	gauge, err := s.GetGaugeValue(t.Context(), "Alloc", nil)
+	if err != nil || gauge != 2 {
+		t.Errorf("GetGaugeValue() = %v, %v, want 2", gauge, err)
+	}
+}
+
+func TestStorage_updateWALError(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	s, err := NewStorage(t.Context(), path, 0, false)
+	if err != nil {
+		t.Fatalf("NewStorage() error = %v", err)
+	}
+	defer s.closeWAL()
+
+	counter := domain.MetricValue{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 2}
+	if err = s.UpdateCounter(t.Context(), "agent", counter); err != nil {
+		t.Fatalf("UpdateCounter() error = %v", err)
+	}
+
+	// запись в закрытый файл журнала завершается ошибкой
+	if err = s.wal.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	counter.CounterValue = 3
+	if err = s.UpdateCounter(t.Context(), "agent", counter); err == nil {
+		t.Fatal("UpdateCounter() error = nil, want wal error")
+	}
+
+	got, err := s.GetCounterValue(t.Context(), "PollCount", nil)
+	if err != nil {
+		t.Fatalf("GetCounterValue() error = %v", err)
+	}
+	if got != 2 {
+		t.Errorf("GetCounterValue() = %d, want 2", got)
+	}
+}
@@ -0,0 +1,119 @@
+package memory
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// shardCount количество шардов рядов метрик, степень двойки.
+const shardCount = 64
+
+// series ряд метрики. Имя и метки не меняются, значение обновляется атомарно.
+type series struct {
+	name   string
+	labels domain.Labels
+	// bits биты значения float64 градусника или значение int64 счетчика.
+	bits atomic.Uint64
+	// mu упорядочивает обновления градусника с номерами записей журнала,
+	// чтобы после восстановления ряд получил то же последнее значение.
+	mu sync.Mutex
+}
+
+func (s *series) gauge() float64 {
+	return math.Float64frombits(s.bits.Load())
+}
+
+func (s *series) counter() int64 {
+	return int64(s.bits.Load())
+}
+
+// seriesMap неизменяемый набор рядов шарда.
+type seriesMap map[string]*series
+
+// shard часть рядов метрик. При добавлении ряда набор заменяется копией,
+// поэтому чтение набора и значений рядов не требует блокировок.
+type shard struct {
+	mu     sync.Mutex
+	series atomic.Pointer[seriesMap]
+}
+
+// load возвращает текущий набор рядов шарда.
+func (sh *shard) load() seriesMap {
+	m := sh.series.Load()
+	if m == nil {
+		return nil
+	}
+
+	return *m
+}
+
+// get возвращает ряд по ключу или nil.
+func (sh *shard) get(key string) *series {
+	return sh.load()[key]
+}
+
+// getOrCreate возвращает ряд по ключу, создавая его при отсутствии с начальным значением bits.
+func (sh *shard) getOrCreate(key, name string, labels domain.Labels, bits uint64) *series {
+	if s := sh.get(key); s != nil {
+		return s
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	old := sh.load()
+	if s, exist := old[key]; exist {
+		return s
+	}
+
+	m := make(seriesMap, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	s := &series{
+		name:   name,
+		labels: labels.Copy(),
+	}
+	s.bits.Store(bits)
+	m[key] = s
+	sh.series.Store(&m)
+
+	return s
+}
+
+// shards набор шардов рядов одного типа.
+type shards [shardCount]shard
+
+// of возвращает шард ряда с ключом key.
+func (s *shards) of(key string) *shard {
+	return &s[shardIndex(key)]
+}
+
+// load возвращает текущие наборы рядов всех шардов.
+func (s *shards) load() [shardCount]seriesMap {
+	var res [shardCount]seriesMap
+	for i := range s {
+		res[i] = s[i].load()
+	}
+
+	return res
+}
+
+// shardIndex номер шарда по хешу FNV-1a ключа.
+func shardIndex(key string) int {
+	const (
+		offset = 2166136261
+		prime  = 16777619
+	)
+
+	h := uint32(offset)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= prime
+	}
+
+	return int(h & (shardCount - 1))
+}
@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/graceful"
+)
+
+// TestStorage_gracefulShutdown проверяет, что метрики, прием которых подтвержден клиенту
+// до остановки сервера, сохраняются при закрытии хранилища.
+func TestStorage_gracefulShutdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		period time.Duration
+	}{
+		{
+			name: "wal sync",
+		},
+		{
+			name:   "periodic snapshot",
+			period: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "metrics.json")
+			s, err := NewStorage(t.Context(), path, tt.period, false)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			url := "http://" + ln.Addr().String()
+
+			var handled atomic.Int64
+			server := &http.Server{
+				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					// обработка не прерывается остановкой сервера
+					time.Sleep(time.Millisecond)
+					err := s.UpdateMetrics(r.Context(), "agent", []domain.MetricValue{
+						{Type: domain.CounterMetricType, Name: "PollCount", CounterValue: 1},
+					})
+					if err != nil {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					handled.Add(1)
+					w.WriteHeader(http.StatusOK)
+				}),
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			done := make(chan error, 1)
+			go func() {
+				done <- graceful.Serve(ctx, server, func() error { return server.Serve(ln) }, 5*time.Second)
+			}()
+
+			client := &http.Client{Timeout: 5 * time.Second}
+			var accepted atomic.Int64
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						resp, err := client.Post(url, "text/plain", nil)
+						if err != nil {
+							// сервер остановлен и больше не принимает запросы
+							return
+						}
+						_ = resp.Body.Close()
+						if resp.StatusCode == http.StatusOK {
+							accepted.Add(1)
+						}
+					}
+				}()
+			}
+
+			for handled.Load() < 50 {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+
+			if err = <-done; err != nil {
+				t.Fatalf("Serve() error = %v", err)
+			}
+			wg.Wait()
+			s.Close(t.Context())
+
+			restored, err := NewStorage(t.Context(), path, 0, true)
+			if err != nil {
+				t.Fatalf("NewStorage() error = %v", err)
+			}
+			defer restored.Close(t.Context())
+
+			got := sortedValues(t, restored)
+			if len(got) != 1 {
+				t.Fatalf("restored %v, want single PollCount", got)
+			}
+			if got[0].CounterValue != accepted.Load() {
+				t.Errorf("restored PollCount = %d, want %d accepted", got[0].CounterValue, accepted.Load())
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"os"
+	"testing"
+
+	"github.com/kdv2001/onlyMetrics/internal/storage/metrics/storagetest"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+)
+
+// testDSNKey переменная окружения с DSN базы для тестов, все данные в базе удаляются.
+const testDSNKey = "TEST_DATABASE_DSN"
+
+func TestStorage_conformance(t *testing.T) {
+	dsn := os.Getenv(testDSNKey)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNKey)
+	}
+
+	pool, err := NewPool(t.Context(), dsn, PoolConfig{MaxConns: 10})
+	if err != nil {
+		t.Fatalf("NewPool() error = %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	storagetest.Run(t, func(t *testing.T) metrics.MetricStorage {
+		s, err := NewStorage(t.Context(), pool)
+		if err != nil {
+			t.Fatalf("NewStorage() error = %v", err)
+		}
+
+		_, err = pool.Exec(t.Context(), `truncate values, values_1m, values_1h,
+			counter_totals, gauge_latest, agents;`)
+		if err != nil {
+			t.Fatalf("truncate tables error = %v", err)
+		}
+
+		return s
+	})
+}
@@ -4,236 +4,537 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
-	"github.com/kdv2001/onlyMetrics/pkg/logger"
 )
 
 // Storage хранилище метрик.
 type Storage struct {
-	dbConn *pgx.Conn
+	dbPool *pgxpool.Pool
+
+	queryTimeout time.Duration
+	// backoff задержки перед повторами запроса при временных ошибках.
+	backoff []time.Duration
+
+	maintenance MaintenanceConfig
+}
+
+// storageOption опция хранилища.
+type storageOption func(s *Storage)
+
+// WithMaintenanceOpt включает периодическое обслуживание таблиц значений.
+func WithMaintenanceOpt(cfg MaintenanceConfig) storageOption {
+	return func(s *Storage) {
+		s.maintenance = cfg
+	}
+}
+
+// WithQueryTimeoutOpt ограничивает время выполнения одной попытки запроса.
+func WithQueryTimeoutOpt(timeout time.Duration) storageOption {
+	return func(s *Storage) {
+		s.queryTimeout = timeout
+	}
 }
 
 // NewStorage создает объект хранилища.
-func NewStorage(ctx context.Context, conn *pgx.Conn) (*Storage, error) {
+func NewStorage(ctx context.Context, pool *pgxpool.Pool, opts ...storageOption) (*Storage, error) {
 	s := &Storage{
-		dbConn: conn,
+		dbPool:  pool,
+		backoff: defaultBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
 
-	err := s.createTables(ctx)
+	if err := s.maintenance.validate(); err != nil {
+		return nil, fmt.Errorf("invalid maintenance config: %w", err)
+	}
+
+	_, err := retry(ctx, s.backoff, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.migrate(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	s.asyncMaintenance(ctx)
+
 	return s, nil
 }
 
-func (s *Storage) createTables(ctx context.Context) error {
-	tx, err := s.dbConn.Begin(ctx)
+// migrate применяет миграции схемы БД, если схема БД не новее известной приложению.
+func (s *Storage) migrate(ctx context.Context) error {
+	migrator, err := NewMigrator(s.dbPool)
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(ctx, metricValuesTable)
-	if err != nil {
+
+	if err = migrator.Check(ctx); err != nil {
 		return err
 	}
 
-	return tx.Commit(ctx)
+	return migrator.Up(ctx)
 }
 
 // Close безопасно закрывает хранилище.
-func (s *Storage) Close(ctx context.Context) {
-	err := s.dbConn.Close(ctx)
-	if err != nil {
-		logger.Errorf(ctx, "error close db conn")
-	}
+func (s *Storage) Close(_ context.Context) {
+	s.dbPool.Close()
 }
 
 // Ping проверяет работоспособность хранилища.
 func (s *Storage) Ping(ctx context.Context) error {
-	return s.dbConn.Ping(ctx)
+	return s.dbPool.Ping(ctx)
 }
 
-// UpdateGauge обновляет метрику типа "Градусник".
-func (s *Storage) UpdateGauge(ctx context.Context, value domain.MetricValue) error {
-	_, err := s.dbConn.Exec(ctx, `insert into values (metric_name, gauge_value, agent_name, created_at)
-values ($1,   $2, $3, $4);`,
-		value.Name, value.GaugeValue, "single agent", time.Now().UTC())
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code) {
-			return domain.ErrResourceIsLocked
+// UpdateGauge обновляет метрику типа "Градусник", присланную агентом.
+func (s *Storage) UpdateGauge(ctx context.Context, agent string, value domain.MetricValue) error {
+	return s.UpdateMetrics(ctx, agent, []domain.MetricValue{value})
+}
+
+// UpdateCounter обновляет метрику типа "Счетчик", присланную агентом.
+func (s *Storage) UpdateCounter(ctx context.Context, agent string, value domain.MetricValue) error {
+	return s.UpdateMetrics(ctx, agent, []domain.MetricValue{value})
+}
+
+const (
+	insertGaugeQuery = `insert into values (metric_name, labels, gauge_value, agent_name, created_at)
+values ($1, $2::jsonb, $3, $4, $5);`
+	insertCounterQuery = `insert into values (metric_name, labels, counter_value, agent_name, created_at)
+values ($1, $2::jsonb, $3, $4, $5);`
+	upsertGaugeLatestQuery = `insert into gauge_latest (metric_name, labels, value, updated_at)
+values ($1, $2::jsonb, $3, $4)
+on conflict (metric_name, labels) do update set value = excluded.value, updated_at = excluded.updated_at;`
+	upsertCounterTotalQuery = `insert into counter_totals (metric_name, labels, value, updated_at)
+values ($1, $2::jsonb, $3, $4)
+on conflict (metric_name, labels) do update
+set value = counter_totals.value + excluded.value, updated_at = excluded.updated_at;`
+	touchAgentQuery = `insert into agents (name, last_seen) values ($1, $2)
+on conflict (name) do update set last_seen = greatest(agents.last_seen, excluded.last_seen);`
+)
+
+// seriesUpdate обновление текущего значения ряда метрики.
+type seriesUpdate struct {
+	name   string
+	labels string
+	gauge  float64
+	delta  int64
+}
+
+// newUpdateBatch формирует пакет запросов для сохранения значений метрик, присланных агентом.
+// Текущие значения рядов обновляются по одному разу на ряд в порядке ключей рядов,
+// чтобы параллельные транзакции блокировали строки в одном порядке и не взаимоблокировались.
+func newUpdateBatch(agent string, metrics []domain.MetricValue, now time.Time) (*pgx.Batch, error) {
+	batch := &pgx.Batch{}
+	gauges := make(map[string]seriesUpdate)
+	counters := make(map[string]seriesUpdate)
+	for _, metric := range metrics {
+		labels, err := marshalLabels(metric.Labels)
+		if err != nil {
+			return nil, err
+		}
+
+		key := metric.SeriesKey()
+		switch metric.Type {
+		case domain.GaugeMetricType:
+			batch.Queue(insertGaugeQuery, metric.Name, labels, metric.GaugeValue, agent, now)
+			gauges[key] = seriesUpdate{
+				name:   metric.Name,
+				labels: labels,
+				gauge:  metric.GaugeValue,
+			}
+		case domain.CounterMetricType:
+			batch.Queue(insertCounterQuery, metric.Name, labels, metric.CounterValue, agent, now)
+			counters[key] = seriesUpdate{
+				name:   metric.Name,
+				labels: labels,
+				delta:  counters[key].delta + metric.CounterValue,
+			}
 		}
-		return err
 	}
 
-	return nil
+	for _, key := range sortedKeys(gauges) {
+		u := gauges[key]
+		batch.Queue(upsertGaugeLatestQuery, u.name, u.labels, u.gauge, now)
+	}
+
+	for _, key := range sortedKeys(counters) {
+		u := counters[key]
+		batch.Queue(upsertCounterTotalQuery, u.name, u.labels, u.delta, now)
+	}
+
+	batch.Queue(touchAgentQuery, agent, now)
+
+	return batch, nil
 }
 
-// UpdateCounter обновляет метрику типа "Счетчик".
-func (s *Storage) UpdateCounter(ctx context.Context, value domain.MetricValue) error {
-	_, err := s.dbConn.Exec(ctx, `insert into values (metric_name, counter_value, agent_name, created_at) 
-values ($1,   $2, $3, $4);`,
-		value.Name, value.CounterValue, "single agent", time.Now().UTC())
-	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code) {
-			return domain.ErrResourceIsLocked
-		}
-		return err
+func sortedKeys(m map[string]seriesUpdate) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	return nil
+	return keys
+}
+
+// convertError приводит ошибку postgres к доменной.
+func convertError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code) {
+		return domain.ErrResourceIsLocked
+	}
+
+	return err
 }
 
 // metricValue postgres представление метрики.
 type metricValue struct {
 	ID           sql.NullInt64   `db:"id"`
 	MetricName   sql.NullString  `db:"metric_name"`
+	Labels       []byte          `db:"labels"`
 	GaugeValue   sql.NullFloat64 `db:"gauge_value"`
 	CounterValue sql.NullInt64   `db:"counter_value"`
 	AgentName    sql.NullString  `db:"agent_name"`
 	CreatedAt    sql.NullTime    `db:"created_at"`
 }
 
+// marshalLabels возвращает jsonb представление меток, пустой набор хранится как {}.
+func marshalLabels(labels domain.Labels) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// unmarshalLabels разбирает jsonb представление меток.
+func unmarshalLabels(data []byte) (domain.Labels, error) {
+	var labels domain.Labels
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	return labels, nil
+}
+
 // GetGaugeValue возвращает значение метрики типа "Градусник".
-func (s *Storage) GetGaugeValue(ctx context.Context, name string) (float64, error) {
-	res := new(metricValue)
-	err := s.dbConn.QueryRow(ctx,
-		`select * from values where metric_name = $1 order by created_at desc limit 1;`,
-		name).Scan(&res.ID, &res.MetricName, &res.GaugeValue, &res.CounterValue, &res.AgentName, &res.CreatedAt)
+func (s *Storage) GetGaugeValue(ctx context.Context, name string, labels domain.Labels) (float64, error) {
+	return withRetry(ctx, s, func(ctx context.Context) (float64, error) {
+		return s.getGaugeValue(ctx, name, labels)
+	})
+}
+
+func (s *Storage) getGaugeValue(ctx context.Context, name string, labels domain.Labels) (float64, error) {
+	labelsJSON, err := marshalLabels(labels)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		switch {
-		case errors.Is(err, pgx.ErrNoRows):
+		return 0, err
+	}
+
+	res := sql.NullFloat64{}
+	err = s.dbPool.QueryRow(ctx,
+		`select value from gauge_latest where metric_name = $1 and labels = $2::jsonb;`,
+		name, labelsJSON).Scan(&res)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, domain.ErrNotFound
-		case errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code):
-			return 0, domain.ErrResourceIsLocked
 		}
 
-		return 0, err
+		return 0, convertError(err)
 	}
 
-	return res.GaugeValue.Float64, nil
+	return res.Float64, nil
 }
 
 // GetCounterValue возвращает значение метрики типа "Счетчик".
-func (s *Storage) GetCounterValue(ctx context.Context, name string) (int64, error) {
+func (s *Storage) GetCounterValue(ctx context.Context, name string, labels domain.Labels) (int64, error) {
+	return withRetry(ctx, s, func(ctx context.Context) (int64, error) {
+		return s.getCounterValue(ctx, name, labels)
+	})
+}
+
+func (s *Storage) getCounterValue(ctx context.Context, name string, labels domain.Labels) (int64, error) {
+	labelsJSON, err := marshalLabels(labels)
+	if err != nil {
+		return 0, err
+	}
+
 	res := sql.NullInt64{}
-	err := s.dbConn.QueryRow(ctx,
-		`select sum(counter_value)  as counter_value from values where metric_name = $1;`,
-		name).Scan(&res)
+	err = s.dbPool.QueryRow(ctx,
+		`select value from counter_totals where metric_name = $1 and labels = $2::jsonb;`,
+		name, labelsJSON).Scan(&res)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		switch {
-		case errors.Is(err, pgx.ErrNoRows):
+		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, domain.ErrNotFound
-		case errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code):
-			return 0, domain.ErrResourceIsLocked
 		}
 
-		return 0, err
+		return 0, convertError(err)
 	}
 
 	return res.Int64, nil
 }
 
-// GetAllValues возвращает все метрики из хранилища.
-func (s *Storage) GetAllValues(ctx context.Context) ([]domain.MetricValue, error) {
-	rowsGauge, err := s.dbConn.Query(ctx, `
-select metric_name, gauge_value
-from values
-where (metric_name, created_at) in
-      (select metric_name, max(created_at)
-       from values
-       group by values.metric_name) and gauge_value notnull;
-`)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		var pgErr *pgconn.PgError
-		switch {
-		case errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code):
-			return nil, domain.ErrResourceIsLocked
-		}
+// GetAllValues возвращает все метрики из хранилища, метки которых удовлетворяют условиям.
+func (s *Storage) GetAllValues(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	return withRetry(ctx, s, func(ctx context.Context) ([]domain.MetricValue, error) {
+		return s.getAllValues(ctx, matchers...)
+	})
+}
 
-		return nil, err
+func (s *Storage) getAllValues(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	rowsGauge, err := s.dbPool.Query(ctx, `select metric_name, labels, value from gauge_latest;`)
+	if err != nil {
+		return nil, convertError(err)
 	}
 
-	// 29 максимальное кол-во уникальных метрик
-	res := make([]domain.MetricValue, 0, 29)
+	res := make([]domain.MetricValue, 0)
 	for rowsGauge.Next() {
 		mv := new(metricValue)
-		err = rowsGauge.Scan(&mv.MetricName, &mv.GaugeValue)
+		err = rowsGauge.Scan(&mv.MetricName, &mv.Labels, &mv.GaugeValue)
+		if err != nil {
+			rowsGauge.Close()
+			return nil, err
+		}
+
+		labels, err := unmarshalLabels(mv.Labels)
 		if err != nil {
+			rowsGauge.Close()
 			return nil, err
 		}
 
+		if !domain.MatchLabels(labels, matchers...) {
+			continue
+		}
+
 		res = append(res, domain.MetricValue{
 			Type:       domain.GaugeMetricType,
 			Name:       mv.MetricName.String,
+			Labels:     labels,
 			GaugeValue: mv.GaugeValue.Float64,
 		})
 	}
 	rowsGauge.Close()
+	if err = rowsGauge.Err(); err != nil {
+		return nil, convertError(err)
+	}
 
-	rowsCounter, err := s.dbConn.Query(ctx, `
-select metric_name, sum(counter_value) as counter_value
-from values where counter_value notnull
-group by metric_name;
-`)
-	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-		var pgErr *pgconn.PgError
-		switch {
-		case errors.As(err, &pgErr) && pgerrcode.IsInvalidTransactionInitiation(pgErr.Code):
-			return nil, domain.ErrResourceIsLocked
-		}
-
-		return nil, err
+	rowsCounter, err := s.dbPool.Query(ctx, `select metric_name, labels, value from counter_totals;`)
+	if err != nil {
+		return nil, convertError(err)
 	}
 
 	for rowsCounter.Next() {
 		mv := new(metricValue)
-		err = rowsCounter.Scan(&mv.MetricName, &mv.CounterValue)
+		err = rowsCounter.Scan(&mv.MetricName, &mv.Labels, &mv.CounterValue)
+		if err != nil {
+			rowsCounter.Close()
+			return nil, err
+		}
+
+		labels, err := unmarshalLabels(mv.Labels)
 		if err != nil {
+			rowsCounter.Close()
 			return nil, err
 		}
 
+		if !domain.MatchLabels(labels, matchers...) {
+			continue
+		}
+
 		res = append(res, domain.MetricValue{
 			Type:         domain.CounterMetricType,
 			Name:         mv.MetricName.String,
+			Labels:       labels,
 			CounterValue: mv.CounterValue.Int64,
 		})
 	}
 	rowsCounter.Close()
-
-	if len(res) == 0 {
-		return nil, domain.ErrNotFound
+	if err = rowsCounter.Err(); err != nil {
+		return nil, convertError(err)
 	}
 
 	return res, nil
 }
 
-// UpdateMetrics обновляет значения переданных метрик.
-func (s *Storage) UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error {
-	for _, metric := range metrics {
-		switch metric.Type {
-		case domain.GaugeMetricType:
-			err := s.UpdateGauge(ctx, metric)
-			if err != nil {
-				return err
-			}
-		case domain.CounterMetricType:
-			err := s.UpdateCounter(ctx, metric)
-			if err != nil {
-				return err
-			}
+// GetSamples возвращает значения ряда метрики за период запроса, объединенные по шагу запроса.
+// Устаревшие значения берутся из минутных и часовых агрегатов.
+// Шаги отсчитываются от начала периода, агрегаты упорядочены по времени.
+func (s *Storage) GetSamples(ctx context.Context, q domain.RangeQuery) ([]domain.Sample, error) {
+	return withRetry(ctx, s, func(ctx context.Context) ([]domain.Sample, error) {
+		return s.getSamples(ctx, q)
+	})
+}
+
+func (s *Storage) getSamples(ctx context.Context, q domain.RangeQuery) ([]domain.Sample, error) {
+	labels, err := marshalLabels(q.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	valueColumn := "gauge_value"
+	isCounter := q.Type == domain.CounterMetricType
+	if isCounter {
+		valueColumn = "counter_value"
+	}
+
+	rows, err := s.dbPool.Query(ctx, `
+with samples as (
+    select created_at as ts, 1::bigint as count, `+valueColumn+`::double precision as sum,
+           `+valueColumn+`::double precision as min, `+valueColumn+`::double precision as max,
+           `+valueColumn+`::double precision as last
+    from values
+    where metric_name = $1 and labels = $2::jsonb and `+valueColumn+` notnull
+      and created_at >= $3::timestamp and created_at < $4::timestamp
+    union all
+    select bucket, count, sum, min, max, last
+    from `+minuteRollup+`
+    where metric_name = $1 and labels = $2::jsonb and is_counter = $6
+      and bucket >= $3::timestamp and bucket < $4::timestamp
+    union all
+    select bucket, count, sum, min, max, last
+    from `+hourRollup+`
+    where metric_name = $1 and labels = $2::jsonb and is_counter = $6
+      and bucket >= $3::timestamp and bucket < $4::timestamp
+), bucketed as (
+    select *, floor(extract(epoch from ts - $3::timestamp)::double precision / $5::double precision) as step
+    from samples
+)
+select $3::timestamp + make_interval(secs => step * $5::double precision) as ts,
+       sum(count)::bigint, sum(sum), min(min), max(max),
+       (array_agg(last order by ts desc))[1]
+from bucketed
+group by step
+order by step;`,
+		q.Name, labels, q.Start.UTC(), q.End.UTC(), q.Step.Seconds(), isCounter)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	defer rows.Close()
+
+	res := make([]domain.Sample, 0)
+	for rows.Next() {
+		var sample domain.Sample
+		err = rows.Scan(&sample.Timestamp, &sample.Count, &sample.Sum,
+			&sample.Min, &sample.Max, &sample.Last)
+		if err != nil {
+			return nil, err
 		}
+
+		sample.Timestamp = sample.Timestamp.UTC()
+		res = append(res, sample)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, convertError(err)
+	}
+
+	return res, nil
+}
+
+// UpdateMetrics обновляет значения переданных метрик, присланных агентом.
+// Значения сохраняются одним пакетом запросов в одной транзакции: либо все, либо ни одного.
+func (s *Storage) UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error {
+	_, err := withRetry(ctx, s, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.updateMetrics(ctx, agent, metrics)
+	})
+
+	return err
+}
+
+func (s *Storage) updateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error {
+	batch, err := newUpdateBatch(agent, metrics, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return convertError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	if err = tx.SendBatch(ctx, batch).Close(); err != nil {
+		return convertError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return convertError(err)
 	}
 
 	return nil
 }
+
+// GetAgents возвращает агентов, присылавших метрики.
+func (s *Storage) GetAgents(ctx context.Context) ([]domain.Agent, error) {
+	return withRetry(ctx, s, func(ctx context.Context) ([]domain.Agent, error) {
+		return s.getAgents(ctx)
+	})
+}
+
+func (s *Storage) getAgents(ctx context.Context) ([]domain.Agent, error) {
+	rows, err := s.dbPool.Query(ctx, `select name, last_seen from agents order by name;`)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	defer rows.Close()
+
+	res := make([]domain.Agent, 0)
+	for rows.Next() {
+		var a domain.Agent
+		if err = rows.Scan(&a.Name, &a.LastSeen); err != nil {
+			return nil, err
+		}
+
+		a.LastSeen = a.LastSeen.UTC()
+		res = append(res, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, convertError(err)
+	}
+
+	return res, nil
+}
+
+// GetAgent возвращает агента по имени.
+func (s *Storage) GetAgent(ctx context.Context, name string) (domain.Agent, error) {
+	return withRetry(ctx, s, func(ctx context.Context) (domain.Agent, error) {
+		return s.getAgent(ctx, name)
+	})
+}
+
+func (s *Storage) getAgent(ctx context.Context, name string) (domain.Agent, error) {
+	a := domain.Agent{Name: name}
+	err := s.dbPool.QueryRow(ctx, `select last_seen from agents where name = $1;`, name).Scan(&a.LastSeen)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return domain.Agent{}, domain.ErrNotFound
+		}
+
+		return domain.Agent{}, convertError(err)
+	}
+
+	a.LastSeen = a.LastSeen.UTC()
+	return a, nil
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func Test_newUpdateBatch(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	type query struct {
+		sql  string
+		args []any
+	}
+	tests := []struct {
+		name    string
+		metrics []domain.MetricValue
+		want    []query
+	}{
+		{
+			name:    "empty",
+			metrics: nil,
+			want: []query{
+				{sql: touchAgentQuery, args: []any{"agent", now}},
+			},
+		},
+		{
+			name: "series updated once in key order",
+			metrics: []domain.MetricValue{
+				{Type: domain.GaugeMetricType, Name: "b", GaugeValue: 1},
+				{Type: domain.CounterMetricType, Name: "c", CounterValue: 2},
+				{Type: domain.GaugeMetricType, Name: "a", Labels: domain.Labels{"host": "x"}, GaugeValue: 3},
+				{Type: domain.GaugeMetricType, Name: "b", GaugeValue: 4},
+				{Type: domain.CounterMetricType, Name: "c", CounterValue: 5},
+			},
+			want: []query{
+				{sql: insertGaugeQuery, args: []any{"b", "{}", float64(1), "agent", now}},
+				{sql: insertCounterQuery, args: []any{"c", "{}", int64(2), "agent", now}},
+				{sql: insertGaugeQuery, args: []any{"a", `{"host":"x"}`, float64(3), "agent", now}},
+				{sql: insertGaugeQuery, args: []any{"b", "{}", float64(4), "agent", now}},
+				{sql: insertCounterQuery, args: []any{"c", "{}", int64(5), "agent", now}},
+				{sql: upsertGaugeLatestQuery, args: []any{"a", `{"host":"x"}`, float64(3), now}},
+				{sql: upsertGaugeLatestQuery, args: []any{"b", "{}", float64(4), now}},
+				{sql: upsertCounterTotalQuery, args: []any{"c", "{}", int64(7), now}},
+				{sql: touchAgentQuery, args: []any{"agent", now}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			batch, err := newUpdateBatch("agent", tt.metrics, now)
+			if err != nil {
+				t.Fatalf("newUpdateBatch() error = %v", err)
+			}
+
+			got := make([]query, 0, batch.Len())
+			for _, q := range batch.QueuedQueries {
+				got = append(got, query{sql: q.SQL, args: q.Arguments})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newUpdateBatch() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// maintenanceLockKey ключ advisory блокировки обслуживания, чтобы его выполнял только один экземпляр сервера.
+const maintenanceLockKey int64 = 0x6f6e6c794d657472
+
+// MaintenanceConfig настройки обслуживания таблиц значений.
+// Значения старше RawRetention сворачиваются в минутные агрегаты,
+// минутные агрегаты старше MinuteRetention сворачиваются в часовые,
+// часовые агрегаты старше Retention удаляются.
+type MaintenanceConfig struct {
+	// Interval период обслуживания, нулевой период отключает обслуживание.
+	Interval        time.Duration
+	RawRetention    time.Duration
+	MinuteRetention time.Duration
+	Retention       time.Duration
+}
+
+func (c MaintenanceConfig) validate() error {
+	if c.Interval <= 0 {
+		return nil
+	}
+
+	if c.RawRetention < time.Minute {
+		return errors.New("raw retention must be at least a minute")
+	}
+
+	if c.MinuteRetention < c.RawRetention+time.Hour {
+		return errors.New("minute retention must exceed raw retention by at least an hour")
+	}
+
+	if c.Retention < c.MinuteRetention {
+		return errors.New("retention must not be less than minute retention")
+	}
+
+	return nil
+}
+
+// maintenanceCutoffs границы обслуживания.
+type maintenanceCutoffs struct {
+	// raw значения до этого момента сворачиваются в минутные агрегаты.
+	raw time.Time
+	// minute минутные агрегаты до этого момента сворачиваются в часовые.
+	minute time.Time
+	// retention часовые агрегаты до этого момента удаляются.
+	retention time.Time
+}
+
+// cutoffs возвращает границы обслуживания, выровненные по интервалам агрегатов,
+// чтобы агрегат интервала не собирался частями за несколько запусков.
+func (c MaintenanceConfig) cutoffs(now time.Time) maintenanceCutoffs {
+	now = now.UTC()
+	return maintenanceCutoffs{
+		raw:       now.Add(-c.RawRetention).Truncate(time.Minute),
+		minute:    now.Add(-c.MinuteRetention).Truncate(time.Hour),
+		retention: now.Add(-c.Retention).Truncate(time.Hour),
+	}
+}
+
+func (s *Storage) asyncMaintenance(ctx context.Context) {
+	if s.maintenance.Interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.maintenance.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				_, err := retry(ctx, s.backoff, func(ctx context.Context) (struct{}, error) {
+					return struct{}{}, s.maintain(ctx, now)
+				})
+				if err != nil {
+					logger.Errorf(ctx, "error maintain values: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// maintain сворачивает устаревшие значения в агрегаты и удаляет агрегаты старше срока хранения.
+// Если обслуживание уже выполняет другой экземпляр сервера, запуск пропускается.
+func (s *Storage) maintain(ctx context.Context, now time.Time) error {
+	tx, err := s.dbPool.Begin(ctx)
+	if err != nil {
+		return convertError(err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var locked bool
+	err = tx.QueryRow(ctx, `select pg_try_advisory_xact_lock($1);`, maintenanceLockKey).Scan(&locked)
+	if err != nil {
+		return convertError(err)
+	}
+	if !locked {
+		logger.Infof(ctx, "skip values maintenance: locked by another instance")
+		return nil
+	}
+
+	cutoffs := s.maintenance.cutoffs(now)
+
+	rawTag, err := tx.Exec(ctx, fmt.Sprintf(`
+insert into %s (metric_name, labels, is_counter, bucket, count, sum, min, max, last)
+select metric_name, labels, counter_value notnull, date_trunc('minute', created_at),
+       count(*), sum(coalesce(gauge_value, counter_value)),
+       min(coalesce(gauge_value, counter_value)), max(coalesce(gauge_value, counter_value)),
+       (array_agg(coalesce(gauge_value, counter_value) order by created_at desc))[1]
+from values
+where created_at < $1::timestamp
+group by metric_name, labels, counter_value notnull, date_trunc('minute', created_at)
+%s`, minuteRollup, rollupConflict(minuteRollup)), cutoffs.raw)
+	if err != nil {
+		return convertError(err)
+	}
+
+	_, err = tx.Exec(ctx, `delete from values where created_at < $1::timestamp;`, cutoffs.raw)
+	if err != nil {
+		return convertError(err)
+	}
+
+	minuteTag, err := tx.Exec(ctx, fmt.Sprintf(`
+insert into %s (metric_name, labels, is_counter, bucket, count, sum, min, max, last)
+select metric_name, labels, is_counter, date_trunc('hour', bucket),
+       sum(count), sum(sum), min(min), max(max),
+       (array_agg(last order by bucket desc))[1]
+from %s
+where bucket < $1::timestamp
+group by metric_name, labels, is_counter, date_trunc('hour', bucket)
+%s`, hourRollup, minuteRollup, rollupConflict(hourRollup)), cutoffs.minute)
+	if err != nil {
+		return convertError(err)
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(`delete from %s where bucket < $1::timestamp;`, minuteRollup), cutoffs.minute)
+	if err != nil {
+		return convertError(err)
+	}
+
+	hourTag, err := tx.Exec(ctx, fmt.Sprintf(`delete from %s where bucket < $1::timestamp;`, hourRollup), cutoffs.retention)
+	if err != nil {
+		return convertError(err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return convertError(err)
+	}
+
+	logger.Infof(ctx, "values maintenance: %d minute rollups, %d hour rollups, %d expired",
+		rawTag.RowsAffected(), minuteTag.RowsAffected(), hourTag.RowsAffected())
+	return nil
+}
+
+// rollupConflict объединяет новый агрегат с уже сохраненным агрегатом того же интервала.
+// Новый агрегат всегда содержит более поздние значения.
+func rollupConflict(table string) string {
+	return fmt.Sprintf(`on conflict (metric_name, labels, is_counter, bucket) do update
+set count = %[1]s.count + excluded.count,
+    sum   = %[1]s.sum + excluded.sum,
+    min   = least(%[1]s.min, excluded.min),
+    max   = greatest(%[1]s.max, excluded.max),
+    last  = excluded.last;`, table)
+}
@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceConfig_validate(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		cfg     MaintenanceConfig
+		wantErr bool
+	}{
+		{
+			name:    "disabled",
+			cfg:     MaintenanceConfig{},
+			wantErr: false,
+		},
+		{
+			name: "valid",
+			cfg: MaintenanceConfig{
+				Interval:        time.Minute,
+				RawRetention:    24 * time.Hour,
+				MinuteRetention: 7 * 24 * time.Hour,
+				Retention:       90 * 24 * time.Hour,
+			},
+			wantErr: false,
+		},
+		{
+			name: "raw retention too short",
+			cfg: MaintenanceConfig{
+				Interval:        time.Minute,
+				RawRetention:    time.Second,
+				MinuteRetention: 7 * 24 * time.Hour,
+				Retention:       90 * 24 * time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "minute retention less than raw",
+			cfg: MaintenanceConfig{
+				Interval:        time.Minute,
+				RawRetention:    24 * time.Hour,
+				MinuteRetention: time.Hour,
+				Retention:       90 * 24 * time.Hour,
+			},
+			wantErr: true,
+		},
+		{
+			name: "retention less than minute",
+			cfg: MaintenanceConfig{
+				Interval:        time.Minute,
+				RawRetention:    24 * time.Hour,
+				MinuteRetention: 7 * 24 * time.Hour,
+				Retention:       48 * time.Hour,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if err := tt.cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaintenanceConfig_cutoffs(t *testing.T) {
+	t.Parallel()
+	cfg := MaintenanceConfig{
+		Interval:        time.Minute,
+		RawRetention:    24 * time.Hour,
+		MinuteRetention: 7 * 24 * time.Hour,
+		Retention:       90 * 24 * time.Hour,
+	}
+	now := time.Date(2024, 3, 31, 12, 34, 56, 0, time.FixedZone("MSK", 3*60*60))
+
+	got := cfg.cutoffs(now)
+	want := maintenanceCutoffs{
+		raw:       time.Date(2024, 3, 30, 9, 34, 0, 0, time.UTC),
+		minute:    time.Date(2024, 3, 24, 9, 0, 0, 0, time.UTC),
+		retention: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if got != want {
+		t.Errorf("cutoffs() got = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,332 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migrationsLockKey ключ advisory блокировки применения миграций.
+const migrationsLockKey int64 = 0x6f6e6c794d696772
+
+// ErrSchemaAhead ошибка версия схемы БД новее версии, известной приложению.
+var ErrSchemaAhead = errors.New("database schema is ahead of the binary")
+
+const schemaMigrationsTable = `
+create table if not exists schema_migrations (
+    version    bigint                      primary key,
+    name       varchar                     NOT NULL,
+    applied_at timestamp WITHOUT TIME ZONE NOT NULL DEFAULT (NOW() AT TIME ZONE 'UTC')
+);`
+
+// migration версия схемы БД.
+type migration struct {
+	version int64
+	name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus состояние миграции.
+type MigrationStatus struct {
+	Version int64
+	Name    string
+	// AppliedAt время применения, нулевое для неприменённой миграции.
+	AppliedAt time.Time
+}
+
+// loadMigrations загружает упорядоченные по версии миграции из файлов вида 0001_name.up.sql и 0001_name.down.sql.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("invalid migration file name %q", base)
+		}
+
+		versionStr, name, found := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		if !found {
+			return nil, fmt.Errorf("invalid migration file name %q", base)
+		}
+
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %q", base)
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m, exist := byVersion[version]
+		if !exist {
+			m = &migration{version: version, name: name}
+			byVersion[version] = m
+		}
+		if m.name != name {
+			return nil, fmt.Errorf("migration %d has different names %q and %q", version, m.name, name)
+		}
+
+		if direction == "up" {
+			m.up = string(data)
+		} else {
+			m.down = string(data)
+		}
+	}
+
+	res := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d must have up and down files", m.version)
+		}
+
+		res = append(res, *m)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].version < res[j].version
+	})
+
+	return res, nil
+}
+
+// Migrator применяет и откатывает миграции схемы БД.
+type Migrator struct {
+	dbPool     *pgxpool.Pool
+	migrations []migration
+}
+
+// NewMigrator создает объект для применения встроенных в приложение миграций.
+func NewMigrator(pool *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	return &Migrator{
+		dbPool:     pool,
+		migrations: migrations,
+	}, nil
+}
+
+// Up применяет все неприменённые миграции, каждую в отдельной транзакции.
+func (m *Migrator) Up(ctx context.Context) error {
+	for _, mig := range m.migrations {
+		applied, err := m.apply(ctx, mig)
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d_%s: %w", mig.version, mig.name, err)
+		}
+
+		if applied {
+			logger.Infof(ctx, "applied migration %d_%s", mig.version, mig.name)
+		}
+	}
+
+	return nil
+}
+
+// Down откатывает steps последних применённых миграций.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	for i := 0; i < steps; i++ {
+		mig, err := m.rollback(ctx)
+		if err != nil {
+			return err
+		}
+
+		if mig == nil {
+			return nil
+		}
+
+		logger.Infof(ctx, "rolled back migration %d_%s", mig.version, mig.name)
+	}
+
+	return nil
+}
+
+// Status возвращает состояние всех известных приложению миграций.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		res = append(res, MigrationStatus{
+			Version:   mig.version,
+			Name:      mig.name,
+			AppliedAt: applied[mig.version],
+		})
+	}
+
+	return res, nil
+}
+
+// Check проверяет, что в БД не применены миграции новее известных приложению.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	var latest int64
+	if len(m.migrations) > 0 {
+		latest = m.migrations[len(m.migrations)-1].version
+	}
+
+	for version := range applied {
+		if version > latest {
+			return fmt.Errorf("%w: database version %d, binary version %d", ErrSchemaAhead, version, latest)
+		}
+	}
+
+	return nil
+}
+
+// applied возвращает версии применённых миграций и время их применения.
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	if _, err := m.dbPool.Exec(ctx, schemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.dbPool.Query(ctx, `select version, applied_at from schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version   int64
+			appliedAt time.Time
+		)
+		if err = rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		res[version] = appliedAt.UTC()
+	}
+
+	return res, rows.Err()
+}
+
+// apply применяет миграцию, если она еще не применена.
+func (m *Migrator) apply(ctx context.Context, mig migration) (bool, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var exist bool
+	err = tx.QueryRow(ctx, `select exists (select 1 from schema_migrations where version = $1);`,
+		mig.version).Scan(&exist)
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		return false, nil
+	}
+
+	if _, err = tx.Exec(ctx, mig.up); err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `insert into schema_migrations (version, name) values ($1, $2);`,
+		mig.version, mig.name)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// rollback откатывает последнюю применённую миграцию, nil означает, что откатывать нечего.
+func (m *Migrator) rollback(ctx context.Context) (*migration, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var version int64
+	err = tx.QueryRow(ctx, `select version from schema_migrations order by version desc limit 1;`).Scan(&version)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var mig *migration
+	for i := range m.migrations {
+		if m.migrations[i].version == version {
+			mig = &m.migrations[i]
+		}
+	}
+	if mig == nil {
+		return nil, fmt.Errorf("%w: unknown migration %d", ErrSchemaAhead, version)
+	}
+
+	if _, err = tx.Exec(ctx, mig.down); err != nil {
+		return nil, fmt.Errorf("failed to roll back migration %d_%s: %w", mig.version, mig.name, err)
+	}
+
+	if _, err = tx.Exec(ctx, `delete from schema_migrations where version = $1;`, version); err != nil {
+		return nil, err
+	}
+
+	return mig, tx.Commit(ctx)
+}
+
+// begin начинает транзакцию миграции, параллельные миграции ожидают друг друга.
+func (m *Migrator) begin(ctx context.Context) (pgx.Tx, error) {
+	if _, err := m.dbPool.Exec(ctx, schemaMigrationsTable); err != nil {
+		return nil, err
+	}
+
+	tx, err := m.dbPool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec(ctx, `select pg_advisory_xact_lock($1);`, migrationsLockKey); err != nil {
+		_ = tx.Rollback(ctx)
+		return nil, err
+	}
+
+	return tx, nil
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func Test_loadMigrations(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name         string
+		fsys         fstest.MapFS
+		wantVersions []int64
+		wantErr      bool
+	}{
+		{
+			name: "ordered",
+			fsys: fstest.MapFS{
+				"migrations/0002_b.up.sql":   {Data: []byte("up b")},
+				"migrations/0002_b.down.sql": {Data: []byte("down b")},
+				"migrations/0001_a.up.sql":   {Data: []byte("up a")},
+				"migrations/0001_a.down.sql": {Data: []byte("down a")},
+			},
+			wantVersions: []int64{1, 2},
+		},
+		{
+			name: "missing down",
+			fsys: fstest.MapFS{
+				"migrations/0001_a.up.sql": {Data: []byte("up a")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "different names",
+			fsys: fstest.MapFS{
+				"migrations/0001_a.up.sql":   {Data: []byte("up a")},
+				"migrations/0001_b.down.sql": {Data: []byte("down b")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid version",
+			fsys: fstest.MapFS{
+				"migrations/first_a.up.sql":   {Data: []byte("up a")},
+				"migrations/first_a.down.sql": {Data: []byte("down a")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid direction",
+			fsys: fstest.MapFS{
+				"migrations/0001_a.sql": {Data: []byte("a")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := loadMigrations(tt.fsys)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.wantVersions) {
+				t.Fatalf("loadMigrations() got %d migrations, want %d", len(got), len(tt.wantVersions))
+			}
+			for i, m := range got {
+				if m.version != tt.wantVersions[i] {
+					t.Errorf("loadMigrations() migration %d version = %d, want %d", i, m.version, tt.wantVersions[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_loadMigrations_embedded(t *testing.T) {
+	t.Parallel()
+	got, err := loadMigrations(migrationsFS)
+	if err != nil {
+		t.Fatalf("loadMigrations() error = %v", err)
+	}
+
+	for i, m := range got {
+		if m.version != int64(i+1) {
+			t.Errorf("migration %s has version %d, want %d", m.name, m.version, i+1)
+		}
+	}
+}
@@ -0,0 +1 @@
+alter table values drop column if exists labels;
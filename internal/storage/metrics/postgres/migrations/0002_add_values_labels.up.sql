@@ -0,0 +1,2 @@
+-- метки ряда метрики, ряд определяется именем и набором меток
+alter table values add column if not exists labels jsonb NOT NULL DEFAULT '{}'::jsonb;
@@ -0,0 +1,5 @@
+-- агенты, присылающие метрики, и время последнего обновления метрик каждым из них
+create table if not exists agents (
+    name      varchar                     primary key,
+    last_seen timestamp WITHOUT TIME ZONE NOT NULL
+);
@@ -0,0 +1 @@
+drop index if exists values_metric_name_created_at_idx;
@@ -0,0 +1,2 @@
+-- выборка значений ряда метрики за период
+create index if not exists values_metric_name_created_at_idx on values (metric_name, created_at);
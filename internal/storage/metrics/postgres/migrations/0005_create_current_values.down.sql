@@ -0,0 +1,2 @@
+drop table if exists gauge_latest;
+drop table if exists counter_totals;
@@ -0,0 +1,29 @@
+-- текущие значения счетчиков, обновляются в одной транзакции с values
+create table if not exists counter_totals (
+    metric_name varchar                     NOT NULL,
+    labels      jsonb                       NOT NULL DEFAULT '{}'::jsonb,
+    value       bigint                      NOT NULL,
+    updated_at  timestamp WITHOUT TIME ZONE NOT NULL,
+    primary key (metric_name, labels)
+);
+
+insert into counter_totals (metric_name, labels, value, updated_at)
+select metric_name, labels, sum(counter_value), max(created_at)
+from values
+where counter_value notnull and not exists (select 1 from counter_totals)
+group by metric_name, labels;
+
+-- последние значения градусников, обновляются в одной транзакции с values
+create table if not exists gauge_latest (
+    metric_name varchar                     NOT NULL,
+    labels      jsonb                       NOT NULL DEFAULT '{}'::jsonb,
+    value       double precision            NOT NULL,
+    updated_at  timestamp WITHOUT TIME ZONE NOT NULL,
+    primary key (metric_name, labels)
+);
+
+insert into gauge_latest (metric_name, labels, value, updated_at)
+select distinct on (metric_name, labels) metric_name, labels, gauge_value, created_at
+from values
+where gauge_value notnull and not exists (select 1 from gauge_latest)
+order by metric_name, labels, created_at desc;
@@ -0,0 +1,3 @@
+drop table if exists values_1h;
+drop table if exists values_1m;
+drop index if exists values_created_at_idx;
@@ -0,0 +1,33 @@
+-- удаление устаревших значений
+create index if not exists values_created_at_idx on values (created_at);
+
+-- агрегаты значений по минутам и часам, в которые сворачиваются устаревшие значения
+create table if not exists values_1m (
+    metric_name varchar                     NOT NULL,
+    labels      jsonb                       NOT NULL DEFAULT '{}'::jsonb,
+    is_counter  boolean                     NOT NULL,
+    bucket      timestamp WITHOUT TIME ZONE NOT NULL,
+    count       bigint                      NOT NULL,
+    sum         double precision            NOT NULL,
+    min         double precision            NOT NULL,
+    max         double precision            NOT NULL,
+    last        double precision            NOT NULL,
+    primary key (metric_name, labels, is_counter, bucket)
+);
+
+create index if not exists values_1m_bucket_idx on values_1m (bucket);
+
+create table if not exists values_1h (
+    metric_name varchar                     NOT NULL,
+    labels      jsonb                       NOT NULL DEFAULT '{}'::jsonb,
+    is_counter  boolean                     NOT NULL,
+    bucket      timestamp WITHOUT TIME ZONE NOT NULL,
+    count       bigint                      NOT NULL,
+    sum         double precision            NOT NULL,
+    min         double precision            NOT NULL,
+    max         double precision            NOT NULL,
+    last        double precision            NOT NULL,
+    primary key (metric_name, labels, is_counter, bucket)
+);
+
+create index if not exists values_1h_bucket_idx on values_1h (bucket);
@@ -0,0 +1 @@
+alter table values alter column counter_value type integer;
@@ -0,0 +1,2 @@
+-- приращения счетчиков не помещаются в integer
+alter table values alter column counter_value type bigint;
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PoolConfig настройки пула соединений.
+type PoolConfig struct {
+	// MaxConns максимальное количество соединений, нулевое значение оставляет значение pgxpool по умолчанию.
+	MaxConns int32
+	// ConnectTimeout время ожидания установки соединения.
+	ConnectTimeout time.Duration
+}
+
+// NewPool создает пул соединений и проверяет доступность БД.
+func NewPool(ctx context.Context, dsn string, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
+	}
+
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = cfg.MaxConns
+	}
+	if cfg.ConnectTimeout > 0 {
+		poolConfig.ConnConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	// разорванные при перезапуске БД соединения закрываются проверкой состояния пула
+	poolConfig.HealthCheckPeriod = 10 * time.Second
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	return pool, nil
+}
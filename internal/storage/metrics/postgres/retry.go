@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// defaultBackoff задержки перед повторами запроса по умолчанию.
+var defaultBackoff = []time.Duration{time.Second, 3 * time.Second, 5 * time.Second}
+
+// withRetry выполняет запрос хранилища с ограничением времени попытки и повторяет его при временных ошибках.
+func withRetry[T any](ctx context.Context, s *Storage, fn func(ctx context.Context) (T, error)) (T, error) {
+	return retry(ctx, s.backoff, func(ctx context.Context) (T, error) {
+		if s.queryTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.queryTimeout)
+			defer cancel()
+		}
+
+		return fn(ctx)
+	})
+}
+
+// retry выполняет fn и повторяет его с задержками из backoff, пока ошибка временная.
+func retry[T any](ctx context.Context, backoff []time.Duration,
+	fn func(ctx context.Context) (T, error)) (T, error) {
+	for i := 0; ; i++ {
+		res, err := fn(ctx)
+		if err == nil || i >= len(backoff) || !isRetriable(err) {
+			return res, err
+		}
+
+		logger.Infof(ctx, "retry postgres query in %s: %v", backoff[i], err)
+
+		timer := time.NewTimer(backoff[i])
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetriable проверяет, можно ли повторить запрос, завершившийся ошибкой:
+// ошибки соединения, перезапуск сервера БД, конфликты сериализации и взаимоблокировки.
+func isRetriable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgerrcode.SerializationFailure,
+			pgerrcode.DeadlockDetected,
+			pgerrcode.AdminShutdown,
+			pgerrcode.CrashShutdown,
+			pgerrcode.CannotConnectNow,
+			pgerrcode.TooManyConnections:
+			return true
+		}
+
+		return pgerrcode.IsConnectionException(pgErr.Code)
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+
+	// запрос не был отправлен серверу, например соединение из пула оказалось разорвано
+	return pgconn.SafeToRetry(err)
+}
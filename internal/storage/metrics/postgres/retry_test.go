@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func Test_isRetriable(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "connection failure",
+			err:  &pgconn.PgError{Code: pgerrcode.ConnectionFailure},
+			want: true,
+		},
+		{
+			name: "serialization failure",
+			err:  fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: pgerrcode.SerializationFailure}),
+			want: true,
+		},
+		{
+			name: "admin shutdown",
+			err:  &pgconn.PgError{Code: pgerrcode.AdminShutdown},
+			want: true,
+		},
+		{
+			name: "unique violation",
+			err:  &pgconn.PgError{Code: pgerrcode.UniqueViolation},
+			want: false,
+		},
+		{
+			name: "not found",
+			err:  domain.ErrNotFound,
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := isRetriable(tt.err); got != tt.want {
+				t.Errorf("isRetriable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retry(t *testing.T) {
+	t.Parallel()
+	retriable := &pgconn.PgError{Code: pgerrcode.DeadlockDetected}
+	backoff := []time.Duration{time.Millisecond, time.Millisecond}
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			name:      "success",
+			errs:      []error{nil},
+			wantCalls: 1,
+			wantErr:   nil,
+		},
+		{
+			name:      "success after retries",
+			errs:      []error{retriable, retriable, nil},
+			wantCalls: 3,
+			wantErr:   nil,
+		},
+		{
+			name:      "retries exhausted",
+			errs:      []error{retriable, retriable, retriable, nil},
+			wantCalls: 3,
+			wantErr:   retriable,
+		},
+		{
+			name:      "not retriable",
+			errs:      []error{domain.ErrNotFound, nil},
+			wantCalls: 1,
+			wantErr:   domain.ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			calls := 0
+			_, err := retry(context.Background(), backoff, func(_ context.Context) (struct{}, error) {
+				err := tt.errs[calls]
+				calls++
+				return struct{}{}, err
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("retry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("retry() calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,419 @@
+// Package storagetest предоставляет общий набор тестов для реализаций хранилища метрик.
+//
+// Набор фиксирует семантику, одинаковую для всех хранилищ:
+//   - градусник хранит последнее записанное значение, счетчик - накопленную сумму приращений;
+//   - ряд определяется типом, именем и набором меток;
+//   - отсутствующие метрики и агенты возвращают ошибку, удовлетворяющую errors.Is(err, domain.ErrNotFound);
+//   - GetAllValues для пустого хранилища возвращает пустой список без ошибки;
+//   - GetSamples возвращает агрегаты приращений счетчика и значений градусника
+//     либо domain.ErrNotSupported, если история не хранится.
+package storagetest
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/internal/usecases/metrics"
+)
+
+// Factory создает пустое хранилище для одного теста.
+type Factory func(t *testing.T) metrics.MetricStorage
+
+// agent имя агента, от которого записываются метрики.
+const agent = "storagetest"
+
+// Run проверяет хранилище, создаваемое newStorage, набором общих тестов.
+// Тесты выполняются последовательно, поэтому хранилища могут использовать общую базу.
+func Run(t *testing.T, newStorage Factory) {
+	t.Helper()
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s metrics.MetricStorage)
+	}{
+		{name: "Ping", fn: testPing},
+		{name: "GaugeLastValue", fn: testGaugeLastValue},
+		{name: "CounterRunningTotal", fn: testCounterRunningTotal},
+		{name: "SeriesIdentity", fn: testSeriesIdentity},
+		{name: "NotFound", fn: testNotFound},
+		{name: "GetAllValuesEmpty", fn: testGetAllValuesEmpty},
+		{name: "GetAllValues", fn: testGetAllValues},
+		{name: "Agents", fn: testAgents},
+		{name: "ConcurrentCounter", fn: testConcurrentCounter},
+		{name: "Samples", fn: testSamples},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStorage(t))
+		})
+	}
+}
+
+func gauge(name string, labels domain.Labels, value float64) domain.MetricValue {
+	return domain.MetricValue{
+		Type:       domain.GaugeMetricType,
+		Name:       name,
+		Labels:     labels,
+		GaugeValue: value,
+	}
+}
+
+func counter(name string, labels domain.Labels, value int64) domain.MetricValue {
+	return domain.MetricValue{
+		Type:         domain.CounterMetricType,
+		Name:         name,
+		Labels:       labels,
+		CounterValue: value,
+	}
+}
+
+func update(t *testing.T, s metrics.MetricStorage, values ...domain.MetricValue) {
+	t.Helper()
+	if err := s.UpdateMetrics(t.Context(), agent, values); err != nil {
+		t.Fatalf("UpdateMetrics() error = %v", err)
+	}
+}
+
+func testPing(t *testing.T, s metrics.MetricStorage) {
+	if err := s.Ping(t.Context()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func testGaugeLastValue(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	if err := s.UpdateGauge(ctx, agent, gauge("Alloc", nil, 1.5)); err != nil {
+		t.Fatalf("UpdateGauge() error = %v", err)
+	}
+	update(t, s, gauge("Alloc", nil, 2.5), gauge("Alloc", nil, -3.25))
+
+	got, err := s.GetGaugeValue(ctx, "Alloc", nil)
+	if err != nil {
+		t.Fatalf("GetGaugeValue() error = %v", err)
+	}
+	if got != -3.25 {
+		t.Errorf("GetGaugeValue() = %v, want %v", got, -3.25)
+	}
+}
+
+func testCounterRunningTotal(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	if err := s.UpdateCounter(ctx, agent, counter("PollCount", nil, 5)); err != nil {
+		t.Fatalf("UpdateCounter() error = %v", err)
+	}
+	update(t, s, counter("PollCount", nil, 3), counter("PollCount", nil, 2))
+	update(t, s, counter("PollCount", nil, -4))
+
+	got, err := s.GetCounterValue(ctx, "PollCount", nil)
+	if err != nil {
+		t.Fatalf("GetCounterValue() error = %v", err)
+	}
+	if got != 6 {
+		t.Errorf("GetCounterValue() = %v, want %v", got, 6)
+	}
+}
+
+func testSeriesIdentity(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	update(t, s,
+		gauge("Value", nil, 1),
+		gauge("Value", domain.Labels{"host": "a"}, 2),
+		gauge("Value", domain.Labels{"host": "a", "dc": "eu"}, 3),
+		counter("Value", nil, 4),
+		counter("Value", domain.Labels{"dc": "eu", "host": "a"}, 5),
+	)
+
+	gauges := []struct {
+		labels domain.Labels
+		want   float64
+	}{
+		{labels: nil, want: 1},
+		{labels: domain.Labels{}, want: 1},
+		{labels: domain.Labels{"host": "a"}, want: 2},
+		{labels: domain.Labels{"dc": "eu", "host": "a"}, want: 3},
+	}
+	for _, g := range gauges {
+		got, err := s.GetGaugeValue(ctx, "Value", g.labels)
+		if err != nil {
+			t.Errorf("GetGaugeValue(%v) error = %v", g.labels, err)
+			continue
+		}
+		if got != g.want {
+			t.Errorf("GetGaugeValue(%v) = %v, want %v", g.labels, got, g.want)
+		}
+	}
+
+	counters := []struct {
+		labels domain.Labels
+		want   int64
+	}{
+		{labels: nil, want: 4},
+		{labels: domain.Labels{"host": "a", "dc": "eu"}, want: 5},
+	}
+	for _, c := range counters {
+		got, err := s.GetCounterValue(ctx, "Value", c.labels)
+		if err != nil {
+			t.Errorf("GetCounterValue(%v) error = %v", c.labels, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("GetCounterValue(%v) = %v, want %v", c.labels, got, c.want)
+		}
+	}
+}
+
+func testNotFound(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	update(t, s, gauge("Gauge", domain.Labels{"host": "a"}, 1), counter("Counter", nil, 1))
+
+	if _, err := s.GetGaugeValue(ctx, "Missing", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetGaugeValue() missing name error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := s.GetGaugeValue(ctx, "Gauge", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetGaugeValue() missing labels error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := s.GetGaugeValue(ctx, "Counter", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetGaugeValue() other type error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := s.GetCounterValue(ctx, "Missing", nil); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetCounterValue() missing name error = %v, want %v", err, domain.ErrNotFound)
+	}
+	if _, err := s.GetAgent(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetAgent() error = %v, want %v", err, domain.ErrNotFound)
+	}
+}
+
+func testGetAllValuesEmpty(t *testing.T, s metrics.MetricStorage) {
+	got, err := s.GetAllValues(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllValues() error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("GetAllValues() = %#v, want empty slice", got)
+	}
+}
+
+// valueString строковое представление метрики для сравнения наборов.
+func valueString(v domain.MetricValue) string {
+	switch v.Type {
+	case domain.GaugeMetricType:
+		return fmt.Sprintf("%s %s %v", v.Type, v.SeriesKey(), v.GaugeValue)
+	default:
+		return fmt.Sprintf("%s %s %d", v.Type, v.SeriesKey(), v.CounterValue)
+	}
+}
+
+func testGetAllValues(t *testing.T, s metrics.MetricStorage) {
+	update(t, s,
+		gauge("Alloc", domain.Labels{"host": "a"}, 1),
+		gauge("Alloc", domain.Labels{"host": "b"}, 2),
+		counter("PollCount", domain.Labels{"host": "a"}, 3),
+		counter("PollCount", domain.Labels{"host": "a"}, 4),
+		counter("PollCount", nil, 5),
+	)
+
+	hostA, err := domain.NewLabelMatcher(domain.MatchEqual, "host", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noHost, err := domain.NewLabelMatcher(domain.MatchEqual, "host", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		matchers []domain.LabelMatcher
+		want     []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				`counter PollCount 5`,
+				`counter PollCount{host="a"} 7`,
+				`gauge Alloc{host="a"} 1`,
+				`gauge Alloc{host="b"} 2`,
+			},
+		},
+		{
+			name:     "label equal",
+			matchers: []domain.LabelMatcher{hostA},
+			want: []string{
+				`counter PollCount{host="a"} 7`,
+				`gauge Alloc{host="a"} 1`,
+			},
+		},
+		{
+			name:     "missing label",
+			matchers: []domain.LabelMatcher{noHost},
+			want: []string{
+				`counter PollCount 5`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := s.GetAllValues(t.Context(), tt.matchers...)
+			if err != nil {
+				t.Fatalf("GetAllValues() error = %v", err)
+			}
+
+			got := make([]string, 0, len(values))
+			for _, v := range values {
+				got = append(got, valueString(v))
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("GetAllValues() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// clockSkew допустимое расхождение часов хранилища и теста.
+const clockSkew = time.Second
+
+func testAgents(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	before := time.Now()
+	for _, name := range []string{"a", "b"} {
+		if err := s.UpdateMetrics(ctx, name, []domain.MetricValue{gauge("Alloc", nil, 1)}); err != nil {
+			t.Fatalf("UpdateMetrics() error = %v", err)
+		}
+	}
+	after := time.Now()
+
+	agents, err := s.GetAgents(ctx)
+	if err != nil {
+		t.Fatalf("GetAgents() error = %v", err)
+	}
+
+	names := make([]string, 0, len(agents))
+	for _, a := range agents {
+		names = append(names, a.Name)
+	}
+	sort.Strings(names)
+	if fmt.Sprint(names) != fmt.Sprint([]string{"a", "b"}) {
+		t.Errorf("GetAgents() = %v, want [a b]", names)
+	}
+
+	a, err := s.GetAgent(ctx, "b")
+	if err != nil {
+		t.Fatalf("GetAgent() error = %v", err)
+	}
+	if a.Name != "b" {
+		t.Errorf("GetAgent() name = %q, want %q", a.Name, "b")
+	}
+	if a.LastSeen.Before(before.Add(-clockSkew)) || a.LastSeen.After(after.Add(clockSkew)) {
+		t.Errorf("GetAgent() last seen = %v, want between %v and %v", a.LastSeen, before, after)
+	}
+}
+
+func testConcurrentCounter(t *testing.T, s metrics.MetricStorage) {
+	const (
+		workers = 8
+		updates = 25
+	)
+
+	ctx := t.Context()
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range updates {
+				if err := s.UpdateCounter(ctx, agent, counter("Requests", nil, 1)); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("UpdateCounter() error = %v", err)
+	}
+
+	got, err := s.GetCounterValue(ctx, "Requests", nil)
+	if err != nil {
+		t.Fatalf("GetCounterValue() error = %v", err)
+	}
+	if got != workers*updates {
+		t.Errorf("GetCounterValue() = %v, want %v", got, workers*updates)
+	}
+}
+
+func testSamples(t *testing.T, s metrics.MetricStorage) {
+	ctx := t.Context()
+	start := time.Now().Add(-time.Minute)
+	update(t, s, counter("PollCount", nil, 5), gauge("Alloc", nil, 1))
+	update(t, s, counter("PollCount", nil, 7), gauge("Alloc", nil, 4))
+	end := time.Now().Add(time.Minute)
+
+	tests := []struct {
+		name      string
+		mType     domain.MetricType
+		wantCount int64
+		wantSum   float64
+		wantLast  float64
+	}{
+		{
+			name:      "counter increments",
+			mType:     domain.CounterMetricType,
+			wantCount: 2,
+			wantSum:   12,
+			wantLast:  7,
+		},
+		{
+			name:      "gauge values",
+			mType:     domain.GaugeMetricType,
+			wantCount: 2,
+			wantSum:   5,
+			wantLast:  4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := "PollCount"
+			if tt.mType == domain.GaugeMetricType {
+				name = "Alloc"
+			}
+
+			samples, err := s.GetSamples(ctx, domain.RangeQuery{
+				Type:        tt.mType,
+				Name:        name,
+				Start:       start,
+				End:         end,
+				Step:        end.Sub(start),
+				Aggregation: domain.DefaultAggregation(tt.mType),
+			})
+			if errors.Is(err, domain.ErrNotSupported) {
+				t.Skip("storage does not keep history")
+			}
+			if err != nil {
+				t.Fatalf("GetSamples() error = %v", err)
+			}
+
+			var total domain.Sample
+			for i, sample := range samples {
+				if i > 0 && !samples[i-1].Timestamp.Before(sample.Timestamp) {
+					t.Errorf("GetSamples() samples are not ordered: %v", samples)
+				}
+				if sample.Timestamp.Before(start.Add(-clockSkew)) || !sample.Timestamp.Before(end) {
+					t.Errorf("GetSamples() sample %v is out of range", sample)
+				}
+				total = total.Merge(sample)
+			}
+			if total.Count != tt.wantCount || total.Sum != tt.wantSum || total.Last != tt.wantLast {
+				t.Errorf("GetSamples() total = %+v, want count %d, sum %v, last %v",
+					total, tt.wantCount, tt.wantSum, tt.wantLast)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Collector источник метрик агента.
+type Collector interface {
+	// Collect возвращает текущие значения метрик. Сбор должен прерываться при отмене ctx.
+	Collect(ctx context.Context) ([]domain.MetricValue, error)
+}
+
+// CollectorConfig настройки сбора метрик отдельным сборщиком.
+type CollectorConfig struct {
+	// Enabled включен ли сборщик.
+	Enabled bool
+	// Interval период сбора метрик.
+	Interval time.Duration
+	// Timeout максимальное время одного сбора, по умолчанию равно Interval.
+	Timeout time.Duration
+}
+
+// registryEntry сборщик с настройками.
+type registryEntry struct {
+	name      string
+	collector Collector
+	config    CollectorConfig
+}
+
+// Registry реестр сборщиков метрик. Метрики сборщиков объединяются в порядке регистрации.
+type Registry struct {
+	entries []registryEntry
+	names   map[string]struct{}
+}
+
+// NewRegistry создает пустой реестр сборщиков.
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]struct{}),
+	}
+}
+
+// Register добавляет сборщик с уникальным именем name.
+func (r *Registry) Register(name string, collector Collector, config CollectorConfig) error {
+	if name == "" {
+		return errors.New("collector name is empty")
+	}
+	if _, exist := r.names[name]; exist {
+		return fmt.Errorf("collector %s already registered", name)
+	}
+	if config.Enabled && config.Interval <= 0 {
+		return fmt.Errorf("invalid collector %s interval: %s", name, config.Interval)
+	}
+	if config.Timeout < 0 {
+		return fmt.Errorf("invalid collector %s timeout: %s", name, config.Timeout)
+	}
+	if config.Timeout == 0 {
+		config.Timeout = config.Interval
+	}
+
+	r.names[name] = struct{}{}
+	r.entries = append(r.entries, registryEntry{
+		name:      name,
+		collector: collector,
+		config:    config,
+	})
+
+	return nil
+}
+
+// enabled возвращает включенные сборщики в порядке регистрации.
+func (r *Registry) enabled() []registryEntry {
+	res := make([]registryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		if e.config.Enabled {
+			res = append(res, e)
+		}
+	}
+
+	return res
+}
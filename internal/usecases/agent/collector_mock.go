@@ -0,0 +1,38 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type collectorMock struct {
+	mu     sync.Mutex
+	values []domain.MetricValue
+	err    error
+	// block при непустом значении сбор ожидает закрытия канала, не учитывая отмену контекста
+	block chan struct{}
+
+	calls atomic.Int64
+}
+
+func (m *collectorMock) Collect(_ context.Context) ([]domain.MetricValue, error) {
+	m.calls.Add(1)
+	if m.block != nil {
+		<-m.block
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.values, m.err
+}
+
+func (m *collectorMock) set(values []domain.MetricValue, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values = values
+	m.err = err
+}
@@ -4,15 +4,26 @@ import (
 	"context"
 	"fmt"
 	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/collectors"
 )
 
 func ExampleMetricsUpdater_GetMetrics() {
 	ctx := context.Background()
 	updatePeriod := 5 * time.Second
+
+	// регистрируем сборщики метрик, каждый со своим периодом
+	registry := NewRegistry()
+	_ = registry.Register(collectors.RuntimeName, collectors.NewRuntime(),
+		CollectorConfig{Enabled: true, Interval: updatePeriod})
+	_ = registry.Register(collectors.PollName, collectors.NewPoll(),
+		CollectorConfig{Enabled: true, Interval: time.Second, Timeout: 100 * time.Millisecond})
+
 	// создаем объект сборщика метрик
-	updater := NewMetricsUpdater(ctx, updatePeriod)
+	updater := NewMetricsUpdater(ctx, registry)
 
 	time.Sleep(updatePeriod)
+
 	// получаем собранные метрики
 	metrics, err := updater.GetMetrics(ctx)
 	if err != nil {
@@ -2,138 +2,95 @@ package agent
 
 import (
 	"context"
-	"reflect"
-	"sync"
-	"sync/atomic"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
 )
 
-func Test_recursiveGetMetrics(t *testing.T) {
+func TestUseCase_SendMetrics_shutdown(t *testing.T) {
 	t.Parallel()
-	type args struct {
-		v reflect.Value
-	}
-	tests := []struct {
-		name string
-		args args
-		want []domain.MetricValue
-	}{
-		{
-			name: "",
-			args: args{
-				v: reflect.ValueOf(struct {
-					metricUint64  uint64
-					metricUint32  uint32
-					metricFloat64 float64
-					metricStruct  struct {
-						metricUint64 uint64
-					}
-				}{
-					metricUint64:  1,
-					metricUint32:  2,
-					metricFloat64: 3,
-					metricStruct: struct {
-						metricUint64 uint64
-					}{
-						metricUint64: 4,
-					},
-				}),
-			},
-			want: []domain.MetricValue{
-				{
-					Type:       domain.GaugeMetricType,
-					Name:       "metricUint64",
-					GaugeValue: 1,
-				},
-				{
-					Type:       domain.GaugeMetricType,
-					Name:       "metricUint32",
-					GaugeValue: 2,
-				},
-				{
-					Type:       domain.GaugeMetricType,
-					Name:       "metricFloat64",
-					GaugeValue: 3,
-				},
-				{
-					Type:       domain.GaugeMetricType,
-					Name:       "metricUint64",
-					GaugeValue: 4,
-				},
-			},
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Parallel()
-			if got := recursiveGetMetrics(tt.args.v); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("recursiveGetMetrics() = %v, want %v", got, tt.want)
-			}
+
+	metrics := make([]domain.MetricValue, 0, 7)
+	for i := range cap(metrics) {
+		metrics = append(metrics, domain.MetricValue{
+			Type:       domain.GaugeMetricType,
+			Name:       fmt.Sprintf("Gauge%d", i),
+			GaugeValue: float64(i),
 		})
 	}
-}
 
-func TestMetricsUpdater_updateMetrics(t *testing.T) {
-	t.Parallel()
-	type fields struct {
-		stats []domain.MetricValue
-	}
-	type args struct {
-		in0 context.Context
-	}
 	tests := []struct {
-		name    string
-		fields  fields
-		args    args
-		wantErr bool
+		name       string
+		workerNums int64
+		metrics    []domain.MetricValue
 	}{
 		{
-			name: "",
-			fields: fields{
-				stats: nil,
-			},
-			args: args{
-				in0: context.Background(),
-			},
-			wantErr: false,
+			name:       "single worker",
+			workerNums: 1,
+			metrics:    metrics,
+		},
+		{
+			name:       "uneven split",
+			workerNums: 3,
+			metrics:    metrics,
+		},
+		{
+			name:       "more workers than metrics",
+			workerNums: 10,
+			metrics:    metrics,
+		},
+		{
+			name:       "no metrics",
+			workerNums: 2,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			m := &MetricsUpdater{
-				mu:          sync.RWMutex{},
-				stats:       tt.fields.stats,
-				pollCount:   atomic.Int64{},
-				randomValue: Container[float64]{},
+
+			sender := &sendClientMock{}
+			collector := &metricsClientMock{metrics: tt.metrics}
+			// интервал отправки больше времени теста, метрики отправляются только при завершении
+			u := NewUseCase(sender, collector, time.Hour, tt.workerNums)
+
+			ctx, cancel := context.WithCancel(t.Context())
+			cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				done <- u.SendMetrics(ctx)
+			}()
+
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("SendMetrics() error = %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("SendMetrics() did not return after cancel")
 			}
-			if err := m.updateMetrics(tt.args.in0); (err != nil) != tt.wantErr {
-				t.Errorf("updateMetrics() error = %v, wantErr %v", err, tt.wantErr)
+
+			if got := collector.collected.Load(); got != 1 {
+				t.Errorf("Collect() calls = %d, want 1", got)
 			}
-		})
-	}
-}
 
-func BenchmarkRecursiveGetMetrics(b *testing.B) {
-	b.Run("recursiveGetMetrics", func(b *testing.B) {
-		_ = recursiveGetMetrics(reflect.ValueOf(struct {
-			metricUint64  uint64
-			metricUint32  uint32
-			metricFloat64 float64
-			metricStruct  struct {
-				metricUint64 uint64
+			sent := make(map[string]float64)
+			for _, v := range sender.getSent() {
+				if _, exist := sent[v.Name]; exist {
+					t.Errorf("metric %s sent twice", v.Name)
+				}
+				sent[v.Name] = v.GaugeValue
 			}
-		}{
-			metricUint64:  1,
-			metricUint32:  2,
-			metricFloat64: 3,
-			metricStruct: struct {
-				metricUint64 uint64
-			}{
-				metricUint64: 4,
-			},
-		}))
-	})
+			if len(sent) != len(tt.metrics) {
+				t.Fatalf("sent %d metrics, want %d", len(sent), len(tt.metrics))
+			}
+			for _, v := range tt.metrics {
+				if got, exist := sent[v.Name]; !exist || got != v.GaugeValue {
+					t.Errorf("metric %s not sent", v.Name)
+				}
+			}
+		})
+	}
 }
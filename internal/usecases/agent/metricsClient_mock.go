@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type metricsClientMock struct {
+	metrics []domain.MetricValue
+	err     error
+
+	collected atomic.Int64
+}
+
+func (m *metricsClientMock) GetMetrics(_ context.Context) ([]domain.MetricValue, error) {
+	return m.metrics, m.err
+}
+
+func (m *metricsClientMock) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	m.collected.Add(1)
+
+	return m.GetMetrics(ctx)
+}
@@ -0,0 +1,36 @@
+package agent
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type sendClientMock struct {
+	mu   sync.Mutex
+	sent []domain.MetricValue
+}
+
+func (m *sendClientMock) SendGauge(ctx context.Context, value domain.MetricValue) error {
+	return m.SendMetrics(ctx, []domain.MetricValue{value})
+}
+
+func (m *sendClientMock) SendCounter(ctx context.Context, value domain.MetricValue) error {
+	return m.SendMetrics(ctx, []domain.MetricValue{value})
+}
+
+func (m *sendClientMock) SendMetrics(_ context.Context, values []domain.MetricValue) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, values...)
+
+	return nil
+}
+
+func (m *sendClientMock) getSent() []domain.MetricValue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]domain.MetricValue(nil), m.sent...)
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// MetricsUpdater объект периодического сбора метрик сборщиками из реестра.
+type MetricsUpdater struct {
+	states []*collectorState
+}
+
+// collectorState последние значения метрик, собранные сборщиком.
+type collectorState struct {
+	registryEntry
+
+	// running выполняется ли сбор, зависший сборщик не запускается повторно
+	running atomic.Bool
+
+	mu     sync.RWMutex
+	values []domain.MetricValue
+}
+
+// collectResult результат сбора метрик.
+type collectResult struct {
+	values []domain.MetricValue
+	err    error
+}
+
+// NewMetricsUpdater создает объект автоматического сбора и обновления метрик.
+// Каждый включенный сборщик реестра опрашивается со своим периодом до отмены ctx.
+func NewMetricsUpdater(ctx context.Context, registry *Registry) *MetricsUpdater {
+	enabled := registry.enabled()
+	m := &MetricsUpdater{
+		states: make([]*collectorState, 0, len(enabled)),
+	}
+	for _, e := range enabled {
+		m.states = append(m.states, &collectorState{registryEntry: e})
+	}
+
+	m.collectAll(ctx)
+	for _, s := range m.states {
+		go m.run(ctx, s)
+	}
+
+	return m
+}
+
+func (m *MetricsUpdater) run(ctx context.Context, s *collectorState) {
+	t := time.NewTicker(s.config.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.collect(ctx)
+		}
+	}
+}
+
+// GetMetrics возвращает последние собранные значения метрик всех сборщиков.
+func (m *MetricsUpdater) GetMetrics(_ context.Context) ([]domain.MetricValue, error) {
+	res := make([]domain.MetricValue, 0)
+	for _, s := range m.states {
+		s.mu.RLock()
+		res = append(res, s.values...)
+		s.mu.RUnlock()
+	}
+
+	return res, nil
+}
+
+// Collect собирает метрики всеми сборщиками немедленно и возвращает их.
+func (m *MetricsUpdater) Collect(ctx context.Context) ([]domain.MetricValue, error) {
+	m.collectAll(ctx)
+
+	return m.GetMetrics(ctx)
+}
+
+func (m *MetricsUpdater) collectAll(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for _, s := range m.states {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.collect(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// collect обновляет значения метрик сборщика. При ошибке или превышении времени сбора
+// сохраняются прежние значения.
+func (s *collectorState) collect(ctx context.Context) {
+	if !s.running.CompareAndSwap(false, true) {
+		logger.Errorf(ctx, "collector %s: previous collection is still running", s.name)
+		return
+	}
+
+	collectCtx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	done := make(chan collectResult, 1)
+	go func() {
+		defer s.running.Store(false)
+		values, err := s.collector.Collect(collectCtx)
+		done <- collectResult{values: values, err: err}
+	}()
+
+	select {
+	case <-collectCtx.Done():
+		logger.Errorf(ctx, "collector %s: %v", s.name, collectCtx.Err())
+	case res := <-done:
+		if res.err != nil {
+			logger.Errorf(ctx, "collector %s: %v", s.name, res.err)
+			return
+		}
+
+		s.mu.Lock()
+		s.values = res.values
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,219 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func gauge(name string, value float64) domain.MetricValue {
+	return domain.MetricValue{
+		Type:       domain.GaugeMetricType,
+		Name:       name,
+		GaugeValue: value,
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		collector   string
+		config      CollectorConfig
+		wantErr     bool
+		wantTimeout time.Duration
+	}{
+		{
+			name:        "timeout defaults to interval",
+			collector:   "b",
+			config:      CollectorConfig{Enabled: true, Interval: time.Second},
+			wantTimeout: time.Second,
+		},
+		{
+			name:        "explicit timeout",
+			collector:   "b",
+			config:      CollectorConfig{Enabled: true, Interval: time.Second, Timeout: time.Millisecond},
+			wantTimeout: time.Millisecond,
+		},
+		{
+			name:      "disabled without interval",
+			collector: "b",
+			config:    CollectorConfig{},
+		},
+		{
+			name:      "duplicate name",
+			collector: "a",
+			config:    CollectorConfig{Enabled: true, Interval: time.Second},
+			wantErr:   true,
+		},
+		{
+			name:    "empty name",
+			config:  CollectorConfig{Enabled: true, Interval: time.Second},
+			wantErr: true,
+		},
+		{
+			name:      "enabled without interval",
+			collector: "b",
+			config:    CollectorConfig{Enabled: true},
+			wantErr:   true,
+		},
+		{
+			name:      "negative timeout",
+			collector: "b",
+			config:    CollectorConfig{Enabled: true, Interval: time.Second, Timeout: -time.Second},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := NewRegistry()
+			if err := r.Register("a", &collectorMock{}, CollectorConfig{Enabled: true, Interval: time.Second}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := r.Register(tt.collector, &collectorMock{}, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := r.entries[len(r.entries)-1].config.Timeout; got != tt.wantTimeout {
+				t.Errorf("timeout = %s, want %s", got, tt.wantTimeout)
+			}
+		})
+	}
+}
+
+func TestMetricsUpdater_GetMetrics(t *testing.T) {
+	t.Parallel()
+
+	first := &collectorMock{values: []domain.MetricValue{gauge("A", 1), gauge("B", 2)}}
+	disabled := &collectorMock{values: []domain.MetricValue{gauge("Disabled", 3)}}
+	second := &collectorMock{values: []domain.MetricValue{gauge("C", 4)}}
+
+	r := NewRegistry()
+	for _, reg := range []struct {
+		name      string
+		collector Collector
+		enabled   bool
+	}{
+		{name: "first", collector: first, enabled: true},
+		{name: "disabled", collector: disabled},
+		{name: "second", collector: second, enabled: true},
+	} {
+		err := r.Register(reg.name, reg.collector, CollectorConfig{Enabled: reg.enabled, Interval: time.Hour})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := NewMetricsUpdater(t.Context(), r)
+
+	got, err := m.GetMetrics(t.Context())
+	if err != nil {
+		t.Fatalf("GetMetrics() error = %v", err)
+	}
+	want := []domain.MetricValue{gauge("A", 1), gauge("B", 2), gauge("C", 4)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetMetrics() = %v, want %v", got, want)
+	}
+	if calls := disabled.calls.Load(); calls != 0 {
+		t.Errorf("disabled collector called %d times", calls)
+	}
+
+	// ошибка сбора не стирает прежние значения
+	second.set([]domain.MetricValue{gauge("C", 5)}, nil)
+	first.set(nil, errors.New("collect failed"))
+	got, err = m.Collect(t.Context())
+	if err != nil {
+		t.Fatalf("Collect() error = %v", err)
+	}
+	want = []domain.MetricValue{gauge("A", 1), gauge("B", 2), gauge("C", 5)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() = %v, want %v", got, want)
+	}
+}
+
+func TestMetricsUpdater_intervals(t *testing.T) {
+	t.Parallel()
+
+	fast := &collectorMock{}
+	slow := &collectorMock{}
+
+	r := NewRegistry()
+	if err := r.Register("fast", fast, CollectorConfig{Enabled: true, Interval: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register("slow", slow, CollectorConfig{Enabled: true, Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	_ = NewMetricsUpdater(ctx, r)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fast.calls.Load() < 4 {
+		if time.Now().After(deadline) {
+			t.Fatalf("fast collector called %d times", fast.calls.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	if calls := slow.calls.Load(); calls != 1 {
+		t.Errorf("slow collector called %d times, want 1", calls)
+	}
+}
+
+func TestMetricsUpdater_timeout(t *testing.T) {
+	t.Parallel()
+
+	hanging := &collectorMock{
+		values: []domain.MetricValue{gauge("Hanging", 1)},
+		block:  make(chan struct{}),
+	}
+	healthy := &collectorMock{values: []domain.MetricValue{gauge("Healthy", 1)}}
+
+	r := NewRegistry()
+	err := r.Register("hanging", hanging,
+		CollectorConfig{Enabled: true, Interval: time.Hour, Timeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = r.Register("healthy", healthy, CollectorConfig{Enabled: true, Interval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	m := NewMetricsUpdater(t.Context(), r)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("NewMetricsUpdater() took %s with hanging collector", elapsed)
+	}
+
+	got, _ := m.Collect(t.Context())
+	want := []domain.MetricValue{gauge("Healthy", 1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collect() = %v, want %v", got, want)
+	}
+	// зависший сбор не запускается повторно
+	if calls := hanging.calls.Load(); calls != 1 {
+		t.Errorf("hanging collector called %d times, want 1", calls)
+	}
+
+	close(hanging.block)
+	deadline := time.Now().Add(5 * time.Second)
+	for hanging.calls.Load() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("collector not called after previous collection finished")
+		}
+		_, _ = m.Collect(t.Context())
+	}
+}
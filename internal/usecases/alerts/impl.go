@@ -0,0 +1,377 @@
+// Package alerts предоставляет методы бизнес-логики для вычисления правил алертинга по значениям метрик.
+package alerts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+// resolvedRetention время, в течение которого разрешенный алерт остается в списке алертов.
+const resolvedRetention = 15 * time.Minute
+
+type metricStorage interface {
+	GetGaugeValue(ctx context.Context, name string, labels domain.Labels) (float64, error)
+	GetCounterValue(ctx context.Context, name string, labels domain.Labels) (int64, error)
+}
+
+// notifier получатель алертов. Notify вызывается после каждого вычисления правил
+// с полным списком текущих алертов, включая неизменившиеся и разрешенные,
+// группировка и дедупликация уведомлений выполняются получателем.
+type notifier interface {
+	Notify(ctx context.Context, alerts []domain.Alert)
+}
+
+// ruleState состояние вычисления правила.
+type ruleState struct {
+	rule Rule
+	// alert текущий алерт правила, пустое состояние означает, что алерта нет.
+	alert domain.Alert
+
+	lastCounter int64
+	counterSeen bool
+	// retired правило удалено или изменено, состояние хранит разрешенный алерт до истечения
+	// resolvedRetention, чтобы о разрешении были отправлены уведомления.
+	retired bool
+}
+
+// UseCases бизнес-логика вычисления правил алертинга.
+type UseCases struct {
+	metricStorage metricStorage
+	rulesPath     string
+	notifier      notifier
+
+	mu           sync.RWMutex
+	states       []*ruleState
+	rulesModTime time.Time
+}
+
+// Option опция бизнес-логики алертинга.
+type Option func(uc *UseCases)
+
+// WithNotifierOpt включает рассылку уведомлений об алертах.
+func WithNotifierOpt(n notifier) Option {
+	return func(uc *UseCases) {
+		uc.notifier = n
+	}
+}
+
+// NewUseCases создает объект бизнес-логики алертинга и запускает периодическое вычисление правил.
+// Файл правил перечитывается при каждом изменении, пустой путь означает отсутствие правил.
+func NewUseCases(ctx context.Context, metricStorage metricStorage,
+	rulesPath string, interval time.Duration, opts ...Option) (*UseCases, error) {
+	uc := &UseCases{
+		metricStorage: metricStorage,
+		rulesPath:     rulesPath,
+	}
+
+	for _, opt := range opts {
+		opt(uc)
+	}
+
+	if rulesPath == "" {
+		return uc, nil
+	}
+
+	if err := uc.Reload(ctx); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				uc.reloadIfChanged(ctx)
+				uc.tick(ctx, time.Now())
+			}
+		}
+	}()
+
+	return uc, nil
+}
+
+// tick вычисляет правила и передает получателю полный список текущих алертов.
+func (uc *UseCases) tick(ctx context.Context, now time.Time) {
+	for _, a := range uc.evaluate(ctx, now) {
+		logger.Infof(ctx, "alert %s is %s", a.RuleName, a.State)
+	}
+
+	if uc.notifier != nil {
+		alerts, _ := uc.GetAlerts(ctx)
+		uc.notifier.Notify(ctx, alerts)
+	}
+}
+
+// Reload перечитывает файл правил.
+// Состояние алертов сохраняется для правил, определение которых не изменилось.
+func (uc *UseCases) Reload(ctx context.Context) error {
+	info, err := os.Stat(uc.rulesPath)
+	if err != nil {
+		return err
+	}
+
+	rules, err := LoadRules(uc.rulesPath)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range uc.setRules(rules, time.Now()) {
+		logger.Infof(ctx, "alert %s is %s: rule removed or changed", a.RuleName, a.State)
+	}
+
+	uc.mu.Lock()
+	uc.rulesModTime = info.ModTime()
+	uc.mu.Unlock()
+
+	logger.Infof(ctx, "loaded %d alerting rules from %s", len(rules), uc.rulesPath)
+	return nil
+}
+
+func (uc *UseCases) reloadIfChanged(ctx context.Context) {
+	info, err := os.Stat(uc.rulesPath)
+	if err != nil {
+		logger.Errorf(ctx, "error stat rules file: %v", err)
+		return
+	}
+
+	uc.mu.RLock()
+	modTime := uc.rulesModTime
+	uc.mu.RUnlock()
+
+	if info.ModTime().Equal(modTime) {
+		return
+	}
+
+	// при ошибке продолжаем работать с предыдущим набором правил
+	if err = uc.Reload(ctx); err != nil {
+		logger.Errorf(ctx, "error reload rules: %v", err)
+	}
+}
+
+// setRules заменяет набор правил. Сработавшие алерты удаленных и измененных правил
+// разрешаются в момент now и возвращаются.
+func (uc *UseCases) setRules(rules []Rule, now time.Time) []domain.Alert {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	prev := make(map[string]*ruleState, len(uc.states))
+	for _, st := range uc.states {
+		if !st.retired {
+			prev[st.rule.Name] = st
+		}
+	}
+
+	states := make([]*ruleState, 0, len(rules))
+	kept := make(map[*ruleState]struct{}, len(rules))
+	for _, r := range rules {
+		if st, exist := prev[r.Name]; exist && reflect.DeepEqual(st.rule, r) {
+			states = append(states, st)
+			kept[st] = struct{}{}
+			continue
+		}
+
+		states = append(states, &ruleState{rule: r})
+	}
+
+	resolved := make([]domain.Alert, 0)
+	for _, st := range uc.states {
+		if _, exist := kept[st]; exist {
+			continue
+		}
+
+		switch st.alert.State {
+		case domain.AlertStateFiring:
+			st.alert.State = domain.AlertStateResolved
+			st.alert.ResolvedAt = now
+			resolved = append(resolved, st.alert)
+		case domain.AlertStateResolved:
+		default:
+			// алерты в ожидании не рассылались, их разрешение не требуется
+			continue
+		}
+
+		// новое состояние не разделяется с вычислением, которое могло прочитать прежний набор
+		states = append(states, &ruleState{rule: st.rule, alert: st.alert, retired: true})
+	}
+
+	uc.states = states
+	return resolved
+}
+
+// observation значение метрики правила, прочитанное из хранилища.
+type observation struct {
+	found   bool
+	value   float64
+	counter int64
+}
+
+// evaluate вычисляет все правила и возвращает алерты, изменившие состояние.
+// Значения метрик читаются без блокировки, чтобы медленное хранилище не задерживало
+// получение алертов и перезагрузку правил.
+func (uc *UseCases) evaluate(ctx context.Context, now time.Time) []domain.Alert {
+	uc.mu.RLock()
+	states := slices.Clone(uc.states)
+	uc.mu.RUnlock()
+
+	observations := make([]observation, len(states))
+	errs := make([]error, len(states))
+	for i, st := range states {
+		if !st.retired {
+			observations[i], errs[i] = uc.observe(ctx, st.rule)
+		}
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	// правила могли быть перечитаны, пока читались значения метрик
+	current := make(map[*ruleState]struct{}, len(uc.states))
+	for _, st := range uc.states {
+		current[st] = struct{}{}
+	}
+
+	changed := make([]domain.Alert, 0)
+	for i, st := range states {
+		if _, exist := current[st]; !exist {
+			continue
+		}
+		if st.retired {
+			st.transit(false, st.alert.Value, now)
+			continue
+		}
+		if errs[i] != nil {
+			logger.Errorf(ctx, "error evaluate rule %s: %v", st.rule.Name, errs[i])
+			continue
+		}
+
+		active, value := st.check(observations[i])
+		prevState := st.alert.State
+		st.transit(active, value, now)
+		if st.alert.State != prevState && st.alert.State != "" {
+			changed = append(changed, st.alert)
+		}
+	}
+
+	// состояния удаленных правил забываются после истечения срока хранения алерта
+	uc.states = slices.DeleteFunc(uc.states, func(st *ruleState) bool {
+		return st.retired && st.alert.State == ""
+	})
+
+	return changed
+}
+
+// observe читает значение метрики правила r.
+func (uc *UseCases) observe(ctx context.Context, r Rule) (observation, error) {
+	switch r.MetricType {
+	case domain.GaugeMetricType:
+		v, err := uc.metricStorage.GetGaugeValue(ctx, r.MetricName, r.Labels)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return observation{}, nil
+			}
+			return observation{}, err
+		}
+		return observation{found: true, value: v}, nil
+	case domain.CounterMetricType:
+		v, err := uc.metricStorage.GetCounterValue(ctx, r.MetricName, r.Labels)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return observation{}, nil
+			}
+			return observation{}, err
+		}
+		return observation{found: true, value: float64(v), counter: v}, nil
+	}
+
+	return observation{found: true}, nil
+}
+
+// check проверяет выполнение условия правила по значению метрики o.
+func (st *ruleState) check(o observation) (bool, float64) {
+	if !o.found {
+		return false, 0
+	}
+
+	if st.rule.MetricType == domain.CounterMetricType && st.rule.Condition == ConditionNoIncrease {
+		// первое наблюдение только запоминается, рост оценивается между вычислениями
+		active := st.counterSeen && o.counter <= st.lastCounter
+		st.lastCounter = o.counter
+		st.counterSeen = true
+		return active, o.value
+	}
+
+	return st.rule.Operator.compare(o.value, st.rule.Threshold), o.value
+}
+
+// transit переводит алерт правила в следующее состояние.
+func (st *ruleState) transit(active bool, value float64, now time.Time) {
+	if active {
+		switch st.alert.State {
+		case "", domain.AlertStateResolved:
+			st.alert = domain.Alert{
+				RuleName:    st.rule.Name,
+				Description: st.rule.Description,
+				State:       domain.AlertStatePending,
+				MetricType:  st.rule.MetricType,
+				MetricName:  st.rule.MetricName,
+				Labels:      st.rule.Labels,
+				ActiveAt:    now,
+			}
+		}
+
+		st.alert.Value = value
+		if st.alert.State == domain.AlertStatePending && now.Sub(st.alert.ActiveAt) >= st.rule.For {
+			st.alert.State = domain.AlertStateFiring
+			st.alert.FiredAt = now
+		}
+
+		return
+	}
+
+	switch st.alert.State {
+	case domain.AlertStatePending:
+		st.alert = domain.Alert{}
+	case domain.AlertStateFiring:
+		st.alert.State = domain.AlertStateResolved
+		st.alert.ResolvedAt = now
+		st.alert.Value = value
+	case domain.AlertStateResolved:
+		if now.Sub(st.alert.ResolvedAt) > resolvedRetention {
+			st.alert = domain.Alert{}
+		}
+	}
+}
+
+// GetAlerts возвращает текущий список алертов.
+func (uc *UseCases) GetAlerts(_ context.Context) ([]domain.Alert, error) {
+	uc.mu.RLock()
+	defer uc.mu.RUnlock()
+
+	res := make([]domain.Alert, 0, len(uc.states))
+	for _, st := range uc.states {
+		if st.alert.State == "" {
+			continue
+		}
+
+		res = append(res, st.alert)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].RuleName < res[j].RuleName
+	})
+
+	return res, nil
+}
@@ -0,0 +1,359 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestUseCases_evaluate(t *testing.T) {
+	t.Parallel()
+	type step struct {
+		gaugeValue   float64
+		counterValue int64
+		err          error
+		after        time.Duration
+		wantState    domain.AlertState
+	}
+	tests := []struct {
+		name  string
+		rule  Rule
+		steps []step
+	}{
+		{
+			name: "gauge threshold pending firing resolved",
+			rule: Rule{
+				Name:       "HighHeap",
+				MetricType: domain.GaugeMetricType,
+				MetricName: "HeapAlloc",
+				Condition:  ConditionThreshold,
+				Operator:   OperatorGreater,
+				Threshold:  100,
+				For:        2 * time.Minute,
+			},
+			steps: []step{
+				{gaugeValue: 10, after: 0, wantState: ""},
+				{gaugeValue: 200, after: time.Minute, wantState: domain.AlertStatePending},
+				{gaugeValue: 200, after: time.Minute, wantState: domain.AlertStatePending},
+				{gaugeValue: 200, after: time.Minute, wantState: domain.AlertStateFiring},
+				{gaugeValue: 10, after: time.Minute, wantState: domain.AlertStateResolved},
+				{gaugeValue: 10, after: resolvedRetention + time.Minute, wantState: ""},
+			},
+		},
+		{
+			name: "pending dropped without firing",
+			rule: Rule{
+				Name:       "HighHeap",
+				MetricType: domain.GaugeMetricType,
+				MetricName: "HeapAlloc",
+				Condition:  ConditionThreshold,
+				Operator:   OperatorGreater,
+				Threshold:  100,
+				For:        2 * time.Minute,
+			},
+			steps: []step{
+				{gaugeValue: 200, after: 0, wantState: domain.AlertStatePending},
+				{gaugeValue: 10, after: time.Minute, wantState: ""},
+			},
+		},
+		{
+			name: "zero for fires immediately",
+			rule: Rule{
+				Name:       "LowCounter",
+				MetricType: domain.CounterMetricType,
+				MetricName: "PollCount",
+				Condition:  ConditionThreshold,
+				Operator:   OperatorLess,
+				Threshold:  5,
+			},
+			steps: []step{
+				{counterValue: 1, after: 0, wantState: domain.AlertStateFiring},
+			},
+		},
+		{
+			name: "counter stopped growing",
+			rule: Rule{
+				Name:       "PollCountStalled",
+				MetricType: domain.CounterMetricType,
+				MetricName: "PollCount",
+				Condition:  ConditionNoIncrease,
+				For:        5 * time.Minute,
+			},
+			steps: []step{
+				{counterValue: 1, after: 0, wantState: ""},
+				{counterValue: 2, after: time.Minute, wantState: ""},
+				{counterValue: 2, after: time.Minute, wantState: domain.AlertStatePending},
+				{counterValue: 2, after: 5 * time.Minute, wantState: domain.AlertStateFiring},
+				{counterValue: 3, after: time.Minute, wantState: domain.AlertStateResolved},
+			},
+		},
+		{
+			name: "not found metric is inactive",
+			rule: Rule{
+				Name:       "HighHeap",
+				MetricType: domain.GaugeMetricType,
+				MetricName: "HeapAlloc",
+				Condition:  ConditionThreshold,
+				Operator:   OperatorGreater,
+				Threshold:  100,
+			},
+			steps: []step{
+				{err: domain.ErrNotFound, after: 0, wantState: ""},
+			},
+		},
+		{
+			name: "storage error keeps state",
+			rule: Rule{
+				Name:       "HighHeap",
+				MetricType: domain.GaugeMetricType,
+				MetricName: "HeapAlloc",
+				Condition:  ConditionThreshold,
+				Operator:   OperatorGreater,
+				Threshold:  100,
+			},
+			steps: []step{
+				{gaugeValue: 200, after: 0, wantState: domain.AlertStateFiring},
+				{err: errors.New("some error"), after: time.Minute, wantState: domain.AlertStateFiring},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			storage := &mockMetric{}
+			uc := &UseCases{
+				metricStorage: storage,
+			}
+			uc.setRules([]Rule{tt.rule}, time.Now())
+
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			for i, s := range tt.steps {
+				storage.gaugeValue = s.gaugeValue
+				storage.counterValue = s.counterValue
+				storage.err = s.err
+				now = now.Add(s.after)
+
+				uc.evaluate(context.Background(), now)
+
+				alerts, err := uc.GetAlerts(context.Background())
+				if err != nil {
+					t.Fatalf("GetAlerts() error = %v", err)
+				}
+
+				var got domain.AlertState
+				if len(alerts) > 0 {
+					got = alerts[0].State
+				}
+				if got != s.wantState {
+					t.Errorf("step %d: state = %q, want %q", i, got, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestUseCases_Reload(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "rules.json")
+	err := os.WriteFile(path, []byte(`[
+		{"name": "HighHeap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 100},
+		{"name": "LowFree", "type": "gauge", "metric": "FreeMemory", "op": "<", "threshold": 100}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	uc := &UseCases{
+		metricStorage: &mockMetric{gaugeValue: 200},
+		rulesPath:     path,
+	}
+	if err = uc.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	uc.evaluate(context.Background(), time.Now())
+
+	// правило HighHeap не изменилось и должно сохранить состояние, LowFree удалено
+	err = os.WriteFile(path, []byte(`[
+		{"name": "HighHeap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 100}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = uc.Reload(context.Background()); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	alerts, err := uc.GetAlerts(context.Background())
+	if err != nil {
+		t.Fatalf("GetAlerts() error = %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].RuleName != "HighHeap" || alerts[0].State != domain.AlertStateFiring {
+		t.Errorf("GetAlerts() = %v, want single firing HighHeap alert", alerts)
+	}
+}
+
+func TestUseCases_evaluateWithoutLock(t *testing.T) {
+	t.Parallel()
+	storage := &mockMetric{
+		gaugeValue: 200,
+		called:     make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	uc := &UseCases{metricStorage: storage}
+	uc.setRules([]Rule{{
+		Name:       "HighHeap",
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		Operator:   OperatorGreater,
+		Threshold:  100,
+	}}, time.Now())
+
+	done := make(chan []domain.Alert)
+	go func() {
+		done <- uc.evaluate(context.Background(), time.Now())
+	}()
+	<-storage.called
+
+	// пока значение метрики читается, алерты доступны
+	if _, err := uc.GetAlerts(context.Background()); err != nil {
+		t.Fatalf("GetAlerts() error = %v", err)
+	}
+
+	close(storage.release)
+	if changed := <-done; len(changed) != 1 || changed[0].State != domain.AlertStateFiring {
+		t.Errorf("evaluate() = %v, want single firing alert", changed)
+	}
+}
+
+func TestUseCases_setRules_resolveFiring(t *testing.T) {
+	t.Parallel()
+	rule := Rule{
+		Name:       "HighHeap",
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		Operator:   OperatorGreater,
+		Threshold:  100,
+	}
+	changed := rule
+	changed.Threshold = 1000
+
+	tests := []struct {
+		name string
+		// rules набор правил после перечитывания
+		rules []Rule
+		// wantAlerts ожидаемое число алертов после перечитывания
+		wantAlerts int
+	}{
+		{
+			name:       "rule removed",
+			wantAlerts: 1,
+		},
+		{
+			name:       "rule changed",
+			rules:      []Rule{changed},
+			wantAlerts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			uc := &UseCases{metricStorage: &mockMetric{gaugeValue: 200}}
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			uc.setRules([]Rule{rule}, now)
+			uc.evaluate(context.Background(), now)
+
+			now = now.Add(time.Minute)
+			resolved := uc.setRules(tt.rules, now)
+			if len(resolved) != 1 || resolved[0].State != domain.AlertStateResolved ||
+				!resolved[0].ResolvedAt.Equal(now) {
+				t.Fatalf("setRules() = %v, want resolved HighHeap alert", resolved)
+			}
+
+			// новое правило не срабатывает, разрешенный алерт остается в списке
+			uc.evaluate(context.Background(), now.Add(time.Minute))
+			alerts, _ := uc.GetAlerts(context.Background())
+			if len(alerts) != tt.wantAlerts || alerts[0].State != domain.AlertStateResolved {
+				t.Errorf("GetAlerts() = %v, want single resolved alert", alerts)
+			}
+
+			uc.evaluate(context.Background(), now.Add(resolvedRetention+time.Minute))
+			if alerts, _ = uc.GetAlerts(context.Background()); len(alerts) != 0 {
+				t.Errorf("GetAlerts() after retention = %v, want empty", alerts)
+			}
+			if len(uc.states) != len(tt.rules) {
+				t.Errorf("got %d rule states, want %d", len(uc.states), len(tt.rules))
+			}
+		})
+	}
+}
+
+func TestUseCases_evaluate_reloadDuringRead(t *testing.T) {
+	t.Parallel()
+	storage := &mockMetric{
+		gaugeValue: 200,
+		called:     make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	uc := &UseCases{metricStorage: storage}
+	rule := Rule{
+		Name:       "HighHeap",
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		Operator:   OperatorGreater,
+		Threshold:  100,
+	}
+	uc.setRules([]Rule{rule}, time.Now())
+
+	done := make(chan []domain.Alert)
+	go func() {
+		done <- uc.evaluate(context.Background(), time.Now())
+	}()
+	<-storage.called
+
+	// правило изменилось, пока читалось значение, результат чтения относится к прежнему правилу
+	rule.Threshold = 1000
+	uc.setRules([]Rule{rule}, time.Now())
+	close(storage.release)
+
+	if changed := <-done; len(changed) != 0 {
+		t.Errorf("evaluate() = %v, want no changes", changed)
+	}
+	if alerts, _ := uc.GetAlerts(context.Background()); len(alerts) != 0 {
+		t.Errorf("GetAlerts() = %v, want empty", alerts)
+	}
+}
+
+func TestUseCases_tick_notifyFullState(t *testing.T) {
+	t.Parallel()
+	n := &mockNotifier{}
+	uc := &UseCases{
+		metricStorage: &mockMetric{gaugeValue: 200},
+		notifier:      n,
+	}
+	uc.setRules([]Rule{{
+		Name:       "HighHeap",
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		Operator:   OperatorGreater,
+		Threshold:  100,
+	}}, time.Now())
+
+	now := time.Now()
+	uc.tick(context.Background(), now)
+	uc.tick(context.Background(), now.Add(time.Minute))
+
+	// получатель при каждом вычислении получает все алерты, в том числе неизменившиеся
+	if len(n.calls) != 2 {
+		t.Fatalf("Notify() called %d times, want 2", len(n.calls))
+	}
+	for i, alerts := range n.calls {
+		if len(alerts) != 1 || alerts[0].State != domain.AlertStateFiring {
+			t.Errorf("Notify() call %d alerts = %v, want single firing alert", i, alerts)
+		}
+	}
+}
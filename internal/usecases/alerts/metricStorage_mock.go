@@ -0,0 +1,36 @@
+package alerts
+
+import (
+	"context"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type mockMetric struct {
+	gaugeValue   float64
+	counterValue int64
+	err          error
+	// called, если задан, получает значение при каждом чтении.
+	called chan struct{}
+	// release, если задан, задерживает чтение до его закрытия.
+	release chan struct{}
+}
+
+func (m *mockMetric) wait() {
+	if m.called != nil {
+		m.called <- struct{}{}
+	}
+	if m.release != nil {
+		<-m.release
+	}
+}
+
+func (m *mockMetric) GetGaugeValue(_ context.Context, _ string, _ domain.Labels) (float64, error) {
+	m.wait()
+	return m.gaugeValue, m.err
+}
+
+func (m *mockMetric) GetCounterValue(_ context.Context, _ string, _ domain.Labels) (int64, error) {
+	m.wait()
+	return m.counterValue, m.err
+}
@@ -0,0 +1,15 @@
+package alerts
+
+import (
+	"context"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type mockNotifier struct {
+	calls [][]domain.Alert
+}
+
+func (m *mockNotifier) Notify(_ context.Context, alerts []domain.Alert) {
+	m.calls = append(m.calls, alerts)
+}
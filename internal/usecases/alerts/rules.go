@@ -0,0 +1,181 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// Condition тип условия правила.
+type Condition string
+
+const (
+	// ConditionThreshold значение метрики сравнивается с порогом.
+	ConditionThreshold Condition = "threshold"
+	// ConditionNoIncrease значение счетчика перестало расти.
+	ConditionNoIncrease Condition = "no_increase"
+)
+
+// Operator оператор сравнения значения метрики с порогом.
+type Operator string
+
+const (
+	OperatorGreater      Operator = ">"
+	OperatorGreaterEqual Operator = ">="
+	OperatorLess         Operator = "<"
+	OperatorLessEqual    Operator = "<="
+	OperatorEqual        Operator = "=="
+	OperatorNotEqual     Operator = "!="
+)
+
+// compare сравнивает значение с порогом.
+func (o Operator) compare(value, threshold float64) bool {
+	switch o {
+	case OperatorGreater:
+		return value > threshold
+	case OperatorGreaterEqual:
+		return value >= threshold
+	case OperatorLess:
+		return value < threshold
+	case OperatorLessEqual:
+		return value <= threshold
+	case OperatorEqual:
+		return value == threshold
+	case OperatorNotEqual:
+		return value != threshold
+	}
+
+	return false
+}
+
+// Rule правило алертинга.
+type Rule struct {
+	Name        string
+	Description string
+	MetricType  domain.MetricType
+	MetricName  string
+	Labels      domain.Labels
+	Condition   Condition
+	Operator    Operator
+	Threshold   float64
+	// For время, в течение которого условие должно выполняться, чтобы алерт сработал.
+	For time.Duration
+}
+
+// rule файловое представление правила.
+type rule struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Type        string            `json:"type"`
+	Metric      string            `json:"metric"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Condition   string            `json:"condition,omitempty"`
+	Operator    string            `json:"op,omitempty"`
+	Threshold   float64           `json:"threshold,omitempty"`
+	For         string            `json:"for,omitempty"`
+}
+
+// ParseRules разбирает правила в формате JSON.
+func ParseRules(data []byte) ([]Rule, error) {
+	var parsed []rule
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	res := make([]Rule, 0, len(parsed))
+	names := make(map[string]struct{}, len(parsed))
+	for _, r := range parsed {
+		dr, err := ruleToDomain(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %w", r.Name, err)
+		}
+
+		if _, exist := names[dr.Name]; exist {
+			return nil, fmt.Errorf("duplicate rule name %q", dr.Name)
+		}
+		names[dr.Name] = struct{}{}
+
+		res = append(res, dr)
+	}
+
+	return res, nil
+}
+
+// LoadRules загружает правила из файла.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRules(data)
+}
+
+func ruleToDomain(r rule) (Rule, error) {
+	if r.Name == "" {
+		return Rule{}, errors.New("name is empty")
+	}
+
+	if r.Metric == "" {
+		return Rule{}, errors.New("metric is empty")
+	}
+
+	mType, err := domain.NewMetricTypeFromString(r.Type)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	labels := domain.Labels(r.Labels)
+	if err = labels.Validate(); err != nil {
+		return Rule{}, err
+	}
+
+	var forDuration time.Duration
+	if r.For != "" {
+		forDuration, err = time.ParseDuration(r.For)
+		if err != nil {
+			return Rule{}, fmt.Errorf("failed to parse for: %w", err)
+		}
+		if forDuration < 0 {
+			return Rule{}, errors.New("for is negative")
+		}
+	}
+
+	res := Rule{
+		Name:        r.Name,
+		Description: r.Description,
+		MetricType:  mType,
+		MetricName:  r.Metric,
+		Labels:      labels.Copy(),
+		Condition:   Condition(r.Condition),
+		Operator:    Operator(r.Operator),
+		Threshold:   r.Threshold,
+		For:         forDuration,
+	}
+
+	if res.Condition == "" {
+		res.Condition = ConditionThreshold
+	}
+
+	switch res.Condition {
+	case ConditionThreshold:
+		switch res.Operator {
+		case OperatorGreater, OperatorGreaterEqual, OperatorLess,
+			OperatorLessEqual, OperatorEqual, OperatorNotEqual:
+		default:
+			return Rule{}, fmt.Errorf("unknown operator %q", r.Operator)
+		}
+	case ConditionNoIncrease:
+		if res.MetricType != domain.CounterMetricType {
+			return Rule{}, fmt.Errorf("condition %s is supported only for counters", res.Condition)
+		}
+	default:
+		return Rule{}, fmt.Errorf("unknown condition %q", r.Condition)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package alerts
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestParseRules(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		data    string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name: "threshold and no increase",
+			data: `[
+				{"name": "HighHeap", "type": "gauge", "metric": "HeapAlloc", "op": ">", "threshold": 1e9, "for": "2m"},
+				{"name": "PollCountStalled", "type": "counter", "metric": "PollCount", "condition": "no_increase", "for": "5m"}
+			]`,
+			want: []Rule{
+				{
+					Name:       "HighHeap",
+					MetricType: domain.GaugeMetricType,
+					MetricName: "HeapAlloc",
+					Condition:  ConditionThreshold,
+					Operator:   OperatorGreater,
+					Threshold:  1e9,
+					For:        2 * time.Minute,
+				},
+				{
+					Name:       "PollCountStalled",
+					MetricType: domain.CounterMetricType,
+					MetricName: "PollCount",
+					Condition:  ConditionNoIncrease,
+					For:        5 * time.Minute,
+				},
+			},
+		},
+		{
+			name:    "unknown operator",
+			data:    `[{"name": "r", "type": "gauge", "metric": "m", "op": "<>"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "no increase for gauge",
+			data:    `[{"name": "r", "type": "gauge", "metric": "m", "condition": "no_increase"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			data:    `[{"name": "r", "type": "gauge", "metric": "m", "op": ">"}, {"name": "r", "type": "gauge", "metric": "m", "op": "<"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "bad duration",
+			data:    `[{"name": "r", "type": "gauge", "metric": "m", "op": ">", "for": "soon"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown metric type",
+			data:    `[{"name": "r", "type": "histogram", "metric": "m", "op": ">"}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := ParseRules([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRules() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,19 +5,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
 )
 
 // MetricStorage хранилище метрик.
 type MetricStorage interface {
-	UpdateGauge(ctx context.Context, value domain.MetricValue) error
-	UpdateCounter(ctx context.Context, value domain.MetricValue) error
-	GetGaugeValue(ctx context.Context, name string) (float64, error)
-	GetCounterValue(ctx context.Context, name string) (int64, error)
-	GetAllValues(ctx context.Context) ([]domain.MetricValue, error)
+	UpdateGauge(ctx context.Context, agent string, value domain.MetricValue) error
+	UpdateCounter(ctx context.Context, agent string, value domain.MetricValue) error
+	GetGaugeValue(ctx context.Context, name string, labels domain.Labels) (float64, error)
+	GetCounterValue(ctx context.Context, name string, labels domain.Labels) (int64, error)
+	GetAllValues(ctx context.Context, matchers ...domain.LabelMatcher) ([]domain.MetricValue, error)
 	Ping(ctx context.Context) error
-	UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error
+	UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error
+	GetAgents(ctx context.Context) ([]domain.Agent, error)
+	GetAgent(ctx context.Context, name string) (domain.Agent, error)
+	GetSamples(ctx context.Context, q domain.RangeQuery) ([]domain.Sample, error)
 }
 
 // UseCases бизнес-логика для сбора и обработки метрик.
@@ -32,16 +37,17 @@ func NewUseCases(metricStorage MetricStorage) *UseCases {
 	}
 }
 
-// UpdateMetric обновляет метрику.
-func (uc *UseCases) UpdateMetric(ctx context.Context, value domain.MetricValue) error {
+// UpdateMetric обновляет метрику, присланную агентом.
+func (uc *UseCases) UpdateMetric(ctx context.Context, agent string, value domain.MetricValue) error {
+	agent = agentName(agent)
 	switch value.Type {
 	case domain.GaugeMetricType:
-		err := uc.metricStorage.UpdateGauge(ctx, value)
+		err := uc.metricStorage.UpdateGauge(ctx, agent, value)
 		if err != nil {
 			return fmt.Errorf("error UpdateGauge: %w", err)
 		}
 	case domain.CounterMetricType:
-		err := uc.metricStorage.UpdateCounter(ctx, value)
+		err := uc.metricStorage.UpdateCounter(ctx, agent, value)
 		if err != nil {
 			return fmt.Errorf("error UpdateGauge: %w", err)
 		}
@@ -50,17 +56,18 @@ func (uc *UseCases) UpdateMetric(ctx context.Context, value domain.MetricValue)
 	return nil
 }
 
-// GetAllMetrics возвращает значения всех метрик.
-func (uc *UseCases) GetAllMetrics(ctx context.Context) ([]domain.MetricValue, error) {
-	return uc.metricStorage.GetAllValues(ctx)
+// GetAllMetrics возвращает значения всех метрик, метки которых удовлетворяют условиям.
+func (uc *UseCases) GetAllMetrics(ctx context.Context,
+	matchers ...domain.LabelMatcher) ([]domain.MetricValue, error) {
+	return uc.metricStorage.GetAllValues(ctx, matchers...)
 }
 
 // GetMetric возвращает значение одной метрики.
 func (uc *UseCases) GetMetric(ctx context.Context, value domain.MetricType,
-	name string) (domain.MetricValue, error) {
+	name string, labels domain.Labels) (domain.MetricValue, error) {
 	switch value {
 	case domain.GaugeMetricType:
-		val, err := uc.metricStorage.GetGaugeValue(ctx, name)
+		val, err := uc.metricStorage.GetGaugeValue(ctx, name, labels)
 		if err != nil {
 			return domain.MetricValue{}, fmt.Errorf("error GetGaugeValue: %w", err)
 		}
@@ -68,16 +75,18 @@ func (uc *UseCases) GetMetric(ctx context.Context, value domain.MetricType,
 		return domain.MetricValue{
 			Type:       domain.GaugeMetricType,
 			Name:       name,
+			Labels:     labels,
 			GaugeValue: val,
 		}, nil
 	case domain.CounterMetricType:
-		val, err := uc.metricStorage.GetCounterValue(ctx, name)
+		val, err := uc.metricStorage.GetCounterValue(ctx, name, labels)
 		if err != nil {
 			return domain.MetricValue{}, fmt.Errorf("error GetCounterValue: %w", err)
 		}
 		return domain.MetricValue{
 			Type:         domain.CounterMetricType,
 			Name:         name,
+			Labels:       labels,
 			CounterValue: val,
 		}, nil
 	}
@@ -90,7 +99,51 @@ func (uc *UseCases) Ping(ctx context.Context) error {
 	return uc.metricStorage.Ping(ctx)
 }
 
-// UpdateMetrics обновляет значения метрик.
-func (uc *UseCases) UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error {
-	return uc.metricStorage.UpdateMetrics(ctx, metrics)
+// UpdateMetrics обновляет значения метрик, присланных агентом.
+func (uc *UseCases) UpdateMetrics(ctx context.Context, agent string, metrics []domain.MetricValue) error {
+	return uc.metricStorage.UpdateMetrics(ctx, agentName(agent), metrics)
+}
+
+// GetAgents возвращает агентов, присылавших метрики.
+// При ненулевом silentFor возвращаются только агенты, не присылавшие метрики дольше этого интервала.
+func (uc *UseCases) GetAgents(ctx context.Context, silentFor time.Duration) ([]domain.Agent, error) {
+	agents, err := uc.metricStorage.GetAgents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error GetAgents: %w", err)
+	}
+
+	res := make([]domain.Agent, 0, len(agents))
+	now := time.Now()
+	for _, a := range agents {
+		if silentFor > 0 && now.Sub(a.LastSeen) < silentFor {
+			continue
+		}
+
+		res = append(res, a)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+
+	return res, nil
+}
+
+// GetAgent возвращает агента по имени.
+func (uc *UseCases) GetAgent(ctx context.Context, name string) (domain.Agent, error) {
+	agent, err := uc.metricStorage.GetAgent(ctx, name)
+	if err != nil {
+		return domain.Agent{}, fmt.Errorf("error GetAgent: %w", err)
+	}
+
+	return agent, nil
+}
+
+// agentName возвращает имя агента, для непредставившегося агента используется UnknownAgentName.
+func agentName(agent string) string {
+	if agent == "" {
+		return domain.UnknownAgentName
+	}
+
+	return agent
 }
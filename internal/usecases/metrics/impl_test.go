@@ -5,6 +5,7 @@ import (
 	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kdv2001/onlyMetrics/internal/domain"
 )
@@ -103,7 +104,7 @@ func TestUseCases_UpdateMetric(t *testing.T) {
 			uc := &UseCases{
 				metricStorage: tt.fields.metricStorage,
 			}
-			if err := uc.UpdateMetric(tt.args.in0, tt.args.value); (err != nil) != tt.wantErr {
+			if err := uc.UpdateMetric(tt.args.in0, "agent", tt.args.value); (err != nil) != tt.wantErr {
 				t.Errorf("UpdateMetric() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -217,7 +218,7 @@ func TestUseCases_GetMetric(t *testing.T) {
 			uc := &UseCases{
 				metricStorage: tt.fields.metricStorage,
 			}
-			got, err := uc.GetMetric(tt.args.ctx, tt.args.value, tt.args.name)
+			got, err := uc.GetMetric(tt.args.ctx, tt.args.value, tt.args.name, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("GetMetric() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -228,3 +229,48 @@ func TestUseCases_GetMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestUseCases_GetAgents(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	storage := &mockMetric{
+		agents: []domain.Agent{
+			{Name: "b", LastSeen: now},
+			{Name: "a", LastSeen: now.Add(-time.Hour)},
+		},
+	}
+	tests := []struct {
+		name      string
+		silentFor time.Duration
+		want      []string
+	}{
+		{
+			name:      "all agents sorted",
+			silentFor: 0,
+			want:      []string{"a", "b"},
+		},
+		{
+			name:      "silent agents",
+			silentFor: 5 * time.Minute,
+			want:      []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			uc := NewUseCases(storage)
+			got, err := uc.GetAgents(context.Background(), tt.silentFor)
+			if err != nil {
+				t.Fatalf("GetAgents() error = %v", err)
+			}
+
+			names := make([]string, 0, len(got))
+			for _, a := range got {
+				names = append(names, a.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("GetAgents() got = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
@@ -9,33 +9,53 @@ import (
 type mockMetric struct {
 	gaugeValue   float64
 	counterValue int64
+	agents       []domain.Agent
+	samples      []domain.Sample
 	err          error
 }
 
-func (m *mockMetric) UpdateGauge(_ context.Context, value domain.MetricValue) error {
+func (m *mockMetric) UpdateGauge(_ context.Context, _ string, value domain.MetricValue) error {
 	return m.err
 }
 
-func (m *mockMetric) UpdateCounter(_ context.Context, value domain.MetricValue) error {
+func (m *mockMetric) UpdateCounter(_ context.Context, _ string, value domain.MetricValue) error {
 	return m.err
 }
 
-func (m *mockMetric) GetGaugeValue(_ context.Context, name string) (float64, error) {
+func (m *mockMetric) GetGaugeValue(_ context.Context, name string, _ domain.Labels) (float64, error) {
 	return m.gaugeValue, m.err
 }
 
-func (m *mockMetric) GetCounterValue(_ context.Context, name string) (int64, error) {
+func (m *mockMetric) GetCounterValue(_ context.Context, name string, _ domain.Labels) (int64, error) {
 	return m.counterValue, m.err
 }
 
-func (m *mockMetric) GetAllValues(_ context.Context) ([]domain.MetricValue, error) {
+func (m *mockMetric) GetAllValues(_ context.Context, _ ...domain.LabelMatcher) ([]domain.MetricValue, error) {
 	return nil, m.err
 }
 
-func (m *mockMetric) UpdateMetrics(ctx context.Context, metrics []domain.MetricValue) error {
+func (m *mockMetric) UpdateMetrics(ctx context.Context, _ string, metrics []domain.MetricValue) error {
 	return m.err
 }
 
 func (m *mockMetric) Ping(_ context.Context) error {
 	return nil
 }
+
+func (m *mockMetric) GetAgents(_ context.Context) ([]domain.Agent, error) {
+	return m.agents, m.err
+}
+
+func (m *mockMetric) GetAgent(_ context.Context, name string) (domain.Agent, error) {
+	for _, a := range m.agents {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+
+	return domain.Agent{}, domain.ErrNotFound
+}
+
+func (m *mockMetric) GetSamples(_ context.Context, _ domain.RangeQuery) ([]domain.Sample, error) {
+	return m.samples, m.err
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+// QueryRange возвращает значения ряда метрики за период, агрегированные по шагу запроса.
+// Шаги без значений пропускаются.
+func (uc *UseCases) QueryRange(ctx context.Context, q domain.RangeQuery) ([]domain.Point, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	samples, err := uc.metricStorage.GetSamples(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("error GetSamples: %w", err)
+	}
+
+	return aggregate(q, samples), nil
+}
+
+// aggregate объединяет упорядоченные по времени агрегаты по шагам запроса
+// и вычисляет значение функции агрегации на каждом шаге.
+func aggregate(q domain.RangeQuery, samples []domain.Sample) []domain.Point {
+	res := make([]domain.Point, 0)
+	var (
+		bucket domain.Sample
+		idx    int64 = -1
+	)
+	flush := func() {
+		if bucket.Count == 0 {
+			return
+		}
+
+		res = append(res, domain.Point{
+			Timestamp: bucket.Timestamp,
+			Value:     aggregationValue(q, bucket),
+		})
+	}
+
+	for _, s := range samples {
+		if s.Timestamp.Before(q.Start) || !s.Timestamp.Before(q.End) {
+			continue
+		}
+
+		i := int64(s.Timestamp.Sub(q.Start) / q.Step)
+		if i != idx {
+			flush()
+			idx = i
+			bucket = domain.Sample{
+				Timestamp: q.Start.Add(time.Duration(i) * q.Step),
+			}
+		}
+
+		bucket = bucket.Merge(s)
+	}
+	flush()
+
+	return res
+}
+
+// aggregationValue вычисляет значение функции агрегации для шага.
+func aggregationValue(q domain.RangeQuery, bucket domain.Sample) float64 {
+	switch q.Aggregation {
+	case domain.AggregationAvg:
+		return bucket.Sum / float64(bucket.Count)
+	case domain.AggregationMin:
+		return bucket.Min
+	case domain.AggregationMax:
+		return bucket.Max
+	case domain.AggregationLast:
+		return bucket.Last
+	case domain.AggregationIncrease:
+		return bucket.Sum
+	case domain.AggregationRate:
+		return bucket.Sum / q.Step.Seconds()
+	}
+
+	return 0
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestUseCases_QueryRange(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gaugeSamples := []domain.Sample{
+		domain.NewSample(start.Add(-time.Second), 100),
+		domain.NewSample(start, 1),
+		domain.NewSample(start.Add(10*time.Second), 3),
+		domain.NewSample(start.Add(70*time.Second), 5),
+		domain.NewSample(start.Add(3*time.Minute), 100),
+	}
+	counterSamples := []domain.Sample{
+		domain.NewSample(start, 30),
+		domain.NewSample(start.Add(30*time.Second), 30),
+		// агрегат хранилища, уже объединенный по шагу
+		{Timestamp: start.Add(time.Minute), Count: 2, Sum: 120, Min: 60, Max: 60, Last: 60},
+	}
+	query := func(t domain.MetricType, a domain.Aggregation) domain.RangeQuery {
+		return domain.RangeQuery{
+			Type:        t,
+			Name:        "some",
+			Start:       start,
+			End:         start.Add(3 * time.Minute),
+			Step:        time.Minute,
+			Aggregation: a,
+		}
+	}
+	tests := []struct {
+		name    string
+		storage *mockMetric
+		q       domain.RangeQuery
+		want    []domain.Point
+		wantErr bool
+	}{
+		{
+			name:    "gauge avg",
+			storage: &mockMetric{samples: gaugeSamples},
+			q:       query(domain.GaugeMetricType, domain.AggregationAvg),
+			want: []domain.Point{
+				{Timestamp: start, Value: 2},
+				{Timestamp: start.Add(time.Minute), Value: 5},
+			},
+		},
+		{
+			name:    "gauge min",
+			storage: &mockMetric{samples: gaugeSamples},
+			q:       query(domain.GaugeMetricType, domain.AggregationMin),
+			want: []domain.Point{
+				{Timestamp: start, Value: 1},
+				{Timestamp: start.Add(time.Minute), Value: 5},
+			},
+		},
+		{
+			name:    "gauge max",
+			storage: &mockMetric{samples: gaugeSamples},
+			q:       query(domain.GaugeMetricType, domain.AggregationMax),
+			want: []domain.Point{
+				{Timestamp: start, Value: 3},
+				{Timestamp: start.Add(time.Minute), Value: 5},
+			},
+		},
+		{
+			name:    "gauge last",
+			storage: &mockMetric{samples: gaugeSamples},
+			q:       query(domain.GaugeMetricType, domain.AggregationLast),
+			want: []domain.Point{
+				{Timestamp: start, Value: 3},
+				{Timestamp: start.Add(time.Minute), Value: 5},
+			},
+		},
+		{
+			name:    "counter increase",
+			storage: &mockMetric{samples: counterSamples},
+			q:       query(domain.CounterMetricType, domain.AggregationIncrease),
+			want: []domain.Point{
+				{Timestamp: start, Value: 60},
+				{Timestamp: start.Add(time.Minute), Value: 120},
+			},
+		},
+		{
+			name:    "counter rate",
+			storage: &mockMetric{samples: counterSamples},
+			q:       query(domain.CounterMetricType, domain.AggregationRate),
+			want: []domain.Point{
+				{Timestamp: start, Value: 1},
+				{Timestamp: start.Add(time.Minute), Value: 2},
+			},
+		},
+		{
+			name:    "empty",
+			storage: &mockMetric{},
+			q:       query(domain.GaugeMetricType, domain.AggregationAvg),
+			want:    []domain.Point{},
+		},
+		{
+			name:    "unsupported aggregation",
+			storage: &mockMetric{samples: gaugeSamples},
+			q:       query(domain.GaugeMetricType, domain.AggregationRate),
+			wantErr: true,
+		},
+		{
+			name:    "storage error",
+			storage: &mockMetric{err: errors.New("some error")},
+			q:       query(domain.GaugeMetricType, domain.AggregationAvg),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			uc := NewUseCases(tt.storage)
+			got, err := uc.QueryRange(context.Background(), tt.q)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("QueryRange() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("QueryRange() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
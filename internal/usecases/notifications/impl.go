@@ -0,0 +1,292 @@
+// Package notifications предоставляет методы бизнес-логики для рассылки уведомлений об алертах:
+// группировку, дедупликацию, повторную отправку и повтор при ошибках.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+	"github.com/kdv2001/onlyMetrics/pkg/logger"
+)
+
+const (
+	retryNums        = 3
+	retryBaseBackoff = time.Second
+	queueSize        = 64
+)
+
+var errQueueFull = errors.New("notification queue is full")
+
+// GroupBy способ группировки алертов в уведомления.
+type GroupBy string
+
+const (
+	// GroupByRule одно уведомление на правило.
+	GroupByRule GroupBy = "rule"
+	// GroupByMetric одно уведомление на метрику.
+	GroupByMetric GroupBy = "metric"
+	// GroupByAll все алерты в одном уведомлении.
+	GroupByAll GroupBy = "all"
+)
+
+// NewGroupByFromString конструктор способа группировки.
+func NewGroupByFromString(s string) (GroupBy, error) {
+	switch GroupBy(s) {
+	case GroupByRule, GroupByMetric, GroupByAll:
+		return GroupBy(s), nil
+	}
+
+	return "", fmt.Errorf("unknown group by: %s", s)
+}
+
+// Sender получатель уведомлений.
+type Sender interface {
+	Send(ctx context.Context, n domain.Notification) error
+}
+
+// group состояние отправки группы алертов получателю.
+type group struct {
+	fingerprint string
+	lastSent    time.Time
+	firing      bool
+	// pending отпечаток уведомления в очереди получателя, пустой, если уведомление не ожидает отправки.
+	pending string
+}
+
+// notification уведомление о группе алертов с отпечатком для дедупликации.
+type notification struct {
+	domain.Notification
+	fingerprint string
+	preparedAt  time.Time
+}
+
+// receiver получатель со своей очередью и состоянием отправленных групп,
+// чтобы медленный или недоступный получатель не задерживал остальных.
+type receiver struct {
+	sender Sender
+	queue  chan notification
+
+	mu     sync.Mutex
+	groups map[string]*group
+}
+
+func newReceiver(sender Sender) *receiver {
+	return &receiver{
+		sender: sender,
+		queue:  make(chan notification, queueSize),
+		groups: make(map[string]*group),
+	}
+}
+
+// UseCases бизнес-логика рассылки уведомлений об алертах.
+type UseCases struct {
+	receivers      []*receiver
+	groupBy        GroupBy
+	repeatInterval time.Duration
+	backoff        time.Duration
+}
+
+// NewUseCases создает объект рассылки уведомлений и запускает отправку уведомлений получателям.
+// Каждому получателю уведомления отправляются независимо.
+func NewUseCases(ctx context.Context, groupBy GroupBy, repeatInterval time.Duration,
+	senders ...Sender) *UseCases {
+	uc := &UseCases{
+		receivers:      make([]*receiver, 0, len(senders)),
+		groupBy:        groupBy,
+		repeatInterval: repeatInterval,
+		backoff:        retryBaseBackoff,
+	}
+
+	for _, s := range senders {
+		r := newReceiver(s)
+		uc.receivers = append(uc.receivers, r)
+
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case n := <-r.queue:
+					r.complete(n, uc.send(ctx, r.sender, n.Notification))
+				}
+			}
+		}()
+	}
+
+	return uc
+}
+
+// Notify принимает текущий список алертов и ставит в очереди получателей уведомления
+// по изменившимся группам, а также по сработавшим группам, для которых истек интервал
+// повторной отправки. Уведомление, которое не удалось отправить, ставится в очередь
+// при следующем вызове.
+func (uc *UseCases) Notify(ctx context.Context, alerts []domain.Alert) {
+	notifications := uc.group(alerts, time.Now())
+	for _, r := range uc.receivers {
+		for _, n := range r.prepare(notifications, uc.repeatInterval) {
+			select {
+			case r.queue <- n:
+			default:
+				logger.Errorf(ctx, "notification queue is full, drop group %s", n.GroupKey)
+				r.complete(n, errQueueFull)
+			}
+		}
+	}
+}
+
+// group группирует алерты в уведомления, отсортированные по ключу группы.
+func (uc *UseCases) group(alerts []domain.Alert, now time.Time) []notification {
+	grouped := make(map[string][]domain.Alert)
+	for _, a := range alerts {
+		// алерты в ожидании не рассылаются
+		if a.State != domain.AlertStateFiring && a.State != domain.AlertStateResolved {
+			continue
+		}
+
+		key := uc.groupKey(a)
+		grouped[key] = append(grouped[key], a)
+	}
+
+	res := make([]notification, 0, len(grouped))
+	for key, groupAlerts := range grouped {
+		sort.Slice(groupAlerts, func(i, j int) bool {
+			return groupAlerts[i].RuleName < groupAlerts[j].RuleName
+		})
+
+		n := notification{
+			Notification: domain.Notification{
+				GroupKey: key,
+				Status:   domain.AlertStateResolved,
+				Alerts:   groupAlerts,
+			},
+			fingerprint: fingerprint(groupAlerts),
+			preparedAt:  now,
+		}
+		for _, a := range groupAlerts {
+			if a.State == domain.AlertStateFiring {
+				n.Status = domain.AlertStateFiring
+				break
+			}
+		}
+
+		res = append(res, n)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].GroupKey < res[j].GroupKey
+	})
+
+	return res
+}
+
+// prepare возвращает уведомления, которые необходимо отправить получателю, и отмечает их
+// как ожидающие отправки. Уведомления, уже ожидающие отправки, повторно не возвращаются.
+func (r *receiver) prepare(notifications []notification, repeatInterval time.Duration) []notification {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := make(map[string]struct{}, len(notifications))
+	res := make([]notification, 0, len(notifications))
+	for _, n := range notifications {
+		keys[n.GroupKey] = struct{}{}
+
+		g, exist := r.groups[n.GroupKey]
+		switch {
+		case !exist:
+			g = &group{}
+			r.groups[n.GroupKey] = g
+		case g.pending == n.fingerprint:
+			continue
+		case g.fingerprint == n.fingerprint && !(g.firing && repeatInterval > 0 &&
+			n.preparedAt.Sub(g.lastSent) >= repeatInterval):
+			// группа не изменилась и повторная отправка не требуется
+			continue
+		}
+
+		g.pending = n.fingerprint
+		res = append(res, n)
+	}
+
+	// группы, алерты которых исчезли, забываются, чтобы повторное срабатывание было отправлено
+	for key := range r.groups {
+		if _, exist := keys[key]; !exist {
+			delete(r.groups, key)
+		}
+	}
+
+	return res
+}
+
+// complete запоминает группу уведомления n отправленной, если err равна nil.
+// Иначе группа будет отправлена при следующем вызове prepare.
+func (r *receiver) complete(n notification, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	g, exist := r.groups[n.GroupKey]
+	if !exist {
+		return
+	}
+	if g.pending == n.fingerprint {
+		g.pending = ""
+	}
+	if err != nil {
+		return
+	}
+
+	g.fingerprint = n.fingerprint
+	g.lastSent = n.preparedAt
+	g.firing = n.Status == domain.AlertStateFiring
+}
+
+func (uc *UseCases) groupKey(a domain.Alert) string {
+	switch uc.groupBy {
+	case GroupByMetric:
+		return fmt.Sprintf("metric=%s/%s", a.MetricType, domain.SeriesKey(a.MetricName, a.Labels))
+	case GroupByAll:
+		return "all"
+	}
+
+	return "rule=" + a.RuleName
+}
+
+// fingerprint вычисляет отпечаток группы для дедупликации уведомлений.
+func fingerprint(alerts []domain.Alert) string {
+	parts := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		parts = append(parts, a.RuleName+":"+a.State.String()+":"+a.ActiveAt.String())
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// send отправляет уведомление получателю с повтором при ошибке.
+func (uc *UseCases) send(ctx context.Context, s Sender, n domain.Notification) error {
+	backoff := uc.backoff
+	var err error
+	for i := 0; i < retryNums; i++ {
+		if err = s.Send(ctx, n); err == nil {
+			return nil
+		}
+
+		logger.Errorf(ctx, "error send notification %s, attempt %d: %v", n.GroupKey, i+1, err)
+		if i == retryNums-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return err
+}
@@ -0,0 +1,221 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+func TestUseCases_prepare(t *testing.T) {
+	t.Parallel()
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	firing := domain.Alert{
+		RuleName:   "HighHeap",
+		State:      domain.AlertStateFiring,
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		ActiveAt:   activeAt,
+	}
+	resolved := firing
+	resolved.State = domain.AlertStateResolved
+	pending := domain.Alert{
+		RuleName:   "LowFree",
+		State:      domain.AlertStatePending,
+		MetricType: domain.GaugeMetricType,
+		MetricName: "FreeMemory",
+		ActiveAt:   activeAt,
+	}
+	otherFiring := domain.Alert{
+		RuleName:   "HeapGrowth",
+		State:      domain.AlertStateFiring,
+		MetricType: domain.GaugeMetricType,
+		MetricName: "HeapAlloc",
+		ActiveAt:   activeAt,
+	}
+
+	type step struct {
+		alerts []domain.Alert
+		after  time.Duration
+		// failed отправка уведомлений шага завершается ошибкой
+		failed    bool
+		wantSent  int
+		wantState domain.AlertState
+	}
+	tests := []struct {
+		name    string
+		groupBy GroupBy
+		steps   []step
+	}{
+		{
+			name:    "dedup and repeat",
+			groupBy: GroupByRule,
+			steps: []step{
+				{alerts: []domain.Alert{firing, pending}, wantSent: 1, wantState: domain.AlertStateFiring},
+				{alerts: []domain.Alert{firing}, after: time.Minute, wantSent: 0},
+				{alerts: []domain.Alert{firing}, after: time.Hour, wantSent: 1, wantState: domain.AlertStateFiring},
+				{alerts: []domain.Alert{resolved}, after: time.Minute, wantSent: 1, wantState: domain.AlertStateResolved},
+				{alerts: []domain.Alert{resolved}, after: 2 * time.Hour, wantSent: 0},
+			},
+		},
+		{
+			name:    "resend after failure",
+			groupBy: GroupByRule,
+			steps: []step{
+				{alerts: []domain.Alert{firing}, failed: true, wantSent: 1, wantState: domain.AlertStateFiring},
+				{alerts: []domain.Alert{firing}, after: time.Minute, wantSent: 1, wantState: domain.AlertStateFiring},
+				{alerts: []domain.Alert{firing}, after: time.Minute, wantSent: 0},
+			},
+		},
+		{
+			name:    "group by rule",
+			groupBy: GroupByRule,
+			steps: []step{
+				{alerts: []domain.Alert{firing, otherFiring}, wantSent: 2, wantState: domain.AlertStateFiring},
+			},
+		},
+		{
+			name:    "group by metric",
+			groupBy: GroupByMetric,
+			steps: []step{
+				{alerts: []domain.Alert{firing, otherFiring}, wantSent: 1, wantState: domain.AlertStateFiring},
+				{alerts: []domain.Alert{resolved, otherFiring}, after: time.Minute, wantSent: 1,
+					wantState: domain.AlertStateFiring},
+			},
+		},
+		{
+			name:    "pending only",
+			groupBy: GroupByAll,
+			steps: []step{
+				{alerts: []domain.Alert{pending}, wantSent: 0},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			uc := &UseCases{
+				groupBy: tt.groupBy,
+			}
+			r := newReceiver(nil)
+
+			now := activeAt
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				got := r.prepare(uc.group(s.alerts, now), time.Hour)
+				for _, n := range got {
+					var err error
+					if s.failed {
+						err = errors.New("some error")
+					}
+					r.complete(n, err)
+				}
+
+				if len(got) != s.wantSent {
+					t.Errorf("step %d: got %d notifications, want %d", i, len(got), s.wantSent)
+					continue
+				}
+				if len(got) > 0 && got[0].Status != s.wantState {
+					t.Errorf("step %d: status = %s, want %s", i, got[0].Status, s.wantState)
+				}
+			}
+		})
+	}
+}
+
+func TestReceiver_prepare_pending(t *testing.T) {
+	t.Parallel()
+	uc := &UseCases{groupBy: GroupByAll}
+	r := newReceiver(nil)
+	alerts := []domain.Alert{{RuleName: "HighHeap", State: domain.AlertStateFiring}}
+
+	first := r.prepare(uc.group(alerts, time.Now()), time.Hour)
+	if len(first) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(first))
+	}
+
+	// пока уведомление ожидает отправки, оно не ставится в очередь повторно
+	if got := r.prepare(uc.group(alerts, time.Now()), time.Hour); len(got) != 0 {
+		t.Errorf("got %d notifications while pending, want 0", len(got))
+	}
+
+	r.complete(first[0], errors.New("some error"))
+	if got := r.prepare(uc.group(alerts, time.Now()), time.Hour); len(got) != 1 {
+		t.Errorf("got %d notifications after failure, want 1", len(got))
+	}
+}
+
+func TestUseCases_send(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantSent  int
+		wantErr   bool
+	}{
+		{
+			name:      "success",
+			wantCalls: 1,
+			wantSent:  1,
+		},
+		{
+			name:      "success after retry",
+			errs:      []error{errors.New("some error"), nil},
+			wantCalls: 2,
+			wantSent:  1,
+		},
+		{
+			name:      "retries exhausted",
+			errs:      []error{errors.New("some error"), errors.New("some error"), errors.New("some error")},
+			wantCalls: retryNums,
+			wantSent:  0,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			s := &senderMock{errs: tt.errs}
+			uc := &UseCases{
+				backoff: time.Millisecond,
+			}
+
+			err := uc.send(context.Background(), s, domain.Notification{GroupKey: "all"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if s.calls != tt.wantCalls {
+				t.Errorf("got %d calls, want %d", s.calls, tt.wantCalls)
+			}
+			if len(s.sent) != tt.wantSent {
+				t.Errorf("got %d sent, want %d", len(s.sent), tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestUseCases_Notify_independentReceivers(t *testing.T) {
+	t.Parallel()
+	release := make(chan struct{})
+	defer close(release)
+	slow := &senderMock{release: release}
+	fast := &senderMock{}
+
+	uc := NewUseCases(t.Context(), GroupByRule, time.Hour, slow, fast)
+	uc.Notify(t.Context(), []domain.Alert{
+		{RuleName: "HighHeap", State: domain.AlertStateFiring},
+		{RuleName: "LowFree", State: domain.AlertStateFiring},
+	})
+
+	// зависший получатель не задерживает отправку остальным
+	deadline := time.Now().Add(5 * time.Second)
+	for fast.sentCount() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("fast receiver got %d notifications, want 2", fast.sentCount())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
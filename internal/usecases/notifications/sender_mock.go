@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+
+	"github.com/kdv2001/onlyMetrics/internal/domain"
+)
+
+type senderMock struct {
+	// release, если задан, задерживает отправку до его закрытия.
+	release chan struct{}
+
+	mu    sync.Mutex
+	errs  []error
+	calls int
+	sent  []domain.Notification
+}
+
+func (m *senderMock) Send(_ context.Context, n domain.Notification) error {
+	if m.release != nil {
+		<-m.release
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.calls++
+	if len(m.errs) > 0 {
+		err := m.errs[0]
+		m.errs = m.errs[1:]
+		if err != nil {
+			return err
+		}
+	}
+
+	m.sent = append(m.sent, n)
+	return nil
+}
+
+func (m *senderMock) sentCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.sent)
+}
@@ -0,0 +1,75 @@
+// Package graceful предоставляет функции для корректного завершения работы по сигналу:
+// сервер перестает принимать соединения и дожидается обработки принятых запросов.
+package graceful
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Signals сигналы, по которым приложение завершает работу.
+var Signals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT}
+
+// NotifyContext возвращает контекст, отменяемый при получении одного из Signals.
+func NotifyContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, Signals...)
+}
+
+// Serve запускает serve и при отмене ctx останавливает server: новые соединения не принимаются,
+// обрабатываемые запросы завершаются в течение timeout, после чего соединения закрываются.
+// Возвращает управление после завершения всех запросов.
+func Serve(ctx context.Context, server *http.Server, serve func() error, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- serve()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		_ = server.Close()
+		<-errCh
+		return fmt.Errorf("failed to shutdown server: %w", err)
+	}
+
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// StopGRPC останавливает grpc сервер, дожидаясь завершения обрабатываемых запросов
+// в течение timeout, после чего прерывает оставшиеся.
+func StopGRPC(server *grpc.Server, timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		server.Stop()
+		<-done
+	}
+}
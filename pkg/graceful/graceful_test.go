@@ -0,0 +1,205 @@
+package graceful
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startServe(t *testing.T, ctx context.Context, handler http.Handler,
+	timeout time.Duration) (string, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{Handler: handler}
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, server, func() error { return server.Serve(ln) }, timeout)
+	}()
+
+	return "http://" + ln.Addr().String(), done
+}
+
+func TestServe_drainsInFlight(t *testing.T) {
+	t.Parallel()
+
+	const requests = 5
+	started := make(chan struct{}, requests)
+	release := make(chan struct{})
+	var handled atomic.Int64
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		started <- struct{}{}
+		<-release
+		handled.Add(1)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	url, done := startServe(t, ctx, handler, 5*time.Second)
+
+	var wg sync.WaitGroup
+	codes := make(chan int, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Post(url, "text/plain", nil)
+			if err != nil {
+				t.Errorf("Post() error = %v", err)
+				return
+			}
+			_ = resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+	}
+	for range requests {
+		<-started
+	}
+
+	cancel()
+
+	// после отмены сервер перестает принимать соединения
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		conn, err := net.DialTimeout("tcp", url[len("http://"):], 100*time.Millisecond)
+		if err != nil {
+			break
+		}
+		_ = conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("server accepts connections after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		t.Fatalf("Serve() returned before requests finished: %v", err)
+	default:
+	}
+
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("status = %d, want %d", code, http.StatusOK)
+		}
+	}
+	if got := handled.Load(); got != requests {
+		t.Errorf("handled = %d, want %d", got, requests)
+	}
+}
+
+func TestServe_timeout(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+	})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	url, done := startServe(t, ctx, handler, 50*time.Millisecond)
+
+	go func() {
+		resp, err := http.Post(url, "text/plain", nil)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+	}()
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Serve() error = nil, want shutdown timeout")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve() did not return after timeout")
+	}
+}
+
+func TestStopGRPC(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		// stream оставить ли открытым бесконечный поток
+		stream bool
+	}{
+		{
+			name: "without requests",
+		},
+		{
+			name:   "hanging stream",
+			stream: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lis := bufconn.Listen(1 << 20)
+			server := grpc.NewServer()
+			grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+			go func() { _ = server.Serve(lis) }()
+
+			if tt.stream {
+				conn, err := grpc.NewClient("passthrough:///bufnet",
+					grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+						return lis.DialContext(ctx)
+					}),
+					grpc.WithTransportCredentials(insecure.NewCredentials()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+
+				stream, err := grpc_health_v1.NewHealthClient(conn).Watch(t.Context(),
+					&grpc_health_v1.HealthCheckRequest{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err = stream.Recv(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			stopped := make(chan struct{})
+			go func() {
+				StopGRPC(server, 100*time.Millisecond)
+				close(stopped)
+			}()
+
+			select {
+			case <-stopped:
+			case <-time.After(2 * time.Second):
+				t.Fatal("StopGRPC() did not return after timeout")
+			}
+		})
+	}
+}
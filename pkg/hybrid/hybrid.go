@@ -0,0 +1,112 @@
+// Package hybrid предоставляет гибридное шифрование данных:
+// - данные шифруются AES-256-GCM одноразовым сеансовым ключом;
+// - сеансовый ключ шифруется открытым ключом RSA получателя (RSA-OAEP, SHA-256).
+package hybrid
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Scheme имя схемы шифрования, передается получателю вместе с данными.
+const Scheme = "rsa-oaep-aes-gcm"
+
+// version версия формата зашифрованных данных.
+const version = 1
+
+// sessionKeySize размер сеансового ключа AES-256.
+const sessionKeySize = 32
+
+// headerSize размер заголовка: версия и длина зашифрованного сеансового ключа.
+const headerSize = 3
+
+// ErrInvalidData ошибка данные повреждены или зашифрованы другим ключом.
+var ErrInvalidData = errors.New("invalid encrypted data")
+
+// Encrypt шифрует данные для владельца закрытого ключа, соответствующего pub.
+// Формат результата: версия (1 байт), длина зашифрованного сеансового ключа (2 байта),
+// зашифрованный сеансовый ключ, nonce и зашифрованные данные с тегом GCM.
+func Encrypt(pub *rsa.PublicKey, plaintext []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, sessionKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt session key: %w", err)
+	}
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, headerSize, headerSize+len(encryptedKey)+gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	res[0] = version
+	binary.BigEndian.PutUint16(res[1:], uint16(len(encryptedKey)))
+	res = append(res, encryptedKey...)
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	res = append(res, nonce...)
+
+	return gcm.Seal(res, nonce, plaintext, nil), nil
+}
+
+// Decrypt расшифровывает данные, зашифрованные Encrypt открытым ключом пары priv.
+func Decrypt(priv *rsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) < headerSize || data[0] != version {
+		return nil, ErrInvalidData
+	}
+
+	keySize := int(binary.BigEndian.Uint16(data[1:]))
+	data = data[headerSize:]
+	if len(data) < keySize {
+		return nil, ErrInvalidData
+	}
+
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), nil, priv, data[:keySize], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+	data = data[keySize:]
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidData
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidData, err)
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init gcm: %w", err)
+	}
+
+	return gcm, nil
+}
@@ -0,0 +1,190 @@
+package hybrid
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, MinKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	t.Parallel()
+
+	key := generateKey(t)
+	large := make([]byte, 1<<20)
+	if _, err := rand.Read(large); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{
+			name:      "empty",
+			plaintext: []byte{},
+		},
+		{
+			name:      "small",
+			plaintext: []byte(`[{"id":"Alloc","type":"gauge","value":1}]`),
+		},
+		{
+			name:      "larger than rsa block",
+			plaintext: large,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := Encrypt(&key.PublicKey, tt.plaintext)
+			if err != nil {
+				t.Fatalf("Encrypt() error = %v", err)
+			}
+			if len(tt.plaintext) > 0 && bytes.Contains(data, tt.plaintext) {
+				t.Fatal("Encrypt() result contains plaintext")
+			}
+
+			got, err := Decrypt(key, data)
+			if err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.plaintext) {
+				t.Errorf("Decrypt() = %d bytes, want %d bytes", len(got), len(tt.plaintext))
+			}
+		})
+	}
+}
+
+func TestDecrypt_invalid(t *testing.T) {
+	t.Parallel()
+
+	key := generateKey(t)
+	data, err := Encrypt(&key.PublicKey, []byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 0xff
+
+	tests := []struct {
+		name string
+		key  *rsa.PrivateKey
+		data []byte
+	}{
+		{
+			name: "empty",
+			key:  key,
+			data: nil,
+		},
+		{
+			name: "unknown version",
+			key:  key,
+			data: append([]byte{2}, data[1:]...),
+		},
+		{
+			name: "truncated",
+			key:  key,
+			data: data[:headerSize+10],
+		},
+		{
+			name: "tampered",
+			key:  key,
+			data: tampered,
+		},
+		{
+			name: "other key",
+			key:  generateKey(t),
+			data: data,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := Decrypt(tt.key, tt.data); !errors.Is(err, ErrInvalidData) {
+				t.Errorf("Decrypt() error = %v, want %v", err, ErrInvalidData)
+			}
+		})
+	}
+}
+
+func TestGenerateKeyFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "public.pem")
+
+	if err := GenerateKeyFiles(privatePath, publicPath, 1024); err == nil {
+		t.Fatal("GenerateKeyFiles() with small key error = nil")
+	}
+
+	if err := GenerateKeyFiles(privatePath, publicPath, MinKeyBits); err != nil {
+		t.Fatalf("GenerateKeyFiles() error = %v", err)
+	}
+
+	info, err := os.Stat(privatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("private key permissions = %o, want 600", perm)
+	}
+
+	priv, err := LoadPrivateKey(privatePath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() error = %v", err)
+	}
+	pub, err := LoadPublicKey(publicPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if !pub.Equal(&priv.PublicKey) {
+		t.Error("public key does not match private key")
+	}
+
+	if err = GenerateKeyFiles(privatePath, publicPath, MinKeyBits); err == nil {
+		t.Error("GenerateKeyFiles() overwrote existing key")
+	}
+
+	if _, err = LoadPublicKey(privatePath); err == nil {
+		t.Error("LoadPublicKey() of private key error = nil")
+	}
+}
+
+func TestGenerateKeyFiles_publicKeyError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private.pem")
+	publicPath := filepath.Join(dir, "missing", "public.pem")
+
+	if err := GenerateKeyFiles(privatePath, publicPath, MinKeyBits); err == nil {
+		t.Fatal("GenerateKeyFiles() to missing directory error = nil")
+	}
+	// закрытый ключ удаляется, чтобы генерацию можно было повторить
+	if _, err := os.Stat(privatePath); !os.IsNotExist(err) {
+		t.Fatalf("private key left after failure: %v", err)
+	}
+
+	publicPath = filepath.Join(dir, "public.pem")
+	if err := GenerateKeyFiles(privatePath, publicPath, MinKeyBits); err != nil {
+		t.Errorf("GenerateKeyFiles() retry error = %v", err)
+	}
+}
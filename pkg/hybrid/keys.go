@@ -0,0 +1,137 @@
+package hybrid
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// MinKeyBits минимальный размер ключа RSA.
+const MinKeyBits = 2048
+
+const (
+	privateKeyBlockType = "PRIVATE KEY"
+	publicKeyBlockType  = "PUBLIC KEY"
+)
+
+// GenerateKeyFiles создает пару ключей RSA размером bits и сохраняет их в формате PEM:
+// закрытый ключ PKCS#8 в privatePath с правами 0600, открытый ключ PKIX в publicPath.
+func GenerateKeyFiles(privatePath, publicPath string, bits int) error {
+	if bits < MinKeyBits {
+		return fmt.Errorf("key size %d is less than %d bits", bits, MinKeyBits)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	// O_EXCL не дает случайно перезаписать действующий ключ
+	if err = writePEM(privatePath, privateKeyBlockType, privateDER, 0600); err != nil {
+		return err
+	}
+
+	if err = writePEM(publicPath, publicKeyBlockType, publicDER, 0644); err != nil {
+		// закрытый ключ без открытого бесполезен и не дал бы повторить генерацию
+		_ = os.Remove(privatePath)
+		return err
+	}
+
+	return nil
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if err = pem.Encode(file, &pem.Block{Type: blockType, Bytes: der}); err != nil {
+		_ = file.Close()
+		_ = os.Remove(path)
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// LoadPublicKey читает открытый ключ RSA из файла PEM в формате PKIX или PKCS#1.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not RSA")
+	}
+
+	return pub, nil
+}
+
+// LoadPrivateKey читает закрытый ключ RSA из файла PEM в формате PKCS#8 или PKCS#1.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	priv, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not RSA")
+	}
+
+	return priv, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	return block, nil
+}
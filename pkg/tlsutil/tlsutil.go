@@ -0,0 +1,144 @@
+// Package tlsutil предоставляет функции для настройки TLS сервера и клиента:
+// - загрузка сертификата с возможностью перезагрузки без перезапуска;
+// - проверка сертификатов клиентов (mutual TLS);
+// - получение имени клиента из проверенного сертификата.
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// CertReloader хранит сертификат и ключ из файлов и позволяет перечитать их,
+// например при обновлении сертификата, не прерывая работу сервера.
+type CertReloader struct {
+	certPath string
+	keyPath  string
+	cert     atomic.Pointer[tls.Certificate]
+}
+
+// NewCertReloader загружает сертификат и ключ в формате PEM.
+func NewCertReloader(certPath, keyPath string) (*CertReloader, error) {
+	r := &CertReloader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload перечитывает сертификат и ключ. При ошибке продолжает использоваться прежний сертификат.
+func (r *CertReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	r.cert.Store(&cert)
+
+	return nil
+}
+
+// GetCertificate возвращает текущий сертификат сервера, используется в tls.Config.
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// GetClientCertificate возвращает текущий сертификат клиента, используется в tls.Config.
+func (r *CertReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// LoadCertPool читает сертификаты удостоверяющих центров в формате PEM.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates in %s", path)
+	}
+
+	return pool, nil
+}
+
+// ServerConfig возвращает настройки TLS сервера. При непустом clientCAPath сервер
+// требует от клиентов сертификат, подписанный одним из удостоверяющих центров из файла.
+func ServerConfig(reloader *CertReloader, clientCAPath string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if clientCAPath == "" {
+		return cfg, nil
+	}
+
+	pool, err := LoadCertPool(clientCAPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client CA: %w", err)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return cfg, nil
+}
+
+// ClientConfig возвращает настройки TLS клиента. При пустом caPath сертификат сервера
+// проверяется по системным удостоверяющим центрам. Сертификат клиента передается,
+// если заданы certPath и keyPath.
+func ClientConfig(caPath, certPath, keyPath string) (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if caPath != "" {
+		pool, err := LoadCertPool(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CA: %w", err)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (certPath == "") != (keyPath == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+
+	if certPath != "" {
+		reloader, err := NewCertReloader(certPath, keyPath)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = reloader.GetClientCertificate
+	}
+
+	return cfg, nil
+}
+
+// PeerName возвращает имя из проверенного сертификата клиента: CommonName,
+// а при его отсутствии первое DNS имя. Для соединения без проверенного сертификата возвращается пустая строка.
+func PeerName(state *tls.ConnectionState) string {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return ""
+	}
+
+	leaf := state.VerifiedChains[0][0]
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,294 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA удостоверяющий центр для выпуска тестовых сертификатов.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	path string
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ca.pem")
+	writePEMFile(t, path, "CERTIFICATE", der)
+
+	return &testCA{cert: cert, key: key, path: path}
+}
+
+// issue выпускает сертификат с именем name и записывает его и ключ в файлы.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPath := filepath.Join(dir, name+".pem")
+	keyPath := filepath.Join(dir, name+".key")
+	writePEMFile(t, certPath, "CERTIFICATE", der)
+	writePEMFile(t, keyPath, "PRIVATE KEY", keyDER)
+
+	return certPath, keyPath
+}
+
+func writePEMFile(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certPath, keyPath := ca.issue(t, dir, "server", 2)
+
+	reloader, err := NewCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("NewCertReloader() error = %v", err)
+	}
+	first, _ := reloader.GetCertificate(nil)
+
+	// новый сертификат выпускается поверх прежних файлов
+	ca.issue(t, dir, "server", 3)
+	if err = reloader.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	second, _ := reloader.GetCertificate(nil)
+	if second.Leaf.SerialNumber.Cmp(first.Leaf.SerialNumber) == 0 {
+		t.Fatal("Reload() did not replace certificate")
+	}
+
+	if err = os.WriteFile(certPath, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = reloader.Reload(); err == nil {
+		t.Fatal("Reload() of broken certificate error = nil")
+	}
+	if got, _ := reloader.GetCertificate(nil); got != second {
+		t.Error("broken certificate replaced previous one")
+	}
+}
+
+func TestServerConfig_mutualTLS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	serverCert, serverKey := ca.issue(t, dir, "server", 2)
+	agentCert, agentKey := ca.issue(t, dir, "agent-1", 3)
+
+	otherDir := t.TempDir()
+	otherCA := newTestCA(t, otherDir)
+	strangerCert, strangerKey := otherCA.issue(t, otherDir, "stranger", 2)
+
+	reloader, err := NewCertReloader(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig, err := ServerConfig(reloader, ca.path)
+	if err != nil {
+		t.Fatalf("ServerConfig() error = %v", err)
+	}
+
+	// httptest.Server подставляет собственный сертификат, поэтому сервер запускается вручную
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(PeerName(r.TLS)))
+		}),
+		TLSConfig: serverConfig,
+		ErrorLog:  log.New(io.Discard, "", 0),
+	}
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+	url := "https://" + ln.Addr().String()
+
+	tests := []struct {
+		name     string
+		certPath string
+		keyPath  string
+		wantErr  bool
+		want     string
+	}{
+		{
+			name:     "trusted client",
+			certPath: agentCert,
+			keyPath:  agentKey,
+			want:     "agent-1",
+		},
+		{
+			name:    "without certificate",
+			wantErr: true,
+		},
+		{
+			name:     "certificate of other CA",
+			certPath: strangerCert,
+			keyPath:  strangerKey,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			clientConfig, err := ClientConfig(ca.path, tt.certPath, tt.keyPath)
+			if err != nil {
+				t.Fatalf("ClientConfig() error = %v", err)
+			}
+			client := &http.Client{
+				Transport: &http.Transport{TLSClientConfig: clientConfig},
+				Timeout:   5 * time.Second,
+			}
+
+			resp, err := client.Get(url)
+			if tt.wantErr {
+				if err == nil {
+					_ = resp.Body.Close()
+					t.Fatal("Get() error = nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != tt.want {
+				t.Errorf("PeerName() = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := newTestCA(t, dir)
+	certPath, _ := ca.issue(t, dir, "agent-1", 2)
+
+	if _, err := ClientConfig("", certPath, ""); err == nil {
+		t.Error("ClientConfig() without key error = nil")
+	}
+	if _, err := ClientConfig(filepath.Join(dir, "missing.pem"), "", ""); err == nil {
+		t.Error("ClientConfig() with missing CA error = nil")
+	}
+	if _, err := ClientConfig(certPath, "", ""); err != nil {
+		t.Errorf("ClientConfig() error = %v", err)
+	}
+}
+
+func TestPeerName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		state *tls.ConnectionState
+		want  string
+	}{
+		{
+			name: "nil state",
+			want: "",
+		},
+		{
+			name:  "not verified",
+			state: &tls.ConnectionState{},
+			want:  "",
+		},
+		{
+			name: "common name",
+			state: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{Subject: pkix.Name{CommonName: "agent-1"}, DNSNames: []string{"agent-1.local"}},
+			}}},
+			want: "agent-1",
+		},
+		{
+			name: "dns name",
+			state: &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{
+				{DNSNames: []string{"agent-1.local"}},
+			}}},
+			want: "agent-1.local",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := PeerName(tt.state); got != tt.want {
+				t.Errorf("PeerName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}